@@ -6,11 +6,34 @@ import (
 )
 
 func NewAdd(acts core.Actions) *cobra.Command {
-	return &cobra.Command{
+	addCmd := &cobra.Command{
 		Use:   "add <url>",
 		Short: "Save a URL to the shortener service",
+		Example: `
+		  	urlshortener add https://example.com
+  			urlshortener add https://example.com/spring --alias spring-sale
+  			urlshortener add https://example.com --ttl 72h
+  			urlshortener add https://example.com --expires-at 2030-01-02T15:04:05Z
+  			urlshortener add "https://example.com/?utm_source=mail" --keep-params utm_source
+  			urlshortener add https://intranet.example.com/handbook --password s3cret`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return acts.AddAction(cmd.Context(), cmd.OutOrStdout(), args)
+			alias, _ := cmd.Flags().GetString("alias")
+			ttl, _ := cmd.Flags().GetDuration("ttl")
+			expiresAt, _ := cmd.Flags().GetString("expires-at")
+			keepParams, _ := cmd.Flags().GetStringSlice("keep-params")
+			password, _ := cmd.Flags().GetString("password")
+
+			opts := core.AddOptions{Alias: alias, TTL: ttl, ExpiresAt: expiresAt, KeepParams: keepParams, Password: password}
+			return acts.AddAction(cmd.Context(), outputWriter(cmd), args, opts)
 		},
 	}
+
+	addCmd.Flags().String("alias", "", "custom short code to use instead of a generated one")
+	addCmd.Flags().Duration("ttl", 0, "time until the link expires, e.g. 72h")
+	addCmd.Flags().String("expires-at", "", "RFC3339 time at which the link expires")
+	addCmd.Flags().StringSlice("keep-params", nil, `query parameters to keep even if the policy strips them, e.g. "utm_*"`)
+	addCmd.Flags().String("password", "", "password visitors must enter to follow the link")
+	addCmd.MarkFlagsMutuallyExclusive("ttl", "expires-at")
+
+	return addCmd
 }
@@ -4,8 +4,17 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/db"
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/anewball/urlshortener/internal/server"
+	"github.com/anewball/urlshortener/internal/shortener"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,13 +24,15 @@ func TestNewAdd(t *testing.T) {
 	var gotCtx context.Context
 	var gotOut io.Writer
 	var gotArgs []string
+	var gotOpts core.AddOptions
 
 	mActions := &mockedActions{
-		addActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+		addActionFunc: func(ctx context.Context, out io.Writer, args []string, opts core.AddOptions) error {
 			called = true
 			gotCtx = ctx
 			gotOut = out
 			gotArgs = append([]string(nil), args...)
+			gotOpts = opts
 			return nil
 		},
 	}
@@ -36,7 +47,7 @@ func TestNewAdd(t *testing.T) {
 	buf := &bytes.Buffer{}
 	cmd.SetOut(buf)
 	cmd.SetErr(io.Discard)
-	cmd.SetArgs(args)
+	cmd.SetArgs(append(args, "--alias", "spring-sale", "--ttl", "72h", "--keep-params", "utm_source,ref", "--password", "s3cret"))
 
 	// Execute the command exactly like a user would
 	require.NoError(t, cmd.ExecuteContext(context.Background()))
@@ -44,22 +55,176 @@ func TestNewAdd(t *testing.T) {
 	// Assertions on wiring
 	assert.True(t, called, "AddAction should be invoked")
 	assert.Equal(t, args, gotArgs)
+	assert.Equal(t, core.AddOptions{Alias: "spring-sale", TTL: 72 * time.Hour, KeepParams: []string{"utm_source", "ref"}, Password: "s3cret"}, gotOpts)
 	assert.Same(t, buf, gotOut)
 	assert.NotNil(t, gotCtx)
 }
 
+func TestNewImport(t *testing.T) {
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "links.csv")
+	require.NoError(t, os.WriteFile(csvFile, []byte("url\nhttps://example.com\n"), 0o600))
+
+	testCases := []struct {
+		name           string
+		args           []string
+		stdin          string
+		expectedOpts   core.ImportOptions
+		expectedInput  string
+		expectedErrMsg string
+	}{
+		{
+			name:          "format from extension",
+			args:          []string{"--file", csvFile},
+			expectedOpts:  core.ImportOptions{Format: core.FormatCSV, BatchSize: core.DefaultImportBatchSize},
+			expectedInput: "url\nhttps://example.com\n",
+		},
+		{
+			name:          "stdin with explicit format",
+			args:          []string{"-f", "-", "--format", "jsonl", "-b", "50"},
+			stdin:         `{"url":"https://example.com"}`,
+			expectedOpts:  core.ImportOptions{Format: core.FormatJSONL, BatchSize: 50},
+			expectedInput: `{"url":"https://example.com"}`,
+		},
+		{
+			name:           "missing file",
+			args:           []string{"--file", filepath.Join(dir, "nope.csv")},
+			expectedErrMsg: "no such file",
+		},
+		{
+			name:           "file flag required",
+			args:           []string{},
+			expectedErrMsg: `required flag(s) "file" not set`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			var gotOut io.Writer
+			var gotInput string
+			var gotOpts core.ImportOptions
+
+			mActions := &mockedActions{
+				importActionFunc: func(ctx context.Context, out io.Writer, in io.Reader, opts core.ImportOptions) error {
+					called = true
+					gotOut = out
+					data, err := io.ReadAll(in)
+					gotInput = string(data)
+					gotOpts = opts
+					return err
+				},
+			}
+
+			cmd := NewImport(mActions)
+			buf := &bytes.Buffer{}
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetIn(strings.NewReader(tc.stdin))
+			cmd.SetArgs(tc.args)
+
+			err := cmd.ExecuteContext(context.Background())
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				assert.False(t, called)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, called, "ImportAction should be invoked")
+			assert.Equal(t, tc.expectedOpts, gotOpts)
+			assert.Equal(t, tc.expectedInput, gotInput)
+			assert.Same(t, buf, gotOut)
+		})
+	}
+}
+
+func TestNewExport(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name           string
+		args           []string
+		file           string
+		actionErr      error
+		expectedOpts   core.ExportOptions
+		expectedStdout string
+		expectedFile   string
+		expectedErrMsg string
+	}{
+		{
+			name:           "stdout by default",
+			args:           []string{},
+			expectedOpts:   core.ExportOptions{Format: core.FormatJSONL, Warnings: io.Discard},
+			expectedStdout: "rows\n",
+		},
+		{
+			name:         "to file as csv",
+			args:         []string{"--format", "csv", "-f", filepath.Join(dir, "links.csv")},
+			file:         filepath.Join(dir, "links.csv"),
+			expectedOpts: core.ExportOptions{Format: core.FormatCSV, Warnings: io.Discard},
+			expectedFile: "rows\n",
+		},
+		{
+			name:           "failed export removes the file",
+			args:           []string{"--file", filepath.Join(dir, "partial.jsonl")},
+			file:           filepath.Join(dir, "partial.jsonl"),
+			actionErr:      core.ErrExport,
+			expectedOpts:   core.ExportOptions{Format: core.FormatJSONL, Warnings: io.Discard},
+			expectedErrMsg: core.ErrExport.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotOpts core.ExportOptions
+			mActions := &mockedActions{
+				exportActionFunc: func(ctx context.Context, out io.Writer, opts core.ExportOptions) error {
+					gotOpts = opts
+					_, _ = io.WriteString(out, "rows\n")
+					return tc.actionErr
+				},
+			}
+
+			cmd := NewExport(mActions)
+			buf := &bytes.Buffer{}
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			err := cmd.ExecuteContext(context.Background())
+			assert.Equal(t, tc.expectedOpts, gotOpts)
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				assert.NoFileExists(t, tc.file)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStdout, buf.String())
+			if tc.file != "" {
+				data, err := os.ReadFile(tc.file)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedFile, string(data))
+			}
+		})
+	}
+}
+
 func TestNewGet(t *testing.T) {
 	called := false
 	var gotCtx context.Context
 	var gotOut io.Writer
 	var gotArgs []string
+	var gotOpts core.GetOptions
 
 	mActions := &mockedActions{
-		getActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+		getActionFunc: func(ctx context.Context, out io.Writer, args []string, opts core.GetOptions) error {
 			called = true
 			gotCtx = ctx
 			gotOut = out
 			gotArgs = append([]string(nil), args...)
+			gotOpts = opts
 			return nil
 		},
 	}
@@ -74,7 +239,7 @@ func TestNewGet(t *testing.T) {
 	buf := &bytes.Buffer{}
 	cmd.SetOut(buf)
 	cmd.SetErr(io.Discard)
-	cmd.SetArgs(args)
+	cmd.SetArgs(append(args, "--password", "s3cret"))
 
 	// Execute the command exactly like a user would
 	require.NoError(t, cmd.ExecuteContext(context.Background()))
@@ -82,6 +247,7 @@ func TestNewGet(t *testing.T) {
 	// Assertions on wiring
 	assert.True(t, called, "GetAction should be invoked")
 	assert.Equal(t, args, gotArgs)
+	assert.Equal(t, core.GetOptions{Password: "s3cret"}, gotOpts)
 	assert.Same(t, buf, gotOut)
 	assert.NotNil(t, gotCtx)
 }
@@ -124,14 +290,131 @@ func TestNewDelete(t *testing.T) {
 	assert.NotNil(t, gotCtx)
 }
 
+func TestNewUpdate(t *testing.T) {
+	called := false
+	var gotCtx context.Context
+	var gotOut io.Writer
+	var gotArgs []string
+
+	mActions := &mockedActions{
+		updateActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+			called = true
+			gotCtx = ctx
+			gotOut = out
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	}
+
+	cmd := NewUpdate(mActions)
+
+	assert.Equal(t, "update <code> <url>", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	args := []string{"Hpa3t2B", "https://example.com/summer"}
+
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+
+	// Execute the command exactly like a user would
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+	// Assertions on wiring
+	assert.True(t, called, "UpdateAction should be invoked")
+	assert.Equal(t, args, gotArgs)
+	assert.Same(t, buf, gotOut)
+	assert.NotNil(t, gotCtx)
+}
+
+func TestNewHistory(t *testing.T) {
+	called := false
+	var gotCtx context.Context
+	var gotOut io.Writer
+	var gotArgs []string
+
+	mActions := &mockedActions{
+		historyActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+			called = true
+			gotCtx = ctx
+			gotOut = out
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	}
+
+	cmd := NewHistory(mActions)
+
+	assert.Equal(t, "history <code>", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	args := []string{"Hpa3t2B"}
+
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+
+	// Execute the command exactly like a user would
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+	// Assertions on wiring
+	assert.True(t, called, "HistoryAction should be invoked")
+	assert.Equal(t, args, gotArgs)
+	assert.Same(t, buf, gotOut)
+	assert.NotNil(t, gotCtx)
+}
+
+func TestNewRollback(t *testing.T) {
+	called := false
+	var gotCtx context.Context
+	var gotOut io.Writer
+	var gotArgs []string
+
+	mActions := &mockedActions{
+		rollbackActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+			called = true
+			gotCtx = ctx
+			gotOut = out
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	}
+
+	cmd := NewRollback(mActions)
+
+	assert.Equal(t, "rollback <code> <revision>", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	args := []string{"Hpa3t2B", "1"}
+
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+
+	// Execute the command exactly like a user would
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+	// Assertions on wiring
+	assert.True(t, called, "RollbackAction should be invoked")
+	assert.Equal(t, args, gotArgs)
+	assert.Same(t, buf, gotOut)
+	assert.NotNil(t, gotCtx)
+}
+
 func TestNewList(t *testing.T) {
 	called := false
 	var gotCtx context.Context
 	var gotOut io.Writer
 
+	var gotOpts core.ListOptions
+
 	mActions := &mockedActions{
-		listActionFunc: func(ctx context.Context, limit int, offset int, out io.Writer) error {
+		listActionFunc: func(ctx context.Context, out io.Writer, opts core.ListOptions) error {
 			called = true
+			gotOpts = opts
 			gotCtx = ctx
 			gotOut = out
 			return nil
@@ -146,19 +429,360 @@ func TestNewList(t *testing.T) {
 	buf := &bytes.Buffer{}
 	cmd.SetOut(buf)
 	cmd.SetErr(io.Discard)
-	cmd.SetArgs([]string{"--offset", "0", "--limit", "2"})
+	cmd.SetArgs([]string{"--offset", "0", "--limit", "2", "--cursor", "abc",
+		"--host", "example.com", "--contains", "docs", "--since", "2025-08-01", "--until", "2025-08-31",
+		"--expired", "--sort", "code", "--order", "desc"})
 
 	// Execute the command exactly like a user would
 	require.NoError(t, cmd.Execute())
 
 	// Assertions on wiring
 	assert.True(t, called, "ListAction should be invoked")
+	assert.Equal(t, core.ListOptions{
+		Limit: 2, Cursor: "abc", Host: "example.com", Contains: "docs", Since: "2025-08-01", Until: "2025-08-31",
+		Expired: true, Sort: "code", Order: "desc",
+	}, gotOpts)
 	assert.Same(t, buf, gotOut)
 	assert.NotNil(t, gotCtx)
 }
 
+func TestNewPurgeExpired(t *testing.T) {
+	called := false
+	var gotCtx context.Context
+	var gotOut io.Writer
+	var gotBatchSize int
+
+	mActions := &mockedActions{
+		purgeActionFunc: func(ctx context.Context, out io.Writer, batchSize int) error {
+			called = true
+			gotCtx = ctx
+			gotOut = out
+			gotBatchSize = batchSize
+			return nil
+		},
+	}
+
+	cmd := NewPurgeExpired(mActions)
+
+	assert.Equal(t, "purge-expired", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--batch-size", "250"})
+
+	// Execute the command exactly like a user would
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+	// Assertions on wiring
+	assert.True(t, called, "PurgeAction should be invoked")
+	assert.Equal(t, 250, gotBatchSize)
+	assert.Same(t, buf, gotOut)
+	assert.NotNil(t, gotCtx)
+}
+
+func TestNewStats(t *testing.T) {
+	called := false
+	var gotCtx context.Context
+	var gotOut io.Writer
+	var gotArgs []string
+
+	mActions := &mockedActions{
+		statsActionFunc: func(ctx context.Context, out io.Writer, args []string) error {
+			called = true
+			gotCtx = ctx
+			gotOut = out
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	}
+
+	cmd := NewStats(mActions)
+
+	assert.Equal(t, "stats <code>", cmd.Use)
+	assert.NotNil(t, cmd.RunE)
+
+	args := []string{"Hpa3t2B"}
+
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+
+	// Execute the command exactly like a user would
+	require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+	// Assertions on wiring
+	assert.True(t, called, "StatsAction should be invoked")
+	assert.Equal(t, args, gotArgs)
+	assert.Same(t, buf, gotOut)
+	assert.NotNil(t, gotCtx)
+}
+
+func TestNewServe(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected server.Addrs
+	}{
+		{
+			name:     "api on loopback by default",
+			args:     []string{"--addr", ":9090"},
+			expected: server.Addrs{Redirect: ":9090", API: "127.0.0.1:8081"},
+		},
+		{
+			name:     "api address",
+			args:     []string{"--addr", ":9090", "--api-addr", "10.0.0.5:9091"},
+			expected: server.Addrs{Redirect: ":9090", API: "10.0.0.5:9091"},
+		},
+		{
+			name:     "api disabled",
+			args:     []string{"--api-addr", ""},
+			expected: server.Addrs{Redirect: ":8080"},
+		},
+		{
+			name:     "debug address",
+			args:     []string{"--debug-addr", "127.0.0.1:6060"},
+			expected: server.Addrs{Redirect: ":8080", API: "127.0.0.1:8081", Debug: "127.0.0.1:6060"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			var gotCtx context.Context
+			var gotAddrs server.Addrs
+
+			mServer := &mockedServer{
+				serveFunc: func(ctx context.Context, addrs server.Addrs) error {
+					called = true
+					gotCtx = ctx
+					gotAddrs = addrs
+					return nil
+				},
+			}
+
+			cmd := NewServe(mServer)
+
+			assert.Equal(t, "serve", cmd.Use)
+			assert.NotNil(t, cmd.RunE)
+
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			// Execute the command exactly like a user would
+			require.NoError(t, cmd.ExecuteContext(context.Background()))
+
+			// Assertions on wiring
+			assert.True(t, called, "Serve should be invoked")
+			assert.Equal(t, tc.expected, gotAddrs)
+			assert.NotNil(t, gotCtx)
+		})
+	}
+}
+
+func TestNewMigrate(t *testing.T) {
+	var gotSteps int
+	var gotVersion uint
+
+	m := &mockedMigrator{
+		upFunc: func(ctx context.Context) (db.MigrationResult, error) {
+			return db.MigrationResult{Applied: []uint{2, 3}, Version: 3}, nil
+		},
+		downFunc: func(ctx context.Context, steps int) (db.MigrationResult, error) {
+			gotSteps = steps
+			return db.MigrationResult{Applied: []uint{3}, Version: 2}, nil
+		},
+		statusFunc: func(ctx context.Context) (db.MigrationStatus, error) {
+			return db.MigrationStatus{MigrationState: db.MigrationState{Version: 2, Dirty: true}, Latest: 3}, nil
+		},
+		forceFunc: func(ctx context.Context, version uint) error {
+			gotVersion = version
+			return nil
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		err      error
+		steps    int
+		version  uint
+	}{
+		{name: "up", args: []string{"up"}, expected: `{"applied":[2,3],"version":3}`},
+		{name: "down defaults to one", args: []string{"down"}, expected: `{"applied":[3],"version":2}`, steps: 1},
+		{name: "down n", args: []string{"down", "2"}, expected: `{"applied":[3],"version":2}`, steps: 2},
+		{name: "down rejects non-number", args: []string{"down", "all"}, err: db.ErrMigrationSteps},
+		{name: "status", args: []string{"status"}, expected: `{"version":2,"dirty":true,"latest":3,"migrations":null}`},
+		{name: "force", args: []string{"force", "2"}, expected: `{"version":2,"dirty":false}`, version: 2},
+		{name: "force rejects non-number", args: []string{"force", "latest"}, err: db.ErrMigrationVersion},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotSteps, gotVersion = 0, 0
+
+			cmd := NewMigrate(m)
+			buf := &bytes.Buffer{}
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			err := cmd.ExecuteContext(context.Background())
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, buf.String())
+			assert.Equal(t, tc.steps, gotSteps)
+			assert.Equal(t, tc.version, gotVersion)
+		})
+	}
+}
+
+func TestNewMigrate_WithoutMigrator(t *testing.T) {
+	cmd := NewMigrate(nil)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"status"})
+
+	assert.ErrorIs(t, cmd.ExecuteContext(context.Background()), ErrNoMigrator)
+}
+
+func TestNewRules(t *testing.T) {
+	var got shortener.DomainRule
+
+	m := &mockedRules{
+		domainRulesFunc: func() (shortener.DomainRules, error) {
+			return shortener.DomainRules{{Action: shortener.RuleDeny, Pattern: "*.phish.example"}}, nil
+		},
+		addFunc: func(rule shortener.DomainRule) (bool, error) {
+			got = rule
+			return true, nil
+		},
+		removeFunc: func(rule shortener.DomainRule) (bool, error) {
+			got = rule
+			return false, nil
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		err      error
+		rule     shortener.DomainRule
+	}{
+		{
+			name:     "add normalizes the pattern",
+			args:     []string{"add", "allow", "*.Example.COM."},
+			expected: `{"rule":"allow *.example.com","changed":true}`,
+			rule:     shortener.DomainRule{Action: shortener.RuleAllow, Pattern: "*.example.com"},
+		},
+		{
+			name:     "remove of a missing rule",
+			args:     []string{"remove", "deny", "example.org"},
+			expected: `{"rule":"deny example.org","changed":false}`,
+			rule:     shortener.DomainRule{Action: shortener.RuleDeny, Pattern: "example.org"},
+		},
+		{
+			name:     "list",
+			args:     []string{"list"},
+			expected: `{"path":"/etc/urlshortener.rules","rules":[{"action":"deny","pattern":"*.phish.example"}]}`,
+		},
+		{name: "unknown action", args: []string{"add", "block", "example.com"}, err: core.ErrInvalidArgs},
+		{name: "bad pattern", args: []string{"add", "deny", "exa mple.com"}, err: core.ErrInvalidArgs},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = shortener.DomainRule{}
+
+			cmd := NewRules(m)
+			buf := &bytes.Buffer{}
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			err := cmd.ExecuteContext(context.Background())
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, buf.String())
+			assert.Equal(t, tc.rule, got)
+		})
+	}
+}
+
 func TestNewRoot(t *testing.T) {
-	cmd := NewRoot(&mockedActions{})
+	cmd := NewRoot(&mockedActions{}, &mockedServer{}, &mockedMigrator{}, &mockedRules{})
 
 	assert.Equal(t, "urlshortener", cmd.Use)
 }
+
+func TestNewRoot_Output(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedOut    string
+		expectedErrMsg string
+	}{
+		{
+			name:        "json by default",
+			args:        []string{"get", "GL9VeCa"},
+			expectedOut: `{"shortCode":"GL9VeCa","rawUrl":"https://example.com"}` + "\n",
+		},
+		{
+			name:        "table",
+			args:        []string{"get", "GL9VeCa", "--output", "table"},
+			expectedOut: "CODE     URL                  EXPIRES\nGL9VeCa  https://example.com  -\n",
+		},
+		{
+			name:        "plain",
+			args:        []string{"--output=plain", "get", "GL9VeCa"},
+			expectedOut: "GL9VeCa\n",
+		},
+		{
+			name:           "unknown format",
+			args:           []string{"get", "GL9VeCa", "--output", "xml"},
+			expectedErrMsg: output.ErrFormat.Error(),
+		},
+		{
+			name:           "unknown flag",
+			args:           []string{"get", "GL9VeCa", "--colour"},
+			expectedErrMsg: "unknown flag: --colour",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mActions := &mockedActions{
+				getActionFunc: func(ctx context.Context, out io.Writer, args []string, opts core.GetOptions) error {
+					return output.Write(out, core.ResultResponse{ShortCode: args[0], RawURL: "https://example.com"})
+				},
+			}
+
+			cmd := NewRoot(mActions, &mockedServer{}, &mockedMigrator{}, &mockedRules{})
+			buf := &bytes.Buffer{}
+			cmd.SetOut(buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(tc.args)
+
+			err := cmd.ExecuteContext(context.Background())
+			if tc.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tc.expectedErrMsg)
+				assert.Equal(t, core.ExitInvalid, core.ExitCode(err), "flag errors are invalid input")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOut, buf.String())
+		})
+	}
+}
@@ -10,7 +10,7 @@ func NewDelete(acts core.Actions) *cobra.Command {
 		Use:   "delete <code>",
 		Short: "Delete a URL from the shortener service by short code",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return acts.DeleteAction(cmd.Context(), cmd.OutOrStdout(), args)
+			return acts.DeleteAction(cmd.Context(), outputWriter(cmd), args)
 		},
 	}
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/spf13/cobra"
+)
+
+func NewExport(acts core.Actions) *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every link, expired ones included, as JSON Lines or CSV",
+		Example: `
+		  	urlshortener export > links.jsonl
+  			urlshortener export --format csv --file links.csv`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			format, _ := cmd.Flags().GetString("format")
+			opts := core.ExportOptions{Format: format, Warnings: cmd.ErrOrStderr()}
+
+			if file == "" || file == "-" {
+				return acts.ExportAction(cmd.Context(), cmd.OutOrStdout(), opts)
+			}
+
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+
+			// A failed export would leave a truncated file that looks like a
+			// complete export, so it is removed.
+			err = acts.ExportAction(cmd.Context(), f, opts)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return errors.Join(err, os.Remove(file))
+			}
+			return nil
+		},
+	}
+
+	exportCmd.Flags().String("format", core.FormatJSONL, fmt.Sprintf("%s or %s", core.FormatJSONL, core.FormatCSV))
+	exportCmd.Flags().StringP("file", "f", "", `file to write to (default: stdout)`)
+
+	return exportCmd
+}
@@ -6,11 +6,17 @@ import (
 )
 
 func NewGet(acts core.Actions) *cobra.Command {
-	return &cobra.Command{
+	getCmd := &cobra.Command{
 		Use:   "get <code>",
 		Short: "Retrieve a URL from the shortener service",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return acts.GetAction(cmd.Context(), cmd.OutOrStdout(), args)
+			password, _ := cmd.Flags().GetString("password")
+
+			return acts.GetAction(cmd.Context(), outputWriter(cmd), args, core.GetOptions{Password: password})
 		},
 	}
+
+	getCmd.Flags().String("password", "", "password of a protected link")
+
+	return getCmd
 }
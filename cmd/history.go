@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/anewball/urlshortener/core"
+	"github.com/spf13/cobra"
+)
+
+func NewHistory(acts core.Actions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history <code>",
+		Short: "List every destination a short code has pointed to",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return acts.HistoryAction(cmd.Context(), outputWriter(cmd), args)
+		},
+	}
+}
+
+func NewRollback(acts core.Actions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback <code> <revision>",
+		Short: "Point a short code back at the destination of an earlier revision",
+		Example: `
+		  	urlshortener history spring-sale
+  			urlshortener rollback spring-sale 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return acts.RollbackAction(cmd.Context(), outputWriter(cmd), args)
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/spf13/cobra"
+)
+
+func NewImport(acts core.Actions) *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Shorten many URLs at once from a CSV or JSON Lines file",
+		Example: `
+		  	urlshortener import --file links.csv
+  			urlshortener import --file links.jsonl --batch-size 500
+  			cat links.txt | urlshortener import --file - --format csv`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			format, _ := cmd.Flags().GetString("format")
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+			if format == "" {
+				format = importFormat(file)
+			}
+
+			var in io.Reader = cmd.InOrStdin()
+			if file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			opts := core.ImportOptions{Format: format, BatchSize: batchSize}
+			return acts.ImportAction(cmd.Context(), outputWriter(cmd), in, opts)
+		},
+	}
+
+	importCmd.Flags().StringP("file", "f", "", `file to import, or "-" for stdin`)
+	importCmd.Flags().String("format", "", fmt.Sprintf("%s or %s (default: from the file extension)", core.FormatCSV, core.FormatJSONL))
+	importCmd.Flags().IntP("batch-size", "b", core.DefaultImportBatchSize, "max links to save per batch")
+	_ = importCmd.MarkFlagRequired("file")
+
+	return importCmd
+}
+
+// importFormat infers the input format from the file extension. Anything
+// unrecognised is passed through so ImportAction can report it.
+func importFormat(file string) string {
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".csv":
+		return core.FormatCSV
+	case ".jsonl", ".ndjson":
+		return core.FormatJSONL
+	default:
+		return strings.TrimPrefix(ext, ".")
+	}
+}
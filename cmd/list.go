@@ -8,20 +8,51 @@ import (
 func NewList(acts core.Actions) *cobra.Command {
 	listCmd := &cobra.Command{
 		Use:   "list",
-		Short: "List all URLs in the shortener service by offset and limit",
+		Short: "List all URLs in the shortener service a page at a time",
 		Example: `
 		  	urlshortener list --offset 0 --limit 10
-  			urlshortener list -o 0 -n 10`,
+  			urlshortener list -o 0 -n 10
+  			urlshortener list -n 10 --cursor <nextCursor>
+  			urlshortener list --host example.com --since 2025-08-01 --until 2025-08-31
+  			urlshortener list --contains docs --expired --sort code --order desc`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			limit, _ := cmd.Flags().GetInt("limit")
 			offset, _ := cmd.Flags().GetInt("offset")
+			cursor, _ := cmd.Flags().GetString("cursor")
+			host, _ := cmd.Flags().GetString("host")
+			contains, _ := cmd.Flags().GetString("contains")
+			since, _ := cmd.Flags().GetString("since")
+			until, _ := cmd.Flags().GetString("until")
+			expired, _ := cmd.Flags().GetBool("expired")
+			sort, _ := cmd.Flags().GetString("sort")
+			order, _ := cmd.Flags().GetString("order")
 
-			return acts.ListAction(cmd.Context(), limit, offset, cmd.OutOrStdout())
+			opts := core.ListOptions{
+				Limit:    limit,
+				Offset:   offset,
+				Cursor:   cursor,
+				Host:     host,
+				Contains: contains,
+				Since:    since,
+				Until:    until,
+				Expired:  expired,
+				Sort:     sort,
+				Order:    order,
+			}
+			return acts.ListAction(cmd.Context(), outputWriter(cmd), opts)
 		},
 	}
 
 	listCmd.Flags().IntP("limit", "n", 50, "max results to return")
 	listCmd.Flags().IntP("offset", "o", 0, "results to skip")
+	listCmd.Flags().String("cursor", "", "resume after the nextCursor of a previous page")
+	listCmd.Flags().String("host", "", "only links to this host or its subdomains")
+	listCmd.Flags().String("contains", "", "only links whose URL contains this text, ignoring case")
+	listCmd.Flags().String("since", "", "only links created at or after this RFC3339 time or date")
+	listCmd.Flags().String("until", "", "only links created at or before this RFC3339 time or date (a date includes the whole day)")
+	listCmd.Flags().Bool("expired", false, "include expired links")
+	listCmd.Flags().String("sort", "created", "sort by created, code or url")
+	listCmd.Flags().String("order", "", "asc or desc (default: desc for created, asc otherwise)")
 
 	return listCmd
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/anewball/urlshortener/internal/db"
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var ErrNoMigrator = errors.New("migrations are only available with the postgres storage backend")
+
+// NewMigrate manages the database schema with the migrations embedded in the
+// binary. m is nil when the configured backend has no schema to migrate.
+func NewMigrate(m db.Migrator) *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, revert or inspect database schema migrations",
+		// Overrides the root hook so a dirty schema never blocks the commands
+		// that repair it.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if m == nil {
+				return ErrNoMigrator
+			}
+			return nil
+		},
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := m.Up(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), res)
+		},
+	}
+
+	downCmd := &cobra.Command{
+		Use:   "down [n]",
+		Short: "Revert the last n migrations (default 1)",
+		Example: `
+		  	urlshortener migrate down
+  			urlshortener migrate down 2`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps := 1
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("%w: %q", db.ErrMigrationSteps, args[0])
+				}
+				steps = n
+			}
+
+			res, err := m.Down(cmd.Context(), steps)
+			if err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), res)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the current schema version and which migrations are applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status, err := m.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), status)
+		},
+	}
+
+	forceCmd := &cobra.Command{
+		Use:   "force <version>",
+		Short: "Record a schema version and clear the dirty flag without running SQL",
+		Example: `
+		  	urlshortener migrate force 2`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %q", db.ErrMigrationVersion, args[0])
+			}
+
+			if err := m.Force(cmd.Context(), uint(v)); err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), db.MigrationState{Version: uint(v)})
+		},
+	}
+
+	migrateCmd.AddCommand(upCmd, downCmd, statusCmd, forceCmd)
+
+	return migrateCmd
+}
@@ -5,29 +5,127 @@ import (
 	"io"
 
 	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/db"
+	"github.com/anewball/urlshortener/internal/rules"
+	"github.com/anewball/urlshortener/internal/server"
+	"github.com/anewball/urlshortener/internal/shortener"
 )
 
 var _ core.Actions = (*mockedActions)(nil)
 
 type mockedActions struct {
-	addActionFunc    func(ctx context.Context, out io.Writer, args []string) error
-	getActionFunc    func(ctx context.Context, out io.Writer, args []string) error
-	listActionFunc   func(ctx context.Context, limit int, offset int, out io.Writer) error
-	deleteActionFunc func(ctx context.Context, out io.Writer, args []string) error
+	addActionFunc      func(ctx context.Context, out io.Writer, args []string, opts core.AddOptions) error
+	importActionFunc   func(ctx context.Context, out io.Writer, in io.Reader, opts core.ImportOptions) error
+	getActionFunc      func(ctx context.Context, out io.Writer, args []string, opts core.GetOptions) error
+	listActionFunc     func(ctx context.Context, out io.Writer, opts core.ListOptions) error
+	exportActionFunc   func(ctx context.Context, out io.Writer, opts core.ExportOptions) error
+	updateActionFunc   func(ctx context.Context, out io.Writer, args []string) error
+	deleteActionFunc   func(ctx context.Context, out io.Writer, args []string) error
+	historyActionFunc  func(ctx context.Context, out io.Writer, args []string) error
+	rollbackActionFunc func(ctx context.Context, out io.Writer, args []string) error
+	purgeActionFunc    func(ctx context.Context, out io.Writer, batchSize int) error
+	statsActionFunc    func(ctx context.Context, out io.Writer, args []string) error
 }
 
-func (m *mockedActions) AddAction(ctx context.Context, out io.Writer, args []string) error {
-	return m.addActionFunc(ctx, out, args)
+func (m *mockedActions) AddAction(ctx context.Context, out io.Writer, args []string, opts core.AddOptions) error {
+	return m.addActionFunc(ctx, out, args, opts)
 }
 
-func (m *mockedActions) GetAction(ctx context.Context, out io.Writer, args []string) error {
-	return m.getActionFunc(ctx, out, args)
+func (m *mockedActions) ImportAction(ctx context.Context, out io.Writer, in io.Reader, opts core.ImportOptions) error {
+	return m.importActionFunc(ctx, out, in, opts)
 }
 
-func (m *mockedActions) ListAction(ctx context.Context, limit int, offset int, out io.Writer) error {
-	return m.listActionFunc(ctx, limit, offset, out)
+func (m *mockedActions) ExportAction(ctx context.Context, out io.Writer, opts core.ExportOptions) error {
+	return m.exportActionFunc(ctx, out, opts)
+}
+
+func (m *mockedActions) GetAction(ctx context.Context, out io.Writer, args []string, opts core.GetOptions) error {
+	return m.getActionFunc(ctx, out, args, opts)
+}
+
+func (m *mockedActions) ListAction(ctx context.Context, out io.Writer, opts core.ListOptions) error {
+	return m.listActionFunc(ctx, out, opts)
+}
+
+func (m *mockedActions) UpdateAction(ctx context.Context, out io.Writer, args []string) error {
+	return m.updateActionFunc(ctx, out, args)
 }
 
 func (m *mockedActions) DeleteAction(ctx context.Context, out io.Writer, args []string) error {
 	return m.deleteActionFunc(ctx, out, args)
 }
+
+func (m *mockedActions) HistoryAction(ctx context.Context, out io.Writer, args []string) error {
+	return m.historyActionFunc(ctx, out, args)
+}
+
+func (m *mockedActions) RollbackAction(ctx context.Context, out io.Writer, args []string) error {
+	return m.rollbackActionFunc(ctx, out, args)
+}
+
+func (m *mockedActions) PurgeAction(ctx context.Context, out io.Writer, batchSize int) error {
+	return m.purgeActionFunc(ctx, out, batchSize)
+}
+
+func (m *mockedActions) StatsAction(ctx context.Context, out io.Writer, args []string) error {
+	return m.statsActionFunc(ctx, out, args)
+}
+
+var _ server.Server = (*mockedServer)(nil)
+
+type mockedServer struct {
+	serveFunc func(ctx context.Context, addrs server.Addrs) error
+}
+
+func (m *mockedServer) Serve(ctx context.Context, addrs server.Addrs) error {
+	return m.serveFunc(ctx, addrs)
+}
+
+var _ db.Migrator = (*mockedMigrator)(nil)
+
+type mockedMigrator struct {
+	upFunc     func(ctx context.Context) (db.MigrationResult, error)
+	downFunc   func(ctx context.Context, steps int) (db.MigrationResult, error)
+	statusFunc func(ctx context.Context) (db.MigrationStatus, error)
+	forceFunc  func(ctx context.Context, version uint) error
+}
+
+func (m *mockedMigrator) Up(ctx context.Context) (db.MigrationResult, error) {
+	return m.upFunc(ctx)
+}
+
+func (m *mockedMigrator) Down(ctx context.Context, steps int) (db.MigrationResult, error) {
+	return m.downFunc(ctx, steps)
+}
+
+func (m *mockedMigrator) Status(ctx context.Context) (db.MigrationStatus, error) {
+	return m.statusFunc(ctx)
+}
+
+func (m *mockedMigrator) Force(ctx context.Context, version uint) error {
+	return m.forceFunc(ctx, version)
+}
+
+var _ rules.Manager = (*mockedRules)(nil)
+
+type mockedRules struct {
+	domainRulesFunc func() (shortener.DomainRules, error)
+	addFunc         func(rule shortener.DomainRule) (bool, error)
+	removeFunc      func(rule shortener.DomainRule) (bool, error)
+}
+
+func (m *mockedRules) DomainRules() (shortener.DomainRules, error) {
+	return m.domainRulesFunc()
+}
+
+func (m *mockedRules) Path() string {
+	return "/etc/urlshortener.rules"
+}
+
+func (m *mockedRules) Add(rule shortener.DomainRule) (bool, error) {
+	return m.addFunc(rule)
+}
+
+func (m *mockedRules) Remove(rule shortener.DomainRule) (bool, error) {
+	return m.removeFunc(rule)
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// outputWriter returns where cmd should write its response, rendered in the
+// format of the root --output flag. Commands run on their own, as in tests,
+// have no such flag and write JSON.
+func outputWriter(cmd *cobra.Command) io.Writer {
+	format := output.JSON
+	if f := cmd.Flags().Lookup("output"); f != nil {
+		format = f.Value.String()
+	}
+	return output.NewWriter(cmd.OutOrStdout(), format)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/spf13/cobra"
+)
+
+func NewPurgeExpired(acts core.Actions) *cobra.Command {
+	purgeCmd := &cobra.Command{
+		Use:   "purge-expired",
+		Short: "Delete expired links from the shortener service",
+		Example: `
+		  	urlshortener purge-expired
+  			urlshortener purge-expired --batch-size 500`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+			return acts.PurgeAction(cmd.Context(), outputWriter(cmd), batchSize)
+		},
+	}
+
+	purgeCmd.Flags().IntP("batch-size", "b", shortener.DefaultPurgeBatchSize, "max rows to delete per batch")
+
+	return purgeCmd
+}
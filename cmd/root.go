@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/db"
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/anewball/urlshortener/internal/rules"
+	"github.com/anewball/urlshortener/internal/server"
 	"github.com/spf13/cobra"
 )
 
-func NewRoot(acts core.Actions) *cobra.Command {
+func NewRoot(acts core.Actions, srv server.Server, m db.Migrator, r rules.Manager) *cobra.Command {
 	var cfgFile string
+	var format output.Format
 
 	rootCmd := &cobra.Command{
 		Use:           "urlshortener",
@@ -17,10 +25,18 @@ func NewRoot(acts core.Actions) *cobra.Command {
 		Version:       "0.1.0",
 	}
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.urlshortener.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "YAML, TOML or JSON config file (default is $HOME/.urlshortener.yaml); flags override env vars, which override the file")
+	// Bad flags are bad input, whichever command they were given to.
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %v", core.ErrInvalidArgs, err)
+	})
+
+	// No -o shorthand: list already uses it for --offset.
+	rootCmd.PersistentFlags().Var(&format, "output", fmt.Sprintf("output format: %s", strings.Join(output.Formats, ", ")))
 	rootCmd.PersistentFlags().String("author", "Andy Newball", "author of the URL shortener")
 
-	rootCmd.AddCommand(NewAdd(acts), NewDelete(acts), NewGet(acts), NewList(acts))
+	rootCmd.AddCommand(NewAdd(acts), NewImport(acts), NewExport(acts), NewDelete(acts), NewGet(acts), NewList(acts), NewUpdate(acts), NewServe(srv),
+		NewPurgeExpired(acts), NewStats(acts), NewHistory(acts), NewRollback(acts), NewMigrate(m), NewRules(r))
 
 	return rootCmd
 }
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/anewball/urlshortener/internal/rules"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/spf13/cobra"
+)
+
+// NewRules manages the domain allow and deny rules that add and update
+// enforce, kept in the file set by DOMAIN_RULES_FILE.
+func NewRules(m rules.Manager) *cobra.Command {
+	rulesCmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Manage the domains links may and may not point to",
+		Long: `Manage the domains links may and may not point to.
+
+A pattern is a domain, which matches only itself, or *.domain, which matches
+every subdomain. Deny rules always win. Once there is an allow rule, links
+must also match an allow rule.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <allow|deny> <pattern>",
+		Short: "Add an allow or deny rule",
+		Example: `
+		  	urlshortener rules add allow example.com
+  			urlshortener rules add allow "*.example.com"
+  			urlshortener rules add deny "*.phish.example"`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := parseRuleArgs(args)
+			if err != nil {
+				return err
+			}
+			added, err := m.Add(rule)
+			if err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), rules.Change{Rule: rule.String(), Changed: added})
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <allow|deny> <pattern>",
+		Short: "Remove an allow or deny rule",
+		Example: `
+		  	urlshortener rules remove deny "*.phish.example"`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, err := parseRuleArgs(args)
+			if err != nil {
+				return err
+			}
+			removed, err := m.Remove(rule)
+			if err != nil {
+				return err
+			}
+			return output.Write(outputWriter(cmd), rules.Change{Rule: rule.String(), Changed: removed})
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the rules in the order they were added",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rs, err := m.DomainRules()
+			if err != nil {
+				return err
+			}
+			if rs == nil {
+				rs = shortener.DomainRules{}
+			}
+			return output.Write(outputWriter(cmd), rules.List{Path: m.Path(), Rules: rs})
+		},
+	}
+
+	rulesCmd.AddCommand(addCmd, removeCmd, listCmd)
+
+	return rulesCmd
+}
+
+// parseRuleArgs reads the action and pattern arguments of rules add and
+// remove. A malformed rule is bad input, whatever the subcommand.
+func parseRuleArgs(args []string) (shortener.DomainRule, error) {
+	rule, err := shortener.ParseRule(args[0], args[1])
+	if err != nil {
+		return shortener.DomainRule{}, fmt.Errorf("%w: %v", core.ErrInvalidArgs, err)
+	}
+	return rule, nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/anewball/urlshortener/internal/server"
+	"github.com/spf13/cobra"
+)
+
+func NewServe(srv server.Server) *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start an HTTP server that redirects short codes to their original URLs",
+		Long: `Start an HTTP server that redirects short codes to their original URLs.
+
+The management API under /api/v1 is served on its own address. It has no
+authentication, so keep --api-addr on loopback or a private network.
+
+With --debug-addr, expvar counters such as shortener_code_collisions are
+served at /debug/vars. They include the command line, so keep that address
+on loopback too.`,
+		Example: `
+		  	urlshortener serve --addr :8080
+		  	urlshortener serve --addr :8080 --api-addr 10.0.0.5:8081
+		  	urlshortener serve --debug-addr 127.0.0.1:6060`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			apiAddr, _ := cmd.Flags().GetString("api-addr")
+			debugAddr, _ := cmd.Flags().GetString("debug-addr")
+
+			return srv.Serve(cmd.Context(), server.Addrs{Redirect: addr, API: apiAddr, Debug: debugAddr})
+		},
+	}
+
+	serveCmd.Flags().StringP("addr", "a", ":8080", "address to serve redirects on")
+	serveCmd.Flags().String("api-addr", "127.0.0.1:8081", "address to serve the management API on; empty disables it")
+	serveCmd.Flags().String("debug-addr", "", "address to serve expvar at /debug/vars on, e.g. 127.0.0.1:6060; off by default")
+
+	return serveCmd
+}
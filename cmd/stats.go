@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/anewball/urlshortener/core"
+	"github.com/spf13/cobra"
+)
+
+func NewStats(acts core.Actions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats <code>",
+		Short: "Show click statistics for a short code",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return acts.StatsAction(cmd.Context(), outputWriter(cmd), args)
+		},
+	}
+}
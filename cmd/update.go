@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/anewball/urlshortener/core"
+	"github.com/spf13/cobra"
+)
+
+func NewUpdate(acts core.Actions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "update <code> <url>",
+		Short: "Change the URL an existing short code redirects to",
+		Example: `
+		  	urlshortener update spring-sale https://example.com/summer`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return acts.UpdateAction(cmd.Context(), outputWriter(cmd), args)
+		},
+	}
+}
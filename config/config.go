@@ -2,31 +2,87 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anewball/urlshortener/env"
 )
 
+// Storage backends selectable through STORAGE_BACKEND.
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+	BackendFile     = "file"
+)
+
+// Query parameter policies selectable through PARAM_POLICY. keep, the
+// default, leaves URLs alone; strip removes the parameters matching
+// PARAM_PATTERNS, or common tracking parameters when none are given; allow
+// keeps only those matching.
+const (
+	ParamPolicyStrip = "strip"
+	ParamPolicyKeep  = "keep"
+	ParamPolicyAllow = "allow"
+)
+
+// defaultRulesFile is the name of the domain rules file when
+// DOMAIN_RULES_FILE is not set. It lives in the user's home directory.
+const defaultRulesFile = ".urlshortener.rules"
+
+// defaultStorageFile is the name of the file backend's data file when
+// STORAGE_PATH is not set. It lives in the user's home directory.
+const defaultStorageFile = ".urlshortener.json"
+
 type Config struct {
-	User            string
-	Password        string
-	Database        string
-	URL             string
-	MaxConns        int32
-	MinConns        int32
-	MaxConnLifetime time.Duration
-	MaxConnIdleTime time.Duration
-	ListMaxLimit    int
+	User               string
+	Password           string
+	Database           string
+	URL                string
+	MaxConns           int32
+	MinConns           int32
+	MaxConnLifetime    time.Duration
+	MaxConnIdleTime    time.Duration
+	ListMaxLimit       int
+	PurgeInterval      time.Duration
+	PurgeBatchSize     int
+	AddMaxAttempts     int
+	StorageBackend     string
+	StoragePath        string
+	AutoMigrate        bool
+	SortQueryParams    bool
+	ParamPolicy        string
+	ParamPatterns      []string
+	RejectMixedScripts bool
+	// BlockPrivateDestinations refuses links to loopback, private and
+	// link-local addresses and internal hostnames. It defaults to true.
+	BlockPrivateDestinations bool
+	// DomainRulesFile holds the domain allow and deny rules. A missing file
+	// means no rules.
+	DomainRulesFile string
 }
 
+// ErrInvalidValue reports a setting whose value cannot be parsed as the
+// type the setting needs.
+var ErrInvalidValue = errors.New("invalid config value")
+
 type Builder struct {
 	db Config
 	en env.Env
+	// errs collects the settings FromEnv could not parse, so Build can
+	// report all of them at once.
+	errs []error
 }
 
 func NewBuilder(en env.Env) *Builder {
-	return &Builder{db: Config{}, en: en}
+	return &Builder{db: Config{
+		StorageBackend:           BackendPostgres,
+		ParamPolicy:              ParamPolicyKeep,
+		BlockPrivateDestinations: true,
+	}, en: en}
 }
 
 func (b *Builder) FromEnv() *Builder {
@@ -42,38 +98,146 @@ func (b *Builder) FromEnv() *Builder {
 	if v, err := b.en.Get("POSTGRES_DB"); err == nil {
 		b.db.Database = v
 	}
-	if v, err := b.en.Get("DB_MAX_CONNS"); err == nil {
-		if n, err := strconv.Atoi(v); err == nil {
-			b.db.MaxConns = int32(n)
-		}
+	if n, ok := b.getInt("DB_MAX_CONNS"); ok {
+		b.db.MaxConns = int32(n)
 	}
-	if v, err := b.en.Get("DB_MIN_CONNS"); err == nil {
-		if n, err := strconv.Atoi(v); err == nil {
-			b.db.MinConns = int32(n)
-		}
+	if n, ok := b.getInt("DB_MIN_CONNS"); ok {
+		b.db.MinConns = int32(n)
 	}
-	if v, err := b.en.Get("DB_MAX_CONN_LIFETIME"); err == nil {
-		if d, err := time.ParseDuration(v); err == nil {
-			b.db.MaxConnLifetime = d
-		}
+	if d, ok := b.getDuration("DB_MAX_CONN_LIFETIME"); ok {
+		b.db.MaxConnLifetime = d
 	}
-	if v, err := b.en.Get("DB_MAX_CONN_IDLE_TIME"); err == nil {
-		if d, err := time.ParseDuration(v); err == nil {
-			b.db.MaxConnIdleTime = d
-		}
+	if d, ok := b.getDuration("DB_MAX_CONN_IDLE_TIME"); ok {
+		b.db.MaxConnIdleTime = d
 	}
-	if v, err := b.en.Get("LIST_MAX_LIMIT"); err == nil {
-		if n, err := strconv.Atoi(v); err == nil {
-			b.db.ListMaxLimit = n
-		}
+	if n, ok := b.getInt("LIST_MAX_LIMIT"); ok {
+		b.db.ListMaxLimit = n
+	}
+	if d, ok := b.getDuration("PURGE_INTERVAL"); ok {
+		b.db.PurgeInterval = d
+	}
+	if n, ok := b.getInt("PURGE_BATCH_SIZE"); ok {
+		b.db.PurgeBatchSize = n
+	}
+	if n, ok := b.getInt("ADD_MAX_ATTEMPTS"); ok {
+		b.db.AddMaxAttempts = n
+	}
+	if v, err := b.en.Get("STORAGE_BACKEND"); err == nil {
+		b.db.StorageBackend = strings.ToLower(v)
+	}
+	if v, err := b.en.Get("STORAGE_PATH"); err == nil {
+		b.db.StoragePath = v
+	}
+	if v, ok := b.getBool("AUTO_MIGRATE"); ok {
+		b.db.AutoMigrate = v
+	}
+	if v, ok := b.getBool("SORT_QUERY_PARAMS"); ok {
+		b.db.SortQueryParams = v
+	}
+	if v, ok := b.getBool("REJECT_MIXED_SCRIPTS"); ok {
+		b.db.RejectMixedScripts = v
+	}
+	if v, ok := b.getBool("BLOCK_PRIVATE_DESTINATIONS"); ok {
+		b.db.BlockPrivateDestinations = v
+	}
+	if v, err := b.en.Get("DOMAIN_RULES_FILE"); err == nil {
+		b.db.DomainRulesFile = v
+	}
+	if v, err := b.en.Get("PARAM_POLICY"); err == nil {
+		b.db.ParamPolicy = strings.ToLower(v)
+	}
+	if v, err := b.en.Get("PARAM_PATTERNS"); err == nil {
+		b.db.ParamPatterns = splitList(v)
 	}
 	return b
 }
 
+// getInt reads key as a whole number. ok is false when key is unset or
+// malformed; a malformed value is recorded for Build to report.
+func (b *Builder) getInt(key string) (n int, ok bool) {
+	v, err := b.en.Get(key)
+	if err != nil {
+		return 0, false
+	}
+	n, err = strconv.Atoi(v)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("%w %s=%q: want a whole number", ErrInvalidValue, key, v))
+		return 0, false
+	}
+	return n, true
+}
+
+// getDuration reads key as a duration such as 90s or 1h30m. A bare number
+// is rejected rather than guessed at, as its unit would be ambiguous.
+func (b *Builder) getDuration(key string) (d time.Duration, ok bool) {
+	v, err := b.en.Get(key)
+	if err != nil {
+		return 0, false
+	}
+	d, err = time.ParseDuration(v)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("%w %s=%q: want a duration with a unit, such as 30s or 1h", ErrInvalidValue, key, v))
+		return 0, false
+	}
+	return d, true
+}
+
+// getBool reads key as a boolean: true, false, 1, 0, t or f.
+func (b *Builder) getBool(key string) (v, ok bool) {
+	s, err := b.en.Get(key)
+	if err != nil {
+		return false, false
+	}
+	v, err = strconv.ParseBool(s)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("%w %s=%q: want true or false", ErrInvalidValue, key, s))
+		return false, false
+	}
+	return v, true
+}
+
 func (b *Builder) validate() error {
+	switch b.db.StorageBackend {
+	case BackendPostgres:
+	case BackendMemory, BackendFile:
+		// Neither backend talks to Postgres, so none of the connection
+		// settings below are required.
+		return b.validateLimits()
+	default:
+		return fmt.Errorf("unknown storage backend %q; want %s, %s or %s",
+			b.db.StorageBackend, BackendPostgres, BackendMemory, BackendFile)
+	}
+
 	if b.db.URL == "" {
 		return errors.New("URL is required")
 	}
+	if err := b.validateLimits(); err != nil {
+		return err
+	}
+	if b.db.Password == "" {
+		return errors.New("password is required")
+	}
+	if b.db.User == "" {
+		return errors.New("user is required")
+	}
+	if b.db.Database == "" {
+		return errors.New("database is required")
+	}
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (b *Builder) validateLimits() error {
 	if b.db.MaxConns < 0 {
 		return errors.New("MaxConns must be >= 0")
 	}
@@ -86,24 +250,53 @@ func (b *Builder) validate() error {
 	if b.db.MaxConnIdleTime < 0 {
 		return errors.New("MaxConnIdleTime must be >= 0")
 	}
-	if b.db.MaxConns > 0 && b.db.MinConns > b.db.MaxConns {
-		return errors.New("MinConns must be <= MaxConns")
+	if b.db.PurgeInterval < 0 {
+		return errors.New("PurgeInterval must be >= 0")
 	}
-	if b.db.Password == "" {
-		return errors.New("password is required")
+	if b.db.PurgeBatchSize < 0 {
+		return errors.New("PurgeBatchSize must be >= 0")
 	}
-	if b.db.User == "" {
-		return errors.New("user is required")
+	if b.db.AddMaxAttempts < 0 {
+		return errors.New("AddMaxAttempts must be >= 0")
 	}
-	if b.db.Database == "" {
-		return errors.New("database is required")
+	if b.db.MaxConns > 0 && b.db.MinConns > b.db.MaxConns {
+		return errors.New("MinConns must be <= MaxConns")
+	}
+	switch b.db.ParamPolicy {
+	case ParamPolicyStrip, ParamPolicyKeep, ParamPolicyAllow:
+	default:
+		return fmt.Errorf("unknown param policy %q; want %s, %s or %s",
+			b.db.ParamPolicy, ParamPolicyStrip, ParamPolicyKeep, ParamPolicyAllow)
 	}
 	return nil
 }
 
 func (b *Builder) Build() (Config, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return Config{}, err
+	}
 	if err := b.validate(); err != nil {
 		return Config{}, err
 	}
+	if b.db.StorageBackend == BackendFile && b.db.StoragePath == "" {
+		b.db.StoragePath = defaultStoragePath()
+	}
+	if b.db.DomainRulesFile == "" {
+		b.db.DomainRulesFile = homeFile(defaultRulesFile)
+	}
 	return b.db, nil
 }
+
+func defaultStoragePath() string {
+	return homeFile(defaultStorageFile)
+}
+
+// homeFile returns the path of name in the user's home directory, or name
+// itself when there is no home directory.
+func homeFile(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+	return filepath.Join(home, name)
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/anewball/urlshortener/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild_StorageBackend(t *testing.T) {
+	postgres := map[string]string{
+		"DB_URL":            "postgres://localhost:5432/urls",
+		"POSTGRES_USER":     "user",
+		"POSTGRES_PASSWORD": "secret",
+		"POSTGRES_DB":       "urls",
+	}
+
+	testCases := []struct {
+		name            string
+		envMap          map[string]string
+		expectedBackend string
+		expectedPath    string
+		expectedErr     string
+	}{
+		{
+			name:            "defaults to postgres",
+			envMap:          postgres,
+			expectedBackend: BackendPostgres,
+		},
+		{
+			name:        "postgres requires connection settings",
+			envMap:      map[string]string{"STORAGE_BACKEND": "postgres"},
+			expectedErr: "URL is required",
+		},
+		{
+			name:            "memory needs no connection settings",
+			envMap:          map[string]string{"STORAGE_BACKEND": "Memory"},
+			expectedBackend: BackendMemory,
+		},
+		{
+			name:            "file uses the configured path",
+			envMap:          map[string]string{"STORAGE_BACKEND": "file", "STORAGE_PATH": "/tmp/links.json"},
+			expectedBackend: BackendFile,
+			expectedPath:    "/tmp/links.json",
+		},
+		{
+			name:        "unknown backend",
+			envMap:      map[string]string{"STORAGE_BACKEND": "redis"},
+			expectedErr: `unknown storage backend "redis"`,
+		},
+		{
+			name:        "limits still validated without postgres",
+			envMap:      map[string]string{"STORAGE_BACKEND": "memory", "PURGE_BATCH_SIZE": "-1"},
+			expectedErr: "PurgeBatchSize must be >= 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := NewBuilder(env.New(tc.envMap)).FromEnv().Build()
+
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBackend, cfg.StorageBackend)
+			if tc.expectedPath != "" {
+				assert.Equal(t, tc.expectedPath, cfg.StoragePath)
+			}
+		})
+	}
+}
+
+func TestBuild_DefaultFilePath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "file"})).FromEnv().Build()
+
+	require.NoError(t, err)
+	assert.Contains(t, cfg.StoragePath, defaultStorageFile)
+}
+
+func TestBuild_DomainRulesFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory"})).FromEnv().Build()
+	require.NoError(t, err)
+	assert.Contains(t, cfg.DomainRulesFile, defaultRulesFile)
+
+	cfg, err = NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory", "DOMAIN_RULES_FILE": "/etc/urlshortener.rules"})).FromEnv().Build()
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/urlshortener.rules", cfg.DomainRulesFile)
+}
+
+func TestBuild_AutoMigrate(t *testing.T) {
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory", "AUTO_MIGRATE": "true"})).FromEnv().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.AutoMigrate)
+}
+
+func TestBuild_RejectMixedScripts(t *testing.T) {
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory", "REJECT_MIXED_SCRIPTS": "1"})).FromEnv().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.RejectMixedScripts)
+}
+
+func TestBuild_BlockPrivateDestinations(t *testing.T) {
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory"})).FromEnv().Build()
+	require.NoError(t, err)
+	assert.True(t, cfg.BlockPrivateDestinations, "on by default")
+
+	cfg, err = NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory", "BLOCK_PRIVATE_DESTINATIONS": "false"})).FromEnv().Build()
+	require.NoError(t, err)
+	assert.False(t, cfg.BlockPrivateDestinations)
+}
+
+func TestBuild_SortQueryParams(t *testing.T) {
+	cfg, err := NewBuilder(env.New(map[string]string{"STORAGE_BACKEND": "memory", "SORT_QUERY_PARAMS": "true"})).FromEnv().Build()
+
+	require.NoError(t, err)
+	assert.True(t, cfg.SortQueryParams)
+}
+
+func TestBuild_ParamPolicy(t *testing.T) {
+	testCases := []struct {
+		name             string
+		envMap           map[string]string
+		expectedPolicy   string
+		expectedPatterns []string
+		expectedErr      string
+	}{
+		{
+			name:           "defaults to keep",
+			envMap:         map[string]string{"STORAGE_BACKEND": "memory"},
+			expectedPolicy: ParamPolicyKeep,
+		},
+		{
+			name:           "strip is opt-in",
+			envMap:         map[string]string{"STORAGE_BACKEND": "memory", "PARAM_POLICY": "strip"},
+			expectedPolicy: ParamPolicyStrip,
+		},
+		{
+			name:             "allow list with patterns",
+			envMap:           map[string]string{"STORAGE_BACKEND": "memory", "PARAM_POLICY": "Allow", "PARAM_PATTERNS": "id, page,,q*"},
+			expectedPolicy:   ParamPolicyAllow,
+			expectedPatterns: []string{"id", "page", "q*"},
+		},
+		{
+			name:        "unknown policy",
+			envMap:      map[string]string{"STORAGE_BACKEND": "memory", "PARAM_POLICY": "drop"},
+			expectedErr: `unknown param policy "drop"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := NewBuilder(env.New(tc.envMap)).FromEnv().Build()
+
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPolicy, cfg.ParamPolicy)
+			assert.Equal(t, tc.expectedPatterns, cfg.ParamPatterns)
+		})
+	}
+}
+
+func TestBuild_RejectsMalformedValues(t *testing.T) {
+	testCases := []struct {
+		name        string
+		envMap      map[string]string
+		expectedErr []string
+	}{
+		{
+			name:        "misspelled bool",
+			envMap:      map[string]string{"BLOCK_PRIVATE_DESTINATIONS": "flase"},
+			expectedErr: []string{`BLOCK_PRIVATE_DESTINATIONS="flase"`},
+		},
+		{
+			name:        "unsupported bool word",
+			envMap:      map[string]string{"REJECT_MIXED_SCRIPTS": "yes"},
+			expectedErr: []string{`REJECT_MIXED_SCRIPTS="yes"`},
+		},
+		{
+			name:        "duration without a unit",
+			envMap:      map[string]string{"PURGE_INTERVAL": "5"},
+			expectedErr: []string{`PURGE_INTERVAL="5"`, "30s"},
+		},
+		{
+			name:        "number with a unit",
+			envMap:      map[string]string{"DB_MAX_CONNS": "10 conns"},
+			expectedErr: []string{`DB_MAX_CONNS="10 conns"`},
+		},
+		{
+			name:        "every malformed key is named",
+			envMap:      map[string]string{"AUTO_MIGRATE": "on", "ADD_MAX_ATTEMPTS": "many"},
+			expectedErr: []string{`AUTO_MIGRATE="on"`, `ADD_MAX_ATTEMPTS="many"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.envMap["STORAGE_BACKEND"] = "memory"
+
+			_, err := NewBuilder(env.New(tc.envMap)).FromEnv().Build()
+
+			assert.ErrorIs(t, err, ErrInvalidValue)
+			for _, want := range tc.expectedErr {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultConfigFile is read when --config is not given. A missing default
+// file is not an error.
+const DefaultConfigFile = ".urlshortener.yaml"
+
+var (
+	ErrConfigFile = errors.New("invalid config file")
+	ErrUnknownKey = errors.New("unknown config key")
+)
+
+// Keys names every setting Builder.FromEnv understands. Config files use the
+// same names; keys are matched case-insensitively, so db_url and DB_URL are
+// the same setting.
+var Keys = []string{
+	"POSTGRES_USER",
+	"POSTGRES_PASSWORD",
+	"POSTGRES_DB",
+	"DB_MAX_CONNS",
+	"DB_MIN_CONNS",
+	"DB_MAX_CONN_LIFETIME",
+	"DB_MAX_CONN_IDLE_TIME",
+	"DB_URL",
+	"LIST_MAX_LIMIT",
+	"PURGE_INTERVAL",
+	"PURGE_BATCH_SIZE",
+	"ADD_MAX_ATTEMPTS",
+	"STORAGE_BACKEND",
+	"STORAGE_PATH",
+	"AUTO_MIGRATE",
+	"SORT_QUERY_PARAMS",
+	"PARAM_POLICY",
+	"PARAM_PATTERNS",
+	"REJECT_MIXED_SCRIPTS",
+	"BLOCK_PRIVATE_DESTINATIONS",
+	"DOMAIN_RULES_FILE",
+}
+
+// Load merges the config file at path with the environment and returns every
+// setting in Keys, ready for env.New. Settings resolve in this order, highest
+// first:
+//
+//  1. environment variables, including those loaded from .env
+//  2. the config file
+//  3. the defaults applied by Builder
+//
+// No command-line flag sets a key; flags such as --addr configure only the
+// command they belong to. A list in the config file, as in
+// param_patterns: [utm_*, fbclid], becomes the comma-separated value the
+// environment variable would hold.
+//
+// path may be a YAML, TOML or JSON file, chosen by its extension. When path
+// is empty $HOME/.urlshortener.yaml is used if it exists.
+func Load(path string) (map[string]string, error) {
+	v := viper.New()
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path != "" {
+		if err := readConfigFile(v, path, explicit); err != nil {
+			return nil, err
+		}
+	}
+
+	v.AutomaticEnv()
+	for _, k := range Keys {
+		_ = v.BindEnv(k)
+	}
+
+	settings := make(map[string]string, len(Keys))
+	for _, k := range Keys {
+		value, err := settingValue(v, k)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrConfigFile, strings.ToLower(k), err)
+		}
+		settings[k] = strings.TrimSpace(value)
+	}
+	return settings, nil
+}
+
+// settingValue returns key as a string. Lists of scalars are joined with
+// commas; anything more deeply nested is rejected rather than read as "".
+func settingValue(v *viper.Viper, key string) (string, error) {
+	list, ok := v.Get(key).([]any)
+	if !ok {
+		return v.GetString(key), nil
+	}
+
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		switch item.(type) {
+		case []any, map[string]any:
+			return "", errors.New("list items must be plain values")
+		}
+		items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+	}
+	return strings.Join(items, ","), nil
+}
+
+func readConfigFile(v *viper.Viper, path string, explicit bool) error {
+	if _, err := os.Stat(path); err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrConfigFile, err)
+	}
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrConfigFile, path, err)
+	}
+
+	for _, k := range v.AllKeys() {
+		if !slices.Contains(Keys, strings.ToUpper(k)) {
+			return fmt.Errorf("%w %q in %s", ErrUnknownKey, k, path)
+		}
+	}
+	return nil
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultConfigFile)
+}
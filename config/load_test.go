@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_FileFormats(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `
+storage_backend: memory
+list_max_limit: 200
+purge_interval: 1h
+auto_migrate: true
+`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `
+STORAGE_BACKEND = "memory"
+LIST_MAX_LIMIT = 200
+PURGE_INTERVAL = "1h"
+AUTO_MIGRATE = true
+`,
+		},
+		{
+			name:    "json",
+			file:    "config.json",
+			content: `{"storage_backend": "memory", "list_max_limit": 200, "purge_interval": "1h", "auto_migrate": true}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, err := Load(writeFile(t, tc.file, tc.content))
+			require.NoError(t, err)
+
+			cfg, err := NewBuilder(env.New(settings)).FromEnv().Build()
+			require.NoError(t, err)
+			assert.Equal(t, BackendMemory, cfg.StorageBackend)
+			assert.Equal(t, 200, cfg.ListMaxLimit)
+			assert.Equal(t, time.Hour, cfg.PurgeInterval)
+			assert.True(t, cfg.AutoMigrate)
+		})
+	}
+}
+
+func TestLoad_Lists(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml flow", file: "config.yaml", content: "storage_backend: memory\nparam_policy: strip\nparam_patterns: [utm_*, fbclid]\n"},
+		{name: "yaml block", file: "config.yaml", content: "storage_backend: memory\nparam_policy: strip\nparam_patterns:\n  - utm_*\n  - fbclid\n"},
+		{name: "toml", file: "config.toml", content: "STORAGE_BACKEND = \"memory\"\nPARAM_POLICY = \"strip\"\nPARAM_PATTERNS = [\"utm_*\", \"fbclid\"]\n"},
+		{name: "json", file: "config.json", content: `{"storage_backend": "memory", "param_policy": "strip", "param_patterns": ["utm_*", "fbclid"]}`},
+		{name: "string", file: "config.yaml", content: "storage_backend: memory\nparam_policy: strip\nparam_patterns: utm_*, fbclid\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, err := Load(writeFile(t, tc.file, tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, "utm_*,fbclid", strings.ReplaceAll(settings["PARAM_PATTERNS"], " ", ""))
+
+			cfg, err := NewBuilder(env.New(settings)).FromEnv().Build()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"utm_*", "fbclid"}, cfg.ParamPatterns)
+		})
+	}
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "list_max_limit: 200\npurge_batch_size: 10\n")
+	t.Setenv("LIST_MAX_LIMIT", "300")
+
+	settings, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "300", settings["LIST_MAX_LIMIT"])
+	assert.Equal(t, "10", settings["PURGE_BATCH_SIZE"])
+}
+
+func TestLoad_DefaultFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	settings, err := Load("")
+	require.NoError(t, err, "a missing default file is fine")
+	assert.Empty(t, settings["STORAGE_PATH"])
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, DefaultConfigFile), []byte("storage_path: /tmp/links.json\n"), 0o600))
+	settings, err = Load("")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/links.json", settings["STORAGE_PATH"])
+}
+
+func TestLoad_Errors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		path        func(t *testing.T) string
+		expectedErr error
+		contains    string
+	}{
+		{
+			name:        "missing explicit file",
+			path:        func(t *testing.T) string { return filepath.Join(t.TempDir(), "nope.yaml") },
+			expectedErr: ErrConfigFile,
+		},
+		{
+			name:        "malformed yaml",
+			path:        func(t *testing.T) string { return writeFile(t, "config.yaml", "db_url: [unterminated\n") },
+			expectedErr: ErrConfigFile,
+		},
+		{
+			name:        "malformed json",
+			path:        func(t *testing.T) string { return writeFile(t, "config.json", "{") },
+			expectedErr: ErrConfigFile,
+		},
+		{
+			name:        "unsupported extension",
+			path:        func(t *testing.T) string { return writeFile(t, "config.ini", "db_url=x") },
+			expectedErr: ErrConfigFile,
+		},
+		{
+			name:        "unknown key",
+			path:        func(t *testing.T) string { return writeFile(t, "config.yaml", "db_url: x\nlist_limit: 5\n") },
+			expectedErr: ErrUnknownKey,
+			contains:    `"list_limit"`,
+		},
+		{
+			name:        "nested list",
+			path:        func(t *testing.T) string { return writeFile(t, "config.yaml", "param_patterns: [[utm_*], fbclid]\n") },
+			expectedErr: ErrConfigFile,
+			contains:    "param_patterns",
+		},
+		{
+			name: "list of maps",
+			path: func(t *testing.T) string {
+				return writeFile(t, "config.json", `{"param_patterns": [{"name": "utm_*"}]}`)
+			},
+			expectedErr: ErrConfigFile,
+			contains:    "param_patterns",
+		},
+		{
+			name:        "nested key",
+			path:        func(t *testing.T) string { return writeFile(t, "config.yaml", "db:\n  url: x\n") },
+			expectedErr: ErrUnknownKey,
+			contains:    `"db.url"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.path(t))
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.contains != "" {
+				assert.ErrorContains(t, err, tc.contains)
+			}
+		})
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/anewball/urlshortener/internal/jsonutil"
+	"github.com/anewball/urlshortener/internal/output"
 	"github.com/anewball/urlshortener/internal/shortener"
 )
 
 const (
 	defaultListMax       = 500
 	defaultActionTimeout = 5 * time.Second
+	defaultPurgeTimeout  = time.Minute
 )
 
 var (
@@ -35,11 +39,43 @@ var (
 	ErrDelete            = errors.New("unable to delete shortCode")
 	ErrDeleteUnsupported = errors.New("service could not delete URL with short code")
 	ErrUnableToDelete    = errors.New("unable to delete short code")
+	ErrAliasFormat       = errors.New("invalid alias")
+	ErrAliasTaken        = errors.New("alias already in use")
+	ErrURLExists         = errors.New("URL is already shortened under a different code")
+	ErrExpiry            = errors.New("invalid expiration")
+	ErrBatchSize         = errors.New("invalid batch size")
+	ErrPurge             = errors.New("unable to purge expired links")
+	ErrUpdate            = errors.New("unable to update short link")
+	ErrRevision          = errors.New("invalid revision")
+	ErrRevisionNotFound  = errors.New("no such revision for the provided shortCode")
+	ErrHistory           = errors.New("unable to retrieve link history")
+	ErrCursor            = errors.New("invalid cursor")
+	ErrSort              = errors.New("invalid sort")
+	ErrFilter            = errors.New("invalid filter")
+	ErrDestination       = errors.New("URL points to an internal network and cannot be shortened")
+	ErrDomain            = errors.New("destination domain is not permitted")
+	ErrPasswordRequired  = errors.New("short link is password protected; provide its password")
+	ErrWrongPassword     = errors.New("wrong password for the short link")
 )
 
+// ResultResponse describes one link. CreatedAt is only set in a
+// ListResponse. URL and StrippedParams are reported by add: the canonical
+// form the destination was stored under and the query parameters removed
+// from it. DisplayURL is the stored destination with its host in Unicode,
+// set only when the host is an internationalized domain name. A listed link
+// that is Protected by a password has its destination left out.
+// Existing is set by add when the URL was already shortened, so nothing was
+// created and the link on record is returned instead.
 type ResultResponse struct {
-	ShortCode string `json:"shortCode"`
-	RawURL    string `json:"rawUrl"`
+	ShortCode      string     `json:"shortCode"`
+	RawURL         string     `json:"rawUrl"`
+	URL            string     `json:"url,omitempty"`
+	DisplayURL     string     `json:"displayUrl,omitempty"`
+	StrippedParams []string   `json:"strippedParams,omitempty"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Protected      bool       `json:"protected,omitempty"`
+	Existing       bool       `json:"existing,omitempty"`
 }
 
 type DeleteResponse struct {
@@ -47,16 +83,90 @@ type DeleteResponse struct {
 	ShortCode string `json:"shortCode"`
 }
 
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+type StatsResponse struct {
+	ShortCode   string               `json:"shortCode"`
+	TotalClicks int64                `json:"totalClicks"`
+	FirstClick  *time.Time           `json:"firstClick,omitempty"`
+	LastClick   *time.Time           `json:"lastClick,omitempty"`
+	Daily       []DailyClickResponse `json:"daily"`
+}
+
+type DailyClickResponse struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+type HistoryResponse struct {
+	ShortCode string             `json:"shortCode"`
+	Revisions []RevisionResponse `json:"revisions"`
+}
+
+// RevisionResponse is one entry of a HistoryResponse. The destinations of a
+// Protected link are left out.
+type RevisionResponse struct {
+	Revision  int       `json:"revision"`
+	RawURL    string    `json:"rawUrl"`
+	ChangedAt time.Time `json:"changedAt"`
+	Protected bool      `json:"protected,omitempty"`
+}
+
+// RollbackResponse reports a rollback. The restored destination of a
+// Protected link is left out.
+type RollbackResponse struct {
+	ShortCode        string `json:"shortCode"`
+	RawURL           string `json:"rawUrl"`
+	RestoredRevision int    `json:"restoredRevision"`
+	Protected        bool   `json:"protected,omitempty"`
+}
+
+// ErrorResponse reports a failed action. Error and Details are for people
+// and may be reworded. Code is the exit code the command ends with; see
+// ExitCode. Kind is one of the stable identifiers in errorKinds and tells
+// apart failures that share a Code, so clients should branch on it. Field
+// names the offending input of a validation error.
 type ErrorResponse struct {
 	Error   string `json:"error"`
+	Code    int    `json:"code"`
+	Kind    string `json:"kind"`
+	Field   string `json:"field,omitempty"`
 	Details string `json:"details,omitempty"`
 }
 
+// AddOptions carries the optional flags of the add command. TTL and
+// ExpiresAt are mutually exclusive ways of setting the link's expiry;
+// ExpiresAt is an RFC3339 timestamp.
+type AddOptions struct {
+	Alias     string
+	TTL       time.Duration
+	ExpiresAt string
+	// KeepParams are patterns of query parameters to keep even when the
+	// configured policy strips them.
+	KeepParams []string
+	// Password protects the link; GetAction then needs it to resolve it.
+	Password string
+}
+
+// GetOptions holds the password of a protected link for GetAction.
+type GetOptions struct {
+	Password string
+}
+
 type Actions interface {
-	AddAction(ctx context.Context, out io.Writer, args []string) error
-	GetAction(ctx context.Context, out io.Writer, args []string) error
-	ListAction(ctx context.Context, limit int, offset int, out io.Writer) error
+	AddAction(ctx context.Context, out io.Writer, args []string, opts AddOptions) error
+	ImportAction(ctx context.Context, out io.Writer, in io.Reader, opts ImportOptions) error
+	GetAction(ctx context.Context, out io.Writer, args []string, opts GetOptions) error
+	ListAction(ctx context.Context, out io.Writer, opts ListOptions) error
+	ExportAction(ctx context.Context, out io.Writer, opts ExportOptions) error
+	UpdateAction(ctx context.Context, out io.Writer, args []string) error
 	DeleteAction(ctx context.Context, out io.Writer, args []string) error
+	HistoryAction(ctx context.Context, out io.Writer, args []string) error
+	RollbackAction(ctx context.Context, out io.Writer, args []string) error
+	PurgeAction(ctx context.Context, out io.Writer, batchSize int) error
+	StatsAction(ctx context.Context, out io.Writer, args []string) error
 }
 
 type actions struct {
@@ -68,7 +178,7 @@ func NewActions(svc shortener.URLShortener, listMaxLimit int) Actions {
 	return &actions{svc: svc, listMaxLimit: listMaxLimit}
 }
 
-func (a *actions) AddAction(ctx context.Context, out io.Writer, args []string) error {
+func (a *actions) AddAction(ctx context.Context, out io.Writer, args []string, opts AddOptions) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
 	defer cancel()
 
@@ -76,27 +186,94 @@ func (a *actions) AddAction(ctx context.Context, out io.Writer, args []string) e
 		return writeAndReturnError(out, ErrLenZero, nil)
 	}
 
+	expiresAt, err := resolveExpiry(opts)
+	if err != nil {
+		return writeAndReturnError(out, ErrExpiry, err)
+	}
+
 	arg := args[0]
-	shortCode, err := a.svc.Add(ctx, arg)
+	res, err := a.svc.Add(ctx, arg, shortener.AddOptions{Alias: opts.Alias, ExpiresAt: expiresAt, KeepParams: opts.KeepParams, Password: opts.Password})
 	if err != nil {
-		switch {
-		case errors.Is(err, shortener.ErrIsValidURL):
-			return writeAndReturnError(out, ErrURLFormat, err)
-		case errors.Is(err, shortener.ErrGenerate):
-			return writeAndReturnError(out, ErrAdd, errors.New("error generating short code"))
-		case errors.Is(err, shortener.ErrQueryRow):
-			return writeAndReturnError(out, ErrAdd, err)
-		default:
-			return writeAndReturnError(out, ErrUnsupported, err)
-		}
+		code, cause := classifyAddError(err)
+		return writeAndReturnError(out, code, cause)
+	}
+
+	response := ResultResponse{
+		ShortCode:      res.ShortCode,
+		RawURL:         arg,
+		URL:            res.URL,
+		DisplayURL:     displayURL(res.URL),
+		StrippedParams: res.StrippedParams,
+		ExpiresAt:      res.ExpiresAt,
+		Existing:       !res.Created,
 	}
 
-	response := ResultResponse{ShortCode: shortCode, RawURL: arg}
+	return output.Write(out, response)
+}
+
+// classifyAddError maps an error from shortener.Add to the core error shown
+// to the user and the cause reported alongside it.
+func classifyAddError(err error) (code, cause error) {
+	switch {
+	case errors.Is(err, shortener.ErrIsValidURL):
+		return ErrURLFormat, err
+	case errors.Is(err, shortener.ErrPrivateDestination):
+		return ErrDestination, err
+	case errors.Is(err, shortener.ErrDomainDenied), errors.Is(err, shortener.ErrDomainNotAllowed):
+		return ErrDomain, err
+	case errors.Is(err, shortener.ErrRule):
+		return ErrAdd, err
+	case errors.Is(err, shortener.ErrAlias):
+		return ErrAliasFormat, err
+	case errors.Is(err, shortener.ErrAliasTaken):
+		return ErrAliasTaken, err
+	case errors.Is(err, shortener.ErrURLExists):
+		return ErrURLExists, err
+	case errors.Is(err, shortener.ErrExpiresAt):
+		return ErrExpiry, err
+	case errors.Is(err, shortener.ErrParamPolicy):
+		return ErrInvalidArgs, WithField("keepParams", err)
+	case errors.Is(err, shortener.ErrPassword):
+		return ErrInvalidArgs, WithField("password", err)
+	case errors.Is(err, shortener.ErrGenerate):
+		return ErrAdd, errors.New("error generating short code")
+	case errors.Is(err, shortener.ErrQueryRow), errors.Is(err, shortener.ErrCollision):
+		return ErrAdd, err
+	default:
+		return ErrUnsupported, err
+	}
+}
+
+// displayURL returns the Unicode form of a stored URL with a punycode host,
+// or "" when it reads the same either way.
+func displayURL(stored string) string {
+	if d := shortener.DisplayURL(stored); d != stored {
+		return d
+	}
+	return ""
+}
 
-	return jsonutil.WriteJSON(out, response)
+func resolveExpiry(opts AddOptions) (*time.Time, error) {
+	switch {
+	case opts.TTL != 0 && opts.ExpiresAt != "":
+		return nil, WithField("ttl", errors.New("use either a TTL or an expiration time, not both"))
+	case opts.TTL < 0:
+		return nil, WithField("ttl", fmt.Errorf("ttl must be positive; got %s", opts.TTL))
+	case opts.TTL > 0:
+		expiresAt := time.Now().Add(opts.TTL).UTC()
+		return &expiresAt, nil
+	case opts.ExpiresAt != "":
+		expiresAt, err := time.Parse(time.RFC3339, opts.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("expiration must be an RFC3339 timestamp; got %q", opts.ExpiresAt)
+		}
+		return &expiresAt, nil
+	default:
+		return nil, nil
+	}
 }
 
-func (a *actions) GetAction(ctx context.Context, out io.Writer, args []string) error {
+func (a *actions) GetAction(ctx context.Context, out io.Writer, args []string, opts GetOptions) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
 	defer cancel()
 
@@ -105,7 +282,7 @@ func (a *actions) GetAction(ctx context.Context, out io.Writer, args []string) e
 	}
 
 	arg := args[0]
-	url, err := a.svc.Get(ctx, arg)
+	url, err := a.svc.Get(ctx, arg, shortener.GetOptions{Password: opts.Password})
 	if err != nil {
 		switch {
 		case errors.Is(err, shortener.ErrShortCode):
@@ -113,6 +290,10 @@ func (a *actions) GetAction(ctx context.Context, out io.Writer, args []string) e
 				errors.New("a required short code was not provided. Please see usage: get <shortCode>"))
 		case errors.Is(err, shortener.ErrNotFound):
 			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, arg), err)
+		case errors.Is(err, shortener.ErrPasswordRequired):
+			return writeAndReturnError(out, ErrPasswordRequired, err)
+		case errors.Is(err, shortener.ErrWrongPassword):
+			return writeAndReturnError(out, ErrWrongPassword, err)
 		case errors.Is(err, shortener.ErrQuery):
 			return writeAndReturnError(out, ErrUnexpected,
 				errors.New("an error occurred while retrieving the short link. Please try again later"))
@@ -121,22 +302,52 @@ func (a *actions) GetAction(ctx context.Context, out io.Writer, args []string) e
 		}
 	}
 
-	response := ResultResponse{ShortCode: arg, RawURL: url}
+	response := ResultResponse{ShortCode: arg, RawURL: url, DisplayURL: displayURL(url)}
+
+	return output.Write(out, response)
+}
 
-	return jsonutil.WriteJSON(out, response)
+// ListOptions selects a page for ListAction. Cursor is the nextCursor of a
+// previous ListResponse and cannot be combined with a non-zero Offset.
+//
+// Host, Contains, Since, Until and Expired narrow the links listed. Since
+// and Until are RFC3339 timestamps or dates; a date Until includes that whole
+// day. Sort is created, code or url and Order is asc or desc; by default
+// links are newest first or, sorted by code or url, alphabetical.
+type ListOptions struct {
+	Limit    int
+	Offset   int
+	Cursor   string
+	Host     string
+	Contains string
+	Since    string
+	Until    string
+	Expired  bool
+	Sort     string
+	Order    string
 }
 
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// ListResponse is one page of links. NextCursor is set when more links
+// follow; pass it back as ListOptions.Cursor to fetch them.
 type ListResponse struct {
-	Items  []ResultResponse `json:"items"`
-	Count  int              `json:"count"`
-	Limit  int              `json:"limit"`
-	Offset int              `json:"offset"`
+	Items      []ResultResponse `json:"items"`
+	Count      int              `json:"count"`
+	Limit      int              `json:"limit"`
+	Offset     int              `json:"offset"`
+	NextCursor string           `json:"nextCursor,omitempty"`
 }
 
-func (a *actions) ListAction(ctx context.Context, limit int, offset int, out io.Writer) error {
+func (a *actions) ListAction(ctx context.Context, out io.Writer, opts ListOptions) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
 	defer cancel()
 
+	limit, offset := opts.Limit, opts.Offset
+
 	max := a.listMaxLimit
 	if max <= 0 {
 		max = defaultListMax
@@ -151,9 +362,32 @@ func (a *actions) ListAction(ctx context.Context, limit int, offset int, out io.
 			fmt.Errorf("offset must be >= 0; got %d", offset))
 	}
 
-	urlItems, err := a.svc.List(ctx, limit, offset)
+	query, code, cause := listQuery(opts)
+	if code != nil {
+		return writeAndReturnError(out, code, cause)
+	}
+	query.Limit, query.Offset = limit+1, offset
+
+	if opts.Cursor != "" {
+		if offset != 0 {
+			return writeAndReturnError(out, ErrOffset,
+				fmt.Errorf("offset cannot be combined with a cursor; got %d", offset))
+		}
+		after, err := shortener.ParseCursor(opts.Cursor)
+		if err != nil {
+			return writeAndReturnError(out, ErrCursor, err)
+		}
+		query.After = &after
+	}
+
+	// The extra row asked for above only tells whether another page follows.
+	urlItems, err := a.svc.List(ctx, query)
 	if err != nil {
 		switch {
+		case errors.Is(err, shortener.ErrCursor):
+			return writeAndReturnError(out, ErrCursor, err)
+		case errors.Is(err, shortener.ErrSort):
+			return writeAndReturnError(out, ErrSort, err)
 		case errors.Is(err, shortener.ErrQuery):
 			return writeAndReturnError(out, ErrUnexpected,
 				fmt.Errorf("error executing list query (limit=%d, offset=%d)", limit, offset))
@@ -169,19 +403,143 @@ func (a *actions) ListAction(ctx context.Context, limit int, offset int, out io.
 		}
 	}
 
+	var nextCursor string
+	if len(urlItems) > limit {
+		urlItems = urlItems[:limit]
+		nextCursor = shortener.CursorFor(urlItems[limit-1], query).String()
+	}
+
 	var results []ResultResponse = make([]ResultResponse, 0, len(urlItems))
 	for _, u := range urlItems {
-		results = append(results, ResultResponse{ShortCode: u.ShortCode, RawURL: u.OriginalURL})
+		item := ResultResponse{
+			ShortCode: u.ShortCode,
+			CreatedAt: &u.CreatedAt,
+			ExpiresAt: u.ExpiresAt,
+			Protected: u.Protected,
+		}
+		// Listing needs no password, so it must not reveal what one guards.
+		if !u.Protected {
+			item.RawURL, item.DisplayURL = u.OriginalURL, displayURL(u.OriginalURL)
+		}
+		results = append(results, item)
 	}
 
 	response := ListResponse{
-		Items:  results,
-		Count:  len(results),
-		Limit:  limit,
-		Offset: offset,
+		Items:      results,
+		Count:      len(results),
+		Limit:      limit,
+		Offset:     offset,
+		NextCursor: nextCursor,
+	}
+
+	return output.Write(out, response)
+}
+
+// listQuery turns the filter and sort of opts into shortener.ListOptions,
+// leaving the paging fields to the caller. On bad input it returns the
+// sentinel and cause to report.
+func listQuery(opts ListOptions) (shortener.ListOptions, error, error) {
+	sort := shortener.SortField(strings.ToLower(opts.Sort))
+	if sort == "" {
+		sort = shortener.SortCreated
+	}
+	if !slices.Contains(shortener.SortFields, sort) {
+		return shortener.ListOptions{}, ErrSort,
+			fmt.Errorf("sort must be one of created, code or url; got %q", opts.Sort)
+	}
+
+	var ascending bool
+	switch strings.ToLower(opts.Order) {
+	case "":
+		ascending = sort != shortener.SortCreated
+	case OrderAsc:
+		ascending = true
+	case OrderDesc:
+	default:
+		return shortener.ListOptions{}, ErrSort,
+			WithField("order", fmt.Errorf("order must be %s or %s; got %q", OrderAsc, OrderDesc, opts.Order))
+	}
+
+	filter := shortener.ListFilter{
+		Host:           strings.TrimSpace(opts.Host),
+		Contains:       opts.Contains,
+		IncludeExpired: opts.Expired,
+	}
+	var err error
+	if filter.Since, err = listTime(opts.Since, false); err != nil {
+		return shortener.ListOptions{}, ErrFilter, WithField("since", fmt.Errorf("since: %v", err))
+	}
+	if filter.Until, err = listTime(opts.Until, true); err != nil {
+		return shortener.ListOptions{}, ErrFilter, WithField("until", fmt.Errorf("until: %v", err))
+	}
+	if filter.Since != nil && filter.Until != nil && filter.Until.Before(*filter.Since) {
+		return shortener.ListOptions{}, ErrFilter,
+			fmt.Errorf("since (%s) must not be after until (%s)", opts.Since, opts.Until)
+	}
+
+	return shortener.ListOptions{Filter: filter, Sort: sort, Ascending: ascending}, nil, nil
+}
+
+// listTime parses an RFC3339 timestamp or a YYYY-MM-DD date in UTC. With
+// endOfDay set a date stands for the last instant of that day.
+func listTime(s string, endOfDay bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, fmt.Errorf("want an RFC3339 timestamp or YYYY-MM-DD date; got %q", s)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+func (a *actions) UpdateAction(ctx context.Context, out io.Writer, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
+	defer cancel()
+
+	if len(args) == 0 {
+		return writeAndReturnError(out, ErrLenZero, nil)
+	}
+	if len(args) < 2 {
+		return writeAndReturnError(out, ErrInvalidArgs,
+			errors.New("a new URL is required. Please see usage: update <shortCode> <url>"))
+	}
+	shortCode, newURL := args[0], args[1]
+
+	if err := a.svc.Update(ctx, shortCode, newURL); err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrShortCode):
+			return writeAndReturnError(out, ErrShortCode,
+				errors.New("a required short code was not provided. Please see usage: update <shortCode> <url>"))
+		case errors.Is(err, shortener.ErrIsValidURL):
+			return writeAndReturnError(out, ErrURLFormat, err)
+		case errors.Is(err, shortener.ErrPrivateDestination):
+			return writeAndReturnError(out, ErrDestination, err)
+		case errors.Is(err, shortener.ErrDomainDenied), errors.Is(err, shortener.ErrDomainNotAllowed):
+			return writeAndReturnError(out, ErrDomain, err)
+		case errors.Is(err, shortener.ErrRule):
+			return writeAndReturnError(out, ErrUpdate, err)
+		case errors.Is(err, shortener.ErrNotFound):
+			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, shortCode), err)
+		case errors.Is(err, shortener.ErrURLExists):
+			return writeAndReturnError(out, ErrURLExists, err)
+		case errors.Is(err, shortener.ErrQuery):
+			return writeAndReturnError(out, ErrUpdate, err)
+		default:
+			return writeAndReturnError(out, ErrUnexpected,
+				fmt.Errorf("failed to update short code: %q", shortCode))
+		}
 	}
 
-	return jsonutil.WriteJSON(out, response)
+	response := ResultResponse{ShortCode: shortCode, RawURL: newURL}
+
+	return output.Write(out, response)
 }
 
 func (a *actions) DeleteAction(ctx context.Context, out io.Writer, args []string) error {
@@ -200,7 +558,7 @@ func (a *actions) DeleteAction(ctx context.Context, out io.Writer, args []string
 			return writeAndReturnError(out, ErrShortCode,
 				errors.New("a required short code was not provided. Please see usage: delete <shortCode>"))
 		case errors.Is(err, shortener.ErrExec):
-			return writeAndReturnError(out, fmt.Errorf("%s %s", ErrDelete.Error(), shortCode), err)
+			return writeAndReturnError(out, fmt.Errorf("%w %s", ErrDelete, shortCode), err)
 		case errors.Is(err, shortener.ErrNotFound):
 			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, shortCode), err)
 		default:
@@ -217,19 +575,150 @@ func (a *actions) DeleteAction(ctx context.Context, out io.Writer, args []string
 	response.Deleted = deleted
 	response.ShortCode = shortCode
 
-	return jsonutil.WriteJSON(out, response)
+	return output.Write(out, response)
+}
+
+func (a *actions) HistoryAction(ctx context.Context, out io.Writer, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
+	defer cancel()
+
+	if len(args) == 0 {
+		return writeAndReturnError(out, ErrLenZero, nil)
+	}
+	shortCode := args[0]
+
+	revisions, err := a.svc.History(ctx, shortCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrShortCode):
+			return writeAndReturnError(out, ErrShortCode,
+				errors.New("a required short code was not provided. Please see usage: history <shortCode>"))
+		case errors.Is(err, shortener.ErrNotFound):
+			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, shortCode), err)
+		default:
+			return writeAndReturnError(out, ErrHistory, err)
+		}
+	}
+
+	items := make([]RevisionResponse, 0, len(revisions))
+	for _, r := range revisions {
+		item := RevisionResponse{Revision: r.Number, ChangedAt: r.ChangedAt, Protected: r.Protected}
+		if !r.Protected {
+			item.RawURL = r.OriginalURL
+		}
+		items = append(items, item)
+	}
+
+	return output.Write(out, HistoryResponse{ShortCode: shortCode, Revisions: items})
+}
+
+func (a *actions) RollbackAction(ctx context.Context, out io.Writer, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
+	defer cancel()
+
+	if len(args) == 0 {
+		return writeAndReturnError(out, ErrLenZero, nil)
+	}
+	if len(args) < 2 {
+		return writeAndReturnError(out, ErrInvalidArgs,
+			errors.New("a revision is required. Please see usage: rollback <shortCode> <revision>"))
+	}
+	shortCode := args[0]
+
+	revision, err := strconv.Atoi(args[1])
+	if err != nil || revision < 1 {
+		return writeAndReturnError(out, ErrRevision,
+			fmt.Errorf("revision must be a positive number; got %q", args[1]))
+	}
+
+	restored, err := a.svc.Rollback(ctx, shortCode, revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrShortCode):
+			return writeAndReturnError(out, ErrShortCode,
+				errors.New("a required short code was not provided. Please see usage: rollback <shortCode> <revision>"))
+		case errors.Is(err, shortener.ErrNotFound):
+			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, shortCode), err)
+		case errors.Is(err, shortener.ErrRevision):
+			return writeAndReturnError(out, ErrRevisionNotFound, err)
+		case errors.Is(err, shortener.ErrPrivateDestination):
+			return writeAndReturnError(out, ErrDestination, err)
+		case errors.Is(err, shortener.ErrDomainDenied), errors.Is(err, shortener.ErrDomainNotAllowed):
+			return writeAndReturnError(out, ErrDomain, err)
+		case errors.Is(err, shortener.ErrURLExists):
+			return writeAndReturnError(out, ErrURLExists, err)
+		default:
+			return writeAndReturnError(out, ErrUpdate, err)
+		}
+	}
+
+	response := RollbackResponse{ShortCode: shortCode, RestoredRevision: revision, Protected: restored.Protected}
+	if !restored.Protected {
+		response.RawURL = restored.OriginalURL
+	}
+
+	return output.Write(out, response)
+}
+
+func (a *actions) PurgeAction(ctx context.Context, out io.Writer, batchSize int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultPurgeTimeout)
+	defer cancel()
+
+	purged, err := a.svc.PurgeExpired(ctx, batchSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrBatchSize):
+			return writeAndReturnError(out, ErrBatchSize, err)
+		default:
+			return writeAndReturnError(out, ErrPurge,
+				fmt.Errorf("purged %d expired links before failing: %v", purged, err))
+		}
+	}
+
+	return output.Write(out, PurgeResponse{Purged: purged})
+}
+
+func (a *actions) StatsAction(ctx context.Context, out io.Writer, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultActionTimeout)
+	defer cancel()
+
+	if len(args) == 0 {
+		return writeAndReturnError(out, ErrLenZero, nil)
+	}
+	shortCode := args[0]
+
+	stats, err := a.svc.Stats(ctx, shortCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrShortCode):
+			return writeAndReturnError(out, ErrShortCode,
+				errors.New("a required short code was not provided. Please see usage: stats <shortCode>"))
+		case errors.Is(err, shortener.ErrNotFound):
+			return writeAndReturnError(out, fmt.Errorf("%w: %s", ErrNotFound, shortCode), err)
+		default:
+			return writeAndReturnError(out, ErrUnexpected,
+				fmt.Errorf("failed to retrieve stats for short code: %q", shortCode))
+		}
+	}
+
+	daily := make([]DailyClickResponse, 0, len(stats.Daily))
+	for _, d := range stats.Daily {
+		daily = append(daily, DailyClickResponse{Date: d.Day.Format(time.DateOnly), Clicks: d.Clicks})
+	}
+
+	response := StatsResponse{
+		ShortCode:   shortCode,
+		TotalClicks: stats.TotalClicks,
+		FirstClick:  stats.FirstClick,
+		LastClick:   stats.LastClick,
+		Daily:       daily,
+	}
+
+	return output.Write(out, response)
 }
 
 func writeAndReturnError(out io.Writer, code error, cause error) error {
-	_ = jsonutil.WriteJSON(out, ErrorResponse{
-		Error: code.Error(),
-		Details: func() string {
-			if cause != nil {
-				return cause.Error()
-			}
-			return ""
-		}(),
-	})
+	_ = output.Write(out, NewErrorResponse(code, cause))
 	if cause != nil {
 		return fmt.Errorf("%w: %v", code, cause)
 	}
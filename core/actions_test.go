@@ -11,13 +11,16 @@ import (
 	"github.com/anewball/urlshortener/internal/jsonutil"
 	"github.com/anewball/urlshortener/internal/shortener"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAddActions(t *testing.T) {
 	shortCode := "Hpa3t2B"
+	expiresAt := time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC)
 	testCases := []struct {
 		name                   string
 		args                   []string
+		opts                   AddOptions
 		buf                    bytes.Buffer
 		isError                bool
 		listMaxLimit           int
@@ -34,8 +37,89 @@ func TestAddActions(t *testing.T) {
 			isError:                false,
 			expectedErrorResponse:  ErrorResponse{},
 			svc: &mockedShortener{
-				addFunc: func(ctx context.Context, url string) (string, error) {
-					return shortCode, nil
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: shortCode, Created: true}, nil
+				},
+			},
+		},
+		{
+			name:                   "already shortened",
+			args:                   []string{"https://example.com"},
+			buf:                    bytes.Buffer{},
+			listMaxLimit:           20,
+			expectedResultResponse: ResultResponse{ShortCode: "Other12", RawURL: "https://example.com", Existing: true},
+			isError:                false,
+			expectedErrorResponse:  ErrorResponse{},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: "Other12", Created: false}, nil
+				},
+			},
+		},
+		{
+			name:                   "success with expiration",
+			args:                   []string{"https://example.com"},
+			opts:                   AddOptions{ExpiresAt: "2030-01-02T15:04:05Z"},
+			buf:                    bytes.Buffer{},
+			listMaxLimit:           20,
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: "https://example.com", ExpiresAt: &expiresAt},
+			isError:                false,
+			expectedErrorResponse:  ErrorResponse{},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: shortCode, ExpiresAt: opts.ExpiresAt, Created: true}, nil
+				},
+			},
+		},
+		{
+			name:                   "success reports canonical url",
+			args:                   []string{"HTTPS://Example.com:443"},
+			buf:                    bytes.Buffer{},
+			listMaxLimit:           20,
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: "HTTPS://Example.com:443", URL: "https://example.com/"},
+			isError:                false,
+			expectedErrorResponse:  ErrorResponse{},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: shortCode, URL: "https://example.com/", Created: true}, nil
+				},
+			},
+		},
+		{
+			name:                   "success reports stripped params",
+			args:                   []string{"https://example.com/?utm_source=mail&id=1"},
+			opts:                   AddOptions{KeepParams: []string{"ref"}},
+			buf:                    bytes.Buffer{},
+			listMaxLimit:           20,
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: "https://example.com/?utm_source=mail&id=1", URL: "https://example.com/?id=1", StrippedParams: []string{"utm_source"}},
+			isError:                false,
+			expectedErrorResponse:  ErrorResponse{},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					if len(opts.KeepParams) != 1 || opts.KeepParams[0] != "ref" {
+						return shortener.AddResult{}, errors.New("keep params not passed through")
+					}
+					return shortener.AddResult{ShortCode: shortCode, URL: "https://example.com/?id=1", StrippedParams: []string{"utm_source"}, Created: true}, nil
+				},
+			},
+		},
+		{
+			name:         "invalid keep params pattern",
+			args:         []string{"https://example.com"},
+			opts:         AddOptions{KeepParams: []string{"utm_["}},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrInvalidArgs.Error(),
+				Code:    ExitInvalid,
+				Kind:    "INVALID_ARGUMENTS",
+				Field:   "keepParams",
+				Details: shortener.ErrParamPolicy.Error() + `: pattern "utm_[": syntax error in pattern`,
+			},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, fmt.Errorf("%w: pattern %q: syntax error in pattern", shortener.ErrParamPolicy, opts.KeepParams[0])
 				},
 			},
 		},
@@ -45,19 +129,138 @@ func TestAddActions(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error()},
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
 			svc:                   &mockedShortener{},
 		},
+		{
+			name:         "ttl and expires-at together",
+			args:         []string{"https://example.com"},
+			opts:         AddOptions{TTL: time.Hour, ExpiresAt: "2030-01-02T15:04:05Z"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrExpiry.Error(),
+				Code:    ExitInvalid,
+				Kind:    "EXPIRY_INVALID",
+				Field:   "ttl",
+				Details: "use either a TTL or an expiration time, not both",
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:         "negative ttl",
+			args:         []string{"https://example.com"},
+			opts:         AddOptions{TTL: -time.Hour},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrExpiry.Error(),
+				Code:    ExitInvalid,
+				Kind:    "EXPIRY_INVALID",
+				Field:   "ttl",
+				Details: "ttl must be positive; got -1h0m0s",
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:         "malformed expires-at",
+			args:         []string{"https://example.com"},
+			opts:         AddOptions{ExpiresAt: "tomorrow"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrExpiry.Error(),
+				Code:    ExitInvalid,
+				Kind:    "EXPIRY_INVALID",
+				Field:   "expiresAt",
+				Details: `expiration must be an RFC3339 timestamp; got "tomorrow"`,
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:                  "expiration in the past",
+			args:                  []string{"https://example.com"},
+			opts:                  AddOptions{ExpiresAt: "2020-01-02T15:04:05Z"},
+			listMaxLimit:          20,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrExpiry.Error(), Code: ExitInvalid, Kind: "EXPIRY_INVALID", Field: "expiresAt", Details: shortener.ErrExpiresAt.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrExpiresAt
+				},
+			},
+		},
 		{
 			name:                  "invalid url",
 			args:                  []string{"https://example.com"},
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrURLFormat.Error(), Details: shortener.ErrIsValidURL.Error()},
+			expectedErrorResponse: ErrorResponse{Error: ErrURLFormat.Error(), Code: ExitInvalid, Kind: "URL_INVALID", Field: "url", Details: shortener.ErrIsValidURL.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrIsValidURL
+				},
+			},
+		},
+		{
+			name:         "password too long",
+			args:         []string{"https://example.com"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrInvalidArgs.Error(),
+				Code:    ExitInvalid,
+				Kind:    "INVALID_ARGUMENTS",
+				Field:   "password",
+				Details: shortener.ErrPassword.Error() + ": at most 72 bytes; got 80",
+			},
 			svc: &mockedShortener{
-				addFunc: func(ctx context.Context, url string) (string, error) {
-					return "", shortener.ErrIsValidURL
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, fmt.Errorf("%w: at most 72 bytes; got 80", shortener.ErrPassword)
+				},
+			},
+		},
+		{
+			name:         "private destination",
+			args:         []string{"http://169.254.169.254/"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrDestination.Error(),
+				Code:    ExitInvalid,
+				Kind:    "URL_DESTINATION_PRIVATE",
+				Field:   "url",
+				Details: shortener.ErrPrivateDestination.Error() + ": 169.254.169.254",
+			},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, fmt.Errorf("%w: 169.254.169.254", shortener.ErrPrivateDestination)
+				},
+			},
+		},
+		{
+			name:         "denied domain",
+			args:         []string{"https://login.phish.example/"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrDomain.Error(),
+				Code:    ExitInvalid,
+				Kind:    "URL_DOMAIN_REJECTED",
+				Field:   "url",
+				Details: shortener.ErrDomainDenied.Error() + `: login.phish.example matches rule "deny *.phish.example"`,
+			},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, fmt.Errorf(`%w: login.phish.example matches rule "deny *.phish.example"`, shortener.ErrDomainDenied)
 				},
 			},
 		},
@@ -69,11 +272,13 @@ func TestAddActions(t *testing.T) {
 			isError:      true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrAdd.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "ADD_FAILED",
 				Details: errors.New("error generating short code").Error(),
 			},
 			svc: &mockedShortener{
-				addFunc: func(ctx context.Context, url string) (string, error) {
-					return "", shortener.ErrGenerate
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrGenerate
 				},
 			},
 		},
@@ -83,10 +288,62 @@ func TestAddActions(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrAdd.Error(), Details: shortener.ErrQueryRow.Error()},
+			expectedErrorResponse: ErrorResponse{Error: ErrAdd.Error(), Code: ExitUnavailable, Kind: "ADD_FAILED", Details: shortener.ErrQueryRow.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrQueryRow
+				},
+			},
+		},
+		{
+			name:                  "invalid alias",
+			args:                  []string{"https://example.com"},
+			listMaxLimit:          20,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrAliasFormat.Error(), Code: ExitInvalid, Kind: "ALIAS_INVALID", Field: "alias", Details: shortener.ErrAlias.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrAlias
+				},
+			},
+		},
+		{
+			name:                  "alias taken",
+			args:                  []string{"https://example.com"},
+			listMaxLimit:          20,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrAliasTaken.Error(), Code: ExitConflict, Kind: "ALIAS_TAKEN", Field: "alias", Details: shortener.ErrAliasTaken.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrAliasTaken
+				},
+			},
+		},
+		{
+			name:                  "url already shortened",
+			args:                  []string{"https://example.com"},
+			listMaxLimit:          20,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrURLExists.Error(), Code: ExitConflict, Kind: "URL_EXISTS", Field: "url", Details: shortener.ErrURLExists.Error()},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrURLExists
+				},
+			},
+		},
+		{
+			name:                  "short code collisions exhausted",
+			args:                  []string{"https://example.com"},
+			listMaxLimit:          20,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrAdd.Error(), Code: ExitUnavailable, Kind: "ADD_FAILED", Details: shortener.ErrCollision.Error()},
 			svc: &mockedShortener{
-				addFunc: func(ctx context.Context, url string) (string, error) {
-					return "", shortener.ErrQueryRow
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrCollision
 				},
 			},
 		},
@@ -96,10 +353,10 @@ func TestAddActions(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrUnsupported.Error(), Details: "Failed to add URL"},
+			expectedErrorResponse: ErrorResponse{Error: ErrUnsupported.Error(), Code: ExitError, Kind: "UNSUPPORTED", Details: "Failed to add URL"},
 			svc: &mockedShortener{
-				addFunc: func(ctx context.Context, url string) (string, error) {
-					return "", errors.New("Failed to add URL")
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, errors.New("Failed to add URL")
 				},
 			},
 		},
@@ -112,7 +369,7 @@ func TestAddActions(t *testing.T) {
 
 			action := NewActions(tc.svc, tc.listMaxLimit)
 
-			err := action.AddAction(ctx, &tc.buf, tc.args)
+			err := action.AddAction(ctx, &tc.buf, tc.args, tc.opts)
 
 			if tc.isError {
 				var actualErrorResponse ErrorResponse
@@ -131,11 +388,37 @@ func TestAddActions(t *testing.T) {
 	}
 }
 
+func TestAddAction_TTL(t *testing.T) {
+	var gotOpts shortener.AddOptions
+	svc := &mockedShortener{
+		addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+			gotOpts = opts
+			return shortener.AddResult{ShortCode: "Hpa3t2B", ExpiresAt: opts.ExpiresAt, Created: true}, nil
+		},
+	}
+
+	before := time.Now()
+	var buf bytes.Buffer
+	err := NewActions(svc, 20).AddAction(context.Background(), &buf, []string{"https://example.com"}, AddOptions{TTL: 72 * time.Hour})
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, gotOpts.ExpiresAt) {
+		assert.WithinDuration(t, before.Add(72*time.Hour), *gotOpts.ExpiresAt, time.Minute)
+	}
+
+	var actualResultResponse ResultResponse
+	jsonutil.ReadJSON(&buf, &actualResultResponse)
+	if assert.NotNil(t, actualResultResponse.ExpiresAt) {
+		assert.True(t, gotOpts.ExpiresAt.Equal(*actualResultResponse.ExpiresAt))
+	}
+}
+
 func TestGetAction(t *testing.T) {
 	shortCode := "Hpa3t2B"
 	testCases := []struct {
 		name                   string
 		args                   []string
+		opts                   GetOptions
 		buf                    bytes.Buffer
 		isError                bool
 		listMaxLimit           int
@@ -153,18 +436,32 @@ func TestGetAction(t *testing.T) {
 			isError:                false,
 			expectedErrorResponse:  ErrorResponse{},
 			svc: &mockedShortener{
-				getFunc: func(ctx context.Context, shortCode string) (string, error) {
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
 					return "https://example.com", nil
 				},
 			},
 		},
+		{
+			name:                   "success with internationalized host",
+			args:                   []string{shortCode},
+			listMaxLimit:           20,
+			buf:                    bytes.Buffer{},
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: "https://xn--bcher-kva.example/", DisplayURL: "https://bücher.example/"},
+			isError:                false,
+			expectedErrorResponse:  ErrorResponse{},
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "https://xn--bcher-kva.example/", nil
+				},
+			},
+		},
 		{
 			name:                  "zero args",
 			args:                  []string{},
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error()},
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
 			svc:                   &mockedShortener{},
 		},
 		{
@@ -175,10 +472,13 @@ func TestGetAction(t *testing.T) {
 			isError:      true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrShortCode.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SHORT_CODE_REQUIRED",
+				Field:   "shortCode",
 				Details: errors.New("a required short code was not provided. Please see usage: get <shortCode>").Error(),
 			},
 			svc: &mockedShortener{
-				getFunc: func(ctx context.Context, url string) (string, error) {
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
 					return "", shortener.ErrShortCode
 				},
 			},
@@ -189,13 +489,68 @@ func TestGetAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: fmt.Sprintf("%s: %s", ErrNotFound, shortCode), Details: shortener.ErrNotFound.Error()},
+			expectedErrorResponse: ErrorResponse{Error: fmt.Sprintf("%s: %s", ErrNotFound, shortCode), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
 			svc: &mockedShortener{
-				getFunc: func(ctx context.Context, url string) (string, error) {
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
 					return "", shortener.ErrNotFound
 				},
 			},
 		},
+		{
+			name:                   "success with password",
+			args:                   []string{shortCode},
+			opts:                   GetOptions{Password: "s3cret"},
+			listMaxLimit:           20,
+			buf:                    bytes.Buffer{},
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: "https://example.com"},
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					if opts.Password != "s3cret" {
+						return "", shortener.ErrWrongPassword
+					}
+					return "https://example.com", nil
+				},
+			},
+		},
+		{
+			name:         "error password required",
+			args:         []string{shortCode},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrPasswordRequired.Error(),
+				Code:    ExitDenied,
+				Kind:    "PASSWORD_REQUIRED",
+				Field:   "password",
+				Details: shortener.ErrPasswordRequired.Error() + ": " + shortCode,
+			},
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
+					return "", fmt.Errorf("%w: %s", shortener.ErrPasswordRequired, shortCode)
+				},
+			},
+		},
+		{
+			name:         "error wrong password",
+			args:         []string{shortCode},
+			opts:         GetOptions{Password: "guess"},
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			isError:      true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrWrongPassword.Error(),
+				Code:    ExitDenied,
+				Kind:    "PASSWORD_WRONG",
+				Field:   "password",
+				Details: shortener.ErrWrongPassword.Error() + ": " + shortCode,
+			},
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
+					return "", fmt.Errorf("%w: %s", shortener.ErrWrongPassword, shortCode)
+				},
+			},
+		},
 		{
 			name:         "error query",
 			args:         []string{shortCode},
@@ -204,10 +559,12 @@ func TestGetAction(t *testing.T) {
 			isError:      true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: errors.New("an error occurred while retrieving the short link. Please try again later").Error(),
 			},
 			svc: &mockedShortener{
-				getFunc: func(ctx context.Context, url string) (string, error) {
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
 					return "", shortener.ErrQuery
 				},
 			},
@@ -218,9 +575,9 @@ func TestGetAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrUnexpected.Error(), Details: "Something went wrong"},
+			expectedErrorResponse: ErrorResponse{Error: ErrUnexpected.Error(), Code: ExitUnavailable, Kind: "UNEXPECTED", Details: "Something went wrong"},
 			svc: &mockedShortener{
-				getFunc: func(ctx context.Context, url string) (string, error) {
+				getFunc: func(ctx context.Context, url string, opts shortener.GetOptions) (string, error) {
 					return "", errors.New("Something went wrong")
 				},
 			},
@@ -234,9 +591,10 @@ func TestGetAction(t *testing.T) {
 
 			action := NewActions(tc.svc, tc.listMaxLimit)
 
-			err := action.GetAction(ctx, &tc.buf, tc.args)
+			err := action.GetAction(ctx, &tc.buf, tc.args, tc.opts)
 
 			if tc.isError {
+				assert.Equal(t, tc.expectedErrorResponse.Code, ExitCode(err))
 				var actualErrorResponse ErrorResponse
 				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
 				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
@@ -286,7 +644,7 @@ func TestDeleteAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error()},
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
 			svc:                   &mockedShortener{},
 		},
 		{
@@ -297,6 +655,9 @@ func TestDeleteAction(t *testing.T) {
 			isError:      true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrShortCode.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SHORT_CODE_REQUIRED",
+				Field:   "shortCode",
 				Details: errors.New("a required short code was not provided. Please see usage: delete <shortCode>").Error(),
 			},
 			svc: &mockedShortener{
@@ -311,7 +672,7 @@ func TestDeleteAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%s %s", ErrDelete.Error(), shortCode).Error(), Details: shortener.ErrExec.Error()},
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%s %s", ErrDelete.Error(), shortCode).Error(), Code: ExitUnavailable, Kind: "DELETE_FAILED", Details: shortener.ErrExec.Error()},
 			svc: &mockedShortener{
 				deleteFunc: func(ctx context.Context, url string) (bool, error) {
 					return false, shortener.ErrExec
@@ -324,7 +685,7 @@ func TestDeleteAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrNotFound, shortCode).Error(), Details: shortener.ErrNotFound.Error()},
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrNotFound, shortCode).Error(), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
 			svc: &mockedShortener{
 				deleteFunc: func(ctx context.Context, url string) (bool, error) {
 					return false, shortener.ErrNotFound
@@ -339,6 +700,8 @@ func TestDeleteAction(t *testing.T) {
 			isError:      true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: fmt.Errorf("failed to delete short code: %q", shortCode).Error(),
 			},
 			svc: &mockedShortener{
@@ -353,7 +716,7 @@ func TestDeleteAction(t *testing.T) {
 			listMaxLimit:          20,
 			buf:                   bytes.Buffer{},
 			isError:               true,
-			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrUnableToDelete, shortCode).Error()},
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrUnableToDelete, shortCode).Error(), Code: ExitNotFound, Kind: "NOT_DELETED"},
 			svc: &mockedShortener{
 				deleteFunc: func(ctx context.Context, url string) (bool, error) {
 					return false, nil
@@ -388,58 +751,494 @@ func TestDeleteAction(t *testing.T) {
 	}
 }
 
-func TestListAction(t *testing.T) {
+func TestUpdateAction(t *testing.T) {
+	shortCode := "Hpa3t2B"
+	newURL := "https://example.com/summer"
 	testCases := []struct {
-		name                  string
-		offset                int
-		limit                 int
-		listMaxLimit          int
-		buf                   bytes.Buffer
-		isError               bool
-		expectedErrorResponse ErrorResponse
-		expectedListResponse  ListResponse
-		action                Actions
-		svc                   shortener.URLShortener
+		name                   string
+		args                   []string
+		buf                    bytes.Buffer
+		isError                bool
+		expectedErrorResponse  ErrorResponse
+		expectedResultResponse ResultResponse
+		svc                    shortener.URLShortener
 	}{
 		{
-			name:         "success",
-			offset:       0,
-			limit:        2,
-			listMaxLimit: 20,
-			buf:          bytes.Buffer{},
-			expectedListResponse: ListResponse{
-				Items: []ResultResponse{
-					{RawURL: "https://anewball.com", ShortCode: "nMHdgTh"},
-					{RawURL: "https://jayden.newball.com", ShortCode: "k5aBWD5"},
-				}, Count: 2, Limit: 2, Offset: 0,
-			},
-			isError:               false,
-			expectedErrorResponse: ErrorResponse{},
+			name:                   "success",
+			args:                   []string{shortCode, newURL},
+			expectedResultResponse: ResultResponse{ShortCode: shortCode, RawURL: newURL},
 			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
-					return []shortener.URLItem{
-						{ID: 1, OriginalURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC), ExpiresAt: nil},
-						{ID: 2, OriginalURL: "https://jayden.newball.com", ShortCode: "k5aBWD5", CreatedAt: time.Date(2025, time.August, 25, 14, 3, 0, 0, time.UTC), ExpiresAt: nil},
-					}, nil
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return nil
 				},
 			},
 		},
 		{
-			name:         "success when limit max is zero",
-			offset:       0,
-			limit:        2,
-			listMaxLimit: -20,
-			buf:          bytes.Buffer{},
-			expectedListResponse: ListResponse{
-				Items: []ResultResponse{
-					{RawURL: "https://anewball.com", ShortCode: "nMHdgTh"},
-					{RawURL: "https://jayden.newball.com", ShortCode: "k5aBWD5"},
-				}, Count: 2, Limit: 2, Offset: 0,
+			name:                  "zero args",
+			args:                  []string{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
+			svc:                   &mockedShortener{},
+		},
+		{
+			name:    "missing URL",
+			args:    []string{shortCode},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrInvalidArgs.Error(),
+				Code:    ExitInvalid,
+				Kind:    "INVALID_ARGUMENTS",
+				Details: "a new URL is required. Please see usage: update <shortCode> <url>",
 			},
-			isError:               false,
-			expectedErrorResponse: ErrorResponse{},
-			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
+			svc: &mockedShortener{},
+		},
+		{
+			name:    "error empty short code",
+			args:    []string{"", newURL},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrShortCode.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SHORT_CODE_REQUIRED",
+				Field:   "shortCode",
+				Details: "a required short code was not provided. Please see usage: update <shortCode> <url>",
+			},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrShortCode
+				},
+			},
+		},
+		{
+			name:                  "invalid URL",
+			args:                  []string{shortCode, "ftp://example.com"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrURLFormat.Error(), Code: ExitInvalid, Kind: "URL_INVALID", Field: "url", Details: shortener.ErrIsValidURL.Error()},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrIsValidURL
+				},
+			},
+		},
+		{
+			name:                  "private destination",
+			args:                  []string{shortCode, "http://10.0.0.5/"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrDestination.Error(), Code: ExitInvalid, Kind: "URL_DESTINATION_PRIVATE", Field: "url", Details: shortener.ErrPrivateDestination.Error()},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrPrivateDestination
+				},
+			},
+		},
+		{
+			name:                  "not found",
+			args:                  []string{shortCode, newURL},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrNotFound, shortCode).Error(), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrNotFound
+				},
+			},
+		},
+		{
+			name:                  "URL already shortened elsewhere",
+			args:                  []string{shortCode, newURL},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrURLExists.Error(), Code: ExitConflict, Kind: "URL_EXISTS", Field: "url", Details: shortener.ErrURLExists.Error()},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrURLExists
+				},
+			},
+		},
+		{
+			name:                  "query error",
+			args:                  []string{shortCode, newURL},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrUpdate.Error(), Code: ExitUnavailable, Kind: "UPDATE_FAILED", Details: shortener.ErrQuery.Error()},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return shortener.ErrQuery
+				},
+			},
+		},
+		{
+			name:    "unknown error",
+			args:    []string{shortCode, newURL},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
+				Details: fmt.Errorf("failed to update short code: %q", shortCode).Error(),
+			},
+			svc: &mockedShortener{
+				updateFunc: func(ctx context.Context, shortCode, newURL string) error {
+					return errors.New("unknown error")
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(tc.svc, 20)
+
+			err := action.UpdateAction(context.Background(), &tc.buf, tc.args)
+
+			if tc.isError {
+				assert.Error(t, err)
+				var actualErrorResponse ErrorResponse
+				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
+				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actualResultResponse ResultResponse
+			jsonutil.ReadJSON(&tc.buf, &actualResultResponse)
+
+			assert.Equal(t, tc.expectedResultResponse, actualResultResponse)
+		})
+	}
+}
+
+func TestHistoryAction(t *testing.T) {
+	shortCode := "Hpa3t2B"
+	first := time.Date(2025, time.August, 20, 12, 0, 0, 0, time.UTC)
+	second := time.Date(2025, time.August, 21, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name                    string
+		args                    []string
+		buf                     bytes.Buffer
+		isError                 bool
+		expectedErrorResponse   ErrorResponse
+		expectedHistoryResponse HistoryResponse
+		svc                     shortener.URLShortener
+	}{
+		{
+			name: "success",
+			args: []string{shortCode},
+			expectedHistoryResponse: HistoryResponse{
+				ShortCode: shortCode,
+				Revisions: []RevisionResponse{
+					{Revision: 1, RawURL: "https://example.com/spring", ChangedAt: first},
+					{Revision: 2, RawURL: "https://example.com/summer", ChangedAt: second},
+				},
+			},
+			svc: &mockedShortener{
+				historyFunc: func(ctx context.Context, shortCode string) ([]shortener.Revision, error) {
+					return []shortener.Revision{
+						{Number: 1, OriginalURL: "https://example.com/spring", ChangedAt: first},
+						{Number: 2, OriginalURL: "https://example.com/summer", ChangedAt: second},
+					}, nil
+				},
+			},
+		},
+		{
+			name: "protected link hides its destinations",
+			args: []string{shortCode},
+			expectedHistoryResponse: HistoryResponse{
+				ShortCode: shortCode,
+				Revisions: []RevisionResponse{
+					{Revision: 1, ChangedAt: first, Protected: true},
+					{Revision: 2, ChangedAt: second, Protected: true},
+				},
+			},
+			svc: &mockedShortener{
+				historyFunc: func(ctx context.Context, shortCode string) ([]shortener.Revision, error) {
+					return []shortener.Revision{
+						{Number: 1, OriginalURL: "https://intranet.example.com/v1", ChangedAt: first, Protected: true},
+						{Number: 2, OriginalURL: "https://intranet.example.com/v2", ChangedAt: second, Protected: true},
+					}, nil
+				},
+			},
+		},
+		{
+			name:                  "zero args",
+			args:                  []string{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
+			svc:                   &mockedShortener{},
+		},
+		{
+			name:                  "not found",
+			args:                  []string{shortCode},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrNotFound, shortCode).Error(), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
+			svc: &mockedShortener{
+				historyFunc: func(ctx context.Context, shortCode string) ([]shortener.Revision, error) {
+					return nil, shortener.ErrNotFound
+				},
+			},
+		},
+		{
+			name:                  "query error",
+			args:                  []string{shortCode},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrHistory.Error(), Code: ExitUnavailable, Kind: "HISTORY_FAILED", Details: shortener.ErrQuery.Error()},
+			svc: &mockedShortener{
+				historyFunc: func(ctx context.Context, shortCode string) ([]shortener.Revision, error) {
+					return nil, shortener.ErrQuery
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(tc.svc, 20)
+
+			err := action.HistoryAction(context.Background(), &tc.buf, tc.args)
+
+			if tc.isError {
+				assert.Error(t, err)
+				var actualErrorResponse ErrorResponse
+				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
+				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actualHistoryResponse HistoryResponse
+			jsonutil.ReadJSON(&tc.buf, &actualHistoryResponse)
+
+			assert.Equal(t, tc.expectedHistoryResponse, actualHistoryResponse)
+		})
+	}
+}
+
+func TestRollbackAction(t *testing.T) {
+	shortCode := "Hpa3t2B"
+	testCases := []struct {
+		name                     string
+		args                     []string
+		buf                      bytes.Buffer
+		isError                  bool
+		expectedErrorResponse    ErrorResponse
+		expectedRollbackResponse RollbackResponse
+		svc                      shortener.URLShortener
+	}{
+		{
+			name:                     "success",
+			args:                     []string{shortCode, "1"},
+			expectedRollbackResponse: RollbackResponse{ShortCode: shortCode, RawURL: "https://example.com/spring", RestoredRevision: 1},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{Number: 1, OriginalURL: "https://example.com/spring"}, nil
+				},
+			},
+		},
+		{
+			name:                     "protected link",
+			args:                     []string{shortCode, "1"},
+			expectedRollbackResponse: RollbackResponse{ShortCode: shortCode, RestoredRevision: 1, Protected: true},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{Number: 1, OriginalURL: "https://intranet.example.com/handbook", Protected: true}, nil
+				},
+			},
+		},
+		{
+			name:    "missing revision",
+			args:    []string{shortCode},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrInvalidArgs.Error(),
+				Code:    ExitInvalid,
+				Kind:    "INVALID_ARGUMENTS",
+				Details: "a revision is required. Please see usage: rollback <shortCode> <revision>",
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:    "revision not a number",
+			args:    []string{shortCode, "first"},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrRevision.Error(),
+				Code:    ExitInvalid,
+				Kind:    "REVISION_INVALID",
+				Field:   "revision",
+				Details: `revision must be a positive number; got "first"`,
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:    "revision zero",
+			args:    []string{shortCode, "0"},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrRevision.Error(),
+				Code:    ExitInvalid,
+				Kind:    "REVISION_INVALID",
+				Field:   "revision",
+				Details: `revision must be a positive number; got "0"`,
+			},
+			svc: &mockedShortener{},
+		},
+		{
+			name:                  "unknown revision",
+			args:                  []string{shortCode, "9"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrRevisionNotFound.Error(), Code: ExitNotFound, Kind: "REVISION_NOT_FOUND", Details: shortener.ErrRevision.Error()},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{}, shortener.ErrRevision
+				},
+			},
+		},
+		{
+			name:                  "denied domain",
+			args:                  []string{shortCode, "1"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrDomain.Error(), Code: ExitInvalid, Kind: "URL_DOMAIN_REJECTED", Field: "url", Details: shortener.ErrDomainDenied.Error()},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{}, shortener.ErrDomainDenied
+				},
+			},
+		},
+		{
+			name:                  "private destination",
+			args:                  []string{shortCode, "1"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrDestination.Error(), Code: ExitInvalid, Kind: "URL_DESTINATION_PRIVATE", Field: "url", Details: shortener.ErrPrivateDestination.Error()},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{}, shortener.ErrPrivateDestination
+				},
+			},
+		},
+		{
+			name:                  "destination taken",
+			args:                  []string{shortCode, "1"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrURLExists.Error(), Code: ExitConflict, Kind: "URL_EXISTS", Field: "url", Details: shortener.ErrURLExists.Error()},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{}, shortener.ErrURLExists
+				},
+			},
+		},
+		{
+			name:                  "not found",
+			args:                  []string{shortCode, "1"},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: fmt.Errorf("%w: %s", ErrNotFound, shortCode).Error(), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
+			svc: &mockedShortener{
+				rollbackFunc: func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error) {
+					return shortener.Revision{}, shortener.ErrNotFound
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(tc.svc, 20)
+
+			err := action.RollbackAction(context.Background(), &tc.buf, tc.args)
+
+			if tc.isError {
+				assert.Error(t, err)
+				var actualErrorResponse ErrorResponse
+				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
+				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actualRollbackResponse RollbackResponse
+			jsonutil.ReadJSON(&tc.buf, &actualRollbackResponse)
+
+			assert.Equal(t, tc.expectedRollbackResponse, actualRollbackResponse)
+		})
+	}
+}
+
+func TestListAction(t *testing.T) {
+	listCreated := []time.Time{
+		time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC),
+		time.Date(2025, time.August, 25, 14, 3, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name                  string
+		offset                int
+		limit                 int
+		listMaxLimit          int
+		buf                   bytes.Buffer
+		isError               bool
+		expectedErrorResponse ErrorResponse
+		expectedListResponse  ListResponse
+		action                Actions
+		svc                   shortener.URLShortener
+	}{
+		{
+			name:         "success",
+			offset:       0,
+			limit:        2,
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			expectedListResponse: ListResponse{
+				Items: []ResultResponse{
+					{RawURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: &listCreated[0]},
+					{RawURL: "https://jayden.newball.com", ShortCode: "k5aBWD5", CreatedAt: &listCreated[1]},
+				}, Count: 2, Limit: 2, Offset: 0,
+			},
+			isError:               false,
+			expectedErrorResponse: ErrorResponse{},
+			svc: &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+					return []shortener.URLItem{
+						{ID: 1, OriginalURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC), ExpiresAt: nil},
+						{ID: 2, OriginalURL: "https://jayden.newball.com", ShortCode: "k5aBWD5", CreatedAt: time.Date(2025, time.August, 25, 14, 3, 0, 0, time.UTC), ExpiresAt: nil},
+					}, nil
+				},
+			},
+		},
+		{
+			name:         "protected link hides its destination",
+			limit:        2,
+			listMaxLimit: 20,
+			buf:          bytes.Buffer{},
+			expectedListResponse: ListResponse{
+				Items: []ResultResponse{
+					{RawURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: &listCreated[0]},
+					{ShortCode: "k5aBWD5", CreatedAt: &listCreated[1], Protected: true},
+				}, Count: 2, Limit: 2, Offset: 0,
+			},
+			svc: &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+					return []shortener.URLItem{
+						{ID: 1, OriginalURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: listCreated[0]},
+						{ID: 2, OriginalURL: "https://intranet.example.com/handbook", ShortCode: "k5aBWD5", CreatedAt: listCreated[1], Protected: true},
+					}, nil
+				},
+			},
+		},
+		{
+			name:         "success when limit max is zero",
+			offset:       0,
+			limit:        2,
+			listMaxLimit: -20,
+			buf:          bytes.Buffer{},
+			expectedListResponse: ListResponse{
+				Items: []ResultResponse{
+					{RawURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: &listCreated[0]},
+					{RawURL: "https://jayden.newball.com", ShortCode: "k5aBWD5", CreatedAt: &listCreated[1]},
+				}, Count: 2, Limit: 2, Offset: 0,
+			},
+			isError:               false,
+			expectedErrorResponse: ErrorResponse{},
+			svc: &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
 					return []shortener.URLItem{
 						{ID: 1, OriginalURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC), ExpiresAt: nil},
 						{ID: 2, OriginalURL: "https://jayden.newball.com", ShortCode: "k5aBWD5", CreatedAt: time.Date(2025, time.August, 25, 14, 3, 0, 0, time.UTC), ExpiresAt: nil},
@@ -457,6 +1256,9 @@ func TestListAction(t *testing.T) {
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrLimit.Error(),
+				Code:    ExitInvalid,
+				Kind:    "LIMIT_OUT_OF_RANGE",
+				Field:   "limit",
 				Details: fmt.Errorf("limit must be between 1 and %d; got %d", 20, -2).Error(),
 			},
 			svc: &mockedShortener{},
@@ -465,12 +1267,15 @@ func TestListAction(t *testing.T) {
 			name:                 "offset less than zero",
 			offset:               -2,
 			limit:                2,
-			listMaxLimit: 20,
+			listMaxLimit:         20,
 			buf:                  bytes.Buffer{},
 			expectedListResponse: ListResponse{},
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrOffset.Error(),
+				Code:    ExitInvalid,
+				Kind:    "OFFSET_INVALID",
+				Field:   "offset",
 				Details: fmt.Errorf("offset must be >= 0; got %d", -2).Error(),
 			},
 			svc: &mockedShortener{},
@@ -479,16 +1284,18 @@ func TestListAction(t *testing.T) {
 			name:                 "error query",
 			offset:               0,
 			limit:                2,
-			listMaxLimit: 20,
+			listMaxLimit:         20,
 			buf:                  bytes.Buffer{},
 			expectedListResponse: ListResponse{},
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: fmt.Errorf("error executing list query (limit=%d, offset=%d)", 2, 0).Error(),
 			},
 			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
 					return []shortener.URLItem{}, shortener.ErrQuery
 				},
 			},
@@ -497,16 +1304,18 @@ func TestListAction(t *testing.T) {
 			name:                 "error scan",
 			offset:               0,
 			limit:                2,
-			listMaxLimit: 20,
+			listMaxLimit:         20,
 			buf:                  bytes.Buffer{},
 			expectedListResponse: ListResponse{},
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: fmt.Errorf("error scanning rows (limit=%d, offset=%d)", 2, 0).Error(),
 			},
 			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
 					return []shortener.URLItem{}, shortener.ErrScan
 				},
 			},
@@ -515,16 +1324,18 @@ func TestListAction(t *testing.T) {
 			name:                 "error rows",
 			offset:               0,
 			limit:                2,
-			listMaxLimit: 20,
+			listMaxLimit:         20,
 			buf:                  bytes.Buffer{},
 			expectedListResponse: ListResponse{},
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: fmt.Errorf("row iteration error (limit=%d, offset=%d)", 2, 0).Error(),
 			},
 			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
 					return []shortener.URLItem{}, shortener.ErrRows
 				},
 			},
@@ -533,16 +1344,18 @@ func TestListAction(t *testing.T) {
 			name:                 "unknown error",
 			offset:               0,
 			limit:                2,
-			listMaxLimit: 20,
+			listMaxLimit:         20,
 			buf:                  bytes.Buffer{},
 			expectedListResponse: ListResponse{},
 			isError:              true,
 			expectedErrorResponse: ErrorResponse{
 				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
 				Details: fmt.Errorf("unknown list error (limit=%d, offset=%d)", 2, 0).Error(),
 			},
 			svc: &mockedShortener{
-				listFunc: func(ctx context.Context, limit int, offset int) ([]shortener.URLItem, error) {
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
 					return []shortener.URLItem{}, errors.New("something went wrong")
 				},
 			},
@@ -556,7 +1369,7 @@ func TestListAction(t *testing.T) {
 
 			action := NewActions(tc.svc, tc.listMaxLimit)
 
-			err := action.ListAction(ctx, tc.limit, tc.offset, &tc.buf)
+			err := action.ListAction(ctx, &tc.buf, ListOptions{Limit: tc.limit, Offset: tc.offset})
 
 			if tc.isError {
 				var actualErrorResponse ErrorResponse
@@ -574,3 +1387,432 @@ func TestListAction(t *testing.T) {
 		})
 	}
 }
+
+func TestListAction_Cursor(t *testing.T) {
+	created := time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC)
+	items := []shortener.URLItem{
+		{ID: 3, OriginalURL: "https://example.com/3", ShortCode: "code003", CreatedAt: created},
+		{ID: 2, OriginalURL: "https://example.com/2", ShortCode: "code002", CreatedAt: created},
+		{ID: 1, OriginalURL: "https://example.com/1", ShortCode: "code001", CreatedAt: created},
+	}
+	after := shortener.Cursor{Sort: shortener.SortCreated, CreatedAt: created, ID: 3}
+	newestFirst := shortener.ListOptions{Sort: shortener.SortCreated}
+
+	testCases := []struct {
+		name                  string
+		opts                  ListOptions
+		expectedQuery         shortener.ListOptions
+		expectedCodes         []string
+		expectedNext          string
+		expectedErrorResponse ErrorResponse
+	}{
+		{
+			name:          "full page has a next cursor",
+			opts:          ListOptions{Limit: 2},
+			expectedQuery: shortener.ListOptions{Limit: 3, Sort: shortener.SortCreated},
+			expectedCodes: []string{"code003", "code002"},
+			expectedNext:  shortener.CursorFor(items[1], newestFirst).String(),
+		},
+		{
+			name:          "last page has none",
+			opts:          ListOptions{Limit: 3},
+			expectedQuery: shortener.ListOptions{Limit: 4, Sort: shortener.SortCreated},
+			expectedCodes: []string{"code003", "code002", "code001"},
+		},
+		{
+			name:          "cursor resumes after a link",
+			opts:          ListOptions{Limit: 3, Cursor: after.String()},
+			expectedQuery: shortener.ListOptions{Limit: 4, After: &after, Sort: shortener.SortCreated},
+			expectedCodes: []string{"code003", "code002", "code001"},
+		},
+		{
+			name: "invalid cursor",
+			opts: ListOptions{Limit: 2, Cursor: "!!"},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrCursor.Error(),
+				Code:    ExitInvalid,
+				Kind:    "CURSOR_INVALID",
+				Field:   "cursor",
+				Details: `invalid cursor: illegal base64 data at input byte 0`,
+			},
+		},
+		{
+			name: "cursor with offset",
+			opts: ListOptions{Limit: 2, Offset: 5, Cursor: after.String()},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrOffset.Error(),
+				Code:    ExitInvalid,
+				Kind:    "OFFSET_INVALID",
+				Field:   "offset",
+				Details: "offset cannot be combined with a cursor; got 5",
+			},
+		},
+		{
+			name: "limit still validated",
+			opts: ListOptions{Limit: 50, Cursor: after.String()},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrLimit.Error(),
+				Code:    ExitInvalid,
+				Kind:    "LIMIT_OUT_OF_RANGE",
+				Field:   "limit",
+				Details: "limit must be between 1 and 20; got 50",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotQuery shortener.ListOptions
+			svc := &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+					gotQuery = opts
+					return items[:min(opts.Limit, len(items))], nil
+				},
+			}
+
+			var buf bytes.Buffer
+			err := NewActions(svc, 20).ListAction(context.Background(), &buf, tc.opts)
+
+			if tc.expectedErrorResponse.Error != "" {
+				assert.Error(t, err)
+				var actual ErrorResponse
+				require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+				assert.Equal(t, tc.expectedErrorResponse, actual)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, gotQuery)
+
+			var actual ListResponse
+			require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+			codes := make([]string, 0, len(actual.Items))
+			for _, item := range actual.Items {
+				codes = append(codes, item.ShortCode)
+			}
+			assert.Equal(t, tc.expectedCodes, codes)
+			assert.Equal(t, tc.expectedNext, actual.NextCursor)
+		})
+	}
+}
+
+func TestListAction_Filters(t *testing.T) {
+	since := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, time.August, 31, 23, 59, 59, 999999999, time.UTC)
+	exact := time.Date(2025, time.August, 15, 8, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name                  string
+		opts                  ListOptions
+		expectedQuery         shortener.ListOptions
+		expectedErrorResponse ErrorResponse
+	}{
+		{
+			name: "filters and dates",
+			opts: ListOptions{Limit: 2, Host: " example.com ", Contains: "docs", Since: "2025-08-01", Until: "2025-08-31", Expired: true},
+			expectedQuery: shortener.ListOptions{
+				Limit:  3,
+				Filter: shortener.ListFilter{Host: "example.com", Contains: "docs", Since: &since, Until: &until, IncludeExpired: true},
+				Sort:   shortener.SortCreated,
+			},
+		},
+		{
+			name:          "timestamp until is exact",
+			opts:          ListOptions{Limit: 2, Until: "2025-08-15T08:30:00Z"},
+			expectedQuery: shortener.ListOptions{Limit: 3, Filter: shortener.ListFilter{Until: &exact}, Sort: shortener.SortCreated},
+		},
+		{
+			name:          "code sorts ascending by default",
+			opts:          ListOptions{Limit: 2, Sort: "CODE"},
+			expectedQuery: shortener.ListOptions{Limit: 3, Sort: shortener.SortCode, Ascending: true},
+		},
+		{
+			name:          "explicit order",
+			opts:          ListOptions{Limit: 2, Sort: "url", Order: "desc"},
+			expectedQuery: shortener.ListOptions{Limit: 3, Sort: shortener.SortURL},
+		},
+		{
+			name:          "created ascending",
+			opts:          ListOptions{Limit: 2, Order: "asc"},
+			expectedQuery: shortener.ListOptions{Limit: 3, Sort: shortener.SortCreated, Ascending: true},
+		},
+		{
+			name: "unknown sort",
+			opts: ListOptions{Limit: 2, Sort: "clicks"},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrSort.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SORT_INVALID",
+				Field:   "sort",
+				Details: `sort must be one of created, code or url; got "clicks"`,
+			},
+		},
+		{
+			name: "unknown order",
+			opts: ListOptions{Limit: 2, Order: "up"},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrSort.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SORT_INVALID",
+				Field:   "order",
+				Details: `order must be asc or desc; got "up"`,
+			},
+		},
+		{
+			name: "bad since",
+			opts: ListOptions{Limit: 2, Since: "yesterday"},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrFilter.Error(),
+				Code:    ExitInvalid,
+				Kind:    "FILTER_INVALID",
+				Field:   "since",
+				Details: `since: want an RFC3339 timestamp or YYYY-MM-DD date; got "yesterday"`,
+			},
+		},
+		{
+			name: "empty range",
+			opts: ListOptions{Limit: 2, Since: "2025-09-01", Until: "2025-08-01"},
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrFilter.Error(),
+				Code:    ExitInvalid,
+				Kind:    "FILTER_INVALID",
+				Details: "since (2025-09-01) must not be after until (2025-08-01)",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotQuery shortener.ListOptions
+			svc := &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+					gotQuery = opts
+					return nil, nil
+				},
+			}
+
+			var buf bytes.Buffer
+			err := NewActions(svc, 20).ListAction(context.Background(), &buf, tc.opts)
+
+			if tc.expectedErrorResponse.Error != "" {
+				assert.Error(t, err)
+				var actual ErrorResponse
+				require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+				assert.Equal(t, tc.expectedErrorResponse, actual)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedQuery, gotQuery)
+		})
+	}
+}
+
+func TestPurgeAction(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		batchSize             int
+		buf                   bytes.Buffer
+		isError               bool
+		expectedErrorResponse ErrorResponse
+		expectedPurgeResponse PurgeResponse
+		svc                   shortener.URLShortener
+	}{
+		{
+			name:                  "success",
+			batchSize:             100,
+			buf:                   bytes.Buffer{},
+			expectedPurgeResponse: PurgeResponse{Purged: 42},
+			svc: &mockedShortener{
+				purgeFunc: func(ctx context.Context, batchSize int) (int64, error) {
+					return 42, nil
+				},
+			},
+		},
+		{
+			name:                  "invalid batch size",
+			batchSize:             0,
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrBatchSize.Error(), Code: ExitInvalid, Kind: "BATCH_SIZE_INVALID", Field: "batchSize", Details: shortener.ErrBatchSize.Error()},
+			svc: &mockedShortener{
+				purgeFunc: func(ctx context.Context, batchSize int) (int64, error) {
+					return 0, shortener.ErrBatchSize
+				},
+			},
+		},
+		{
+			name:      "exec error after partial purge",
+			batchSize: 100,
+			buf:       bytes.Buffer{},
+			isError:   true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrPurge.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "PURGE_FAILED",
+				Details: fmt.Sprintf("purged %d expired links before failing: %v", 100, shortener.ErrExec),
+			},
+			svc: &mockedShortener{
+				purgeFunc: func(ctx context.Context, batchSize int) (int64, error) {
+					return 100, shortener.ErrExec
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(tc.svc, 20)
+
+			err := action.PurgeAction(context.Background(), &tc.buf, tc.batchSize)
+
+			if tc.isError {
+				assert.Error(t, err)
+				var actualErrorResponse ErrorResponse
+				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
+				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actualPurgeResponse PurgeResponse
+			jsonutil.ReadJSON(&tc.buf, &actualPurgeResponse)
+
+			assert.Equal(t, tc.expectedPurgeResponse, actualPurgeResponse)
+		})
+	}
+}
+
+func TestStatsAction(t *testing.T) {
+	shortCode := "Hpa3t2B"
+	first := time.Date(2025, time.August, 20, 9, 0, 0, 0, time.UTC)
+	last := time.Date(2025, time.August, 21, 18, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		name                  string
+		args                  []string
+		buf                   bytes.Buffer
+		isError               bool
+		expectedErrorResponse ErrorResponse
+		expectedStatsResponse StatsResponse
+		svc                   shortener.URLShortener
+	}{
+		{
+			name: "success",
+			args: []string{shortCode},
+			buf:  bytes.Buffer{},
+			expectedStatsResponse: StatsResponse{
+				ShortCode:   shortCode,
+				TotalClicks: 3,
+				FirstClick:  &first,
+				LastClick:   &last,
+				Daily: []DailyClickResponse{
+					{Date: "2025-08-20", Clicks: 2},
+					{Date: "2025-08-21", Clicks: 1},
+				},
+			},
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{
+						TotalClicks: 3,
+						FirstClick:  &first,
+						LastClick:   &last,
+						Daily: []shortener.DailyClicks{
+							{Day: time.Date(2025, time.August, 20, 0, 0, 0, 0, time.UTC), Clicks: 2},
+							{Day: time.Date(2025, time.August, 21, 0, 0, 0, 0, time.UTC), Clicks: 1},
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			name:                  "no clicks yet",
+			args:                  []string{shortCode},
+			buf:                   bytes.Buffer{},
+			expectedStatsResponse: StatsResponse{ShortCode: shortCode, Daily: []DailyClickResponse{}},
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{}, nil
+				},
+			},
+		},
+		{
+			name:                  "zero args",
+			args:                  []string{},
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: ErrLenZero.Error(), Code: ExitInvalid, Kind: "ARGUMENT_MISSING"},
+			svc:                   &mockedShortener{},
+		},
+		{
+			name:    "error empty short code",
+			args:    []string{""},
+			buf:     bytes.Buffer{},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrShortCode.Error(),
+				Code:    ExitInvalid,
+				Kind:    "SHORT_CODE_REQUIRED",
+				Field:   "shortCode",
+				Details: "a required short code was not provided. Please see usage: stats <shortCode>",
+			},
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{}, shortener.ErrShortCode
+				},
+			},
+		},
+		{
+			name:                  "error not found",
+			args:                  []string{shortCode},
+			buf:                   bytes.Buffer{},
+			isError:               true,
+			expectedErrorResponse: ErrorResponse{Error: fmt.Sprintf("%s: %s", ErrNotFound, shortCode), Code: ExitNotFound, Kind: "NOT_FOUND", Details: shortener.ErrNotFound.Error()},
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{}, shortener.ErrNotFound
+				},
+			},
+		},
+		{
+			name:    "error query",
+			args:    []string{shortCode},
+			buf:     bytes.Buffer{},
+			isError: true,
+			expectedErrorResponse: ErrorResponse{
+				Error:   ErrUnexpected.Error(),
+				Code:    ExitUnavailable,
+				Kind:    "UNEXPECTED",
+				Details: fmt.Sprintf("failed to retrieve stats for short code: %q", shortCode),
+			},
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{}, shortener.ErrQuery
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(tc.svc, 20)
+
+			err := action.StatsAction(context.Background(), &tc.buf, tc.args)
+
+			if tc.isError {
+				assert.Error(t, err)
+				var actualErrorResponse ErrorResponse
+				jsonutil.ReadJSON(&tc.buf, &actualErrorResponse)
+				assert.Equal(t, tc.expectedErrorResponse, actualErrorResponse)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var actualStatsResponse StatsResponse
+			jsonutil.ReadJSON(&tc.buf, &actualStatsResponse)
+
+			assert.Equal(t, tc.expectedStatsResponse, actualStatsResponse)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+// Exit codes of the urlshortener command, one per kind of failure. Scripts
+// depend on them, so a value is never reused for another meaning. The same
+// number is reported as ErrorResponse.Code.
+const (
+	ExitOK          = 0
+	ExitError       = 1 // any failure not listed below
+	ExitInvalid     = 2 // the arguments or input were rejected
+	ExitNotFound    = 3 // no such link or revision
+	ExitConflict    = 4 // the alias or URL is already taken
+	ExitUnavailable = 5 // storage failed or timed out
+	ExitPartial     = 6 // an import finished but some rows failed
+	ExitDenied      = 7 // the link needs a password, or a different one
+)
+
+// KindUnknown is the ErrorResponse.Kind of an error no sentinel matches.
+const KindUnknown = "UNKNOWN"
+
+// errorKind describes a sentinel to clients. kind is a stable identifier
+// for ErrorResponse.Kind that, unlike the message, never changes once
+// published. field names the input a validation error is about.
+type errorKind struct {
+	err   error
+	kind  string
+	exit  int
+	field string
+}
+
+// errorKinds covers every sentinel of core and then of shortener, so an
+// error chain is described by its outermost, most user-facing sentinel. The
+// first entry matching a chain wins.
+var errorKinds = []errorKind{
+	{ErrInvalidArgs, "INVALID_ARGUMENTS", ExitInvalid, ""},
+	{ErrLenZero, "ARGUMENT_MISSING", ExitInvalid, ""},
+	{ErrLimit, "LIMIT_OUT_OF_RANGE", ExitInvalid, "limit"},
+	{ErrOffset, "OFFSET_INVALID", ExitInvalid, "offset"},
+	{ErrCursor, "CURSOR_INVALID", ExitInvalid, "cursor"},
+	{ErrSort, "SORT_INVALID", ExitInvalid, "sort"},
+	{ErrFilter, "FILTER_INVALID", ExitInvalid, ""},
+	{ErrURLFormat, "URL_INVALID", ExitInvalid, "url"},
+	{ErrDestination, "URL_DESTINATION_PRIVATE", ExitInvalid, "url"},
+	{ErrDomain, "URL_DOMAIN_REJECTED", ExitInvalid, "url"},
+	{ErrPasswordRequired, "PASSWORD_REQUIRED", ExitDenied, "password"},
+	{ErrWrongPassword, "PASSWORD_WRONG", ExitDenied, "password"},
+	{ErrShortCode, "SHORT_CODE_REQUIRED", ExitInvalid, "shortCode"},
+	{ErrAliasFormat, "ALIAS_INVALID", ExitInvalid, "alias"},
+	{ErrExpiry, "EXPIRY_INVALID", ExitInvalid, "expiresAt"},
+	{ErrBatchSize, "BATCH_SIZE_INVALID", ExitInvalid, "batchSize"},
+	{ErrRevision, "REVISION_INVALID", ExitInvalid, "revision"},
+	{ErrImportFormat, "IMPORT_FORMAT_INVALID", ExitInvalid, "format"},
+	{ErrImportRead, "IMPORT_UNREADABLE", ExitInvalid, "file"},
+	{ErrExportFormat, "EXPORT_FORMAT_INVALID", ExitInvalid, "format"},
+
+	{ErrNotFound, "NOT_FOUND", ExitNotFound, ""},
+	{ErrRevisionNotFound, "REVISION_NOT_FOUND", ExitNotFound, ""},
+	{ErrUnableToDelete, "NOT_DELETED", ExitNotFound, ""},
+
+	{ErrAliasTaken, "ALIAS_TAKEN", ExitConflict, "alias"},
+	{ErrURLExists, "URL_EXISTS", ExitConflict, "url"},
+
+	{ErrUnexpected, "UNEXPECTED", ExitUnavailable, ""},
+	{ErrTimeout, "TIMEOUT", ExitUnavailable, ""},
+	{context.DeadlineExceeded, "TIMEOUT", ExitUnavailable, ""},
+	{ErrAdd, "ADD_FAILED", ExitUnavailable, ""},
+	{ErrUpdate, "UPDATE_FAILED", ExitUnavailable, ""},
+	{ErrDelete, "DELETE_FAILED", ExitUnavailable, ""},
+	{ErrDeleteUnsupported, "DELETE_UNSUPPORTED", ExitUnavailable, ""},
+	{ErrHistory, "HISTORY_FAILED", ExitUnavailable, ""},
+	{ErrPurge, "PURGE_FAILED", ExitUnavailable, ""},
+	{ErrExport, "EXPORT_FAILED", ExitUnavailable, ""},
+	{ErrQuery, "QUERY_FAILED", ExitUnavailable, ""},
+	{ErrScan, "SCAN_FAILED", ExitUnavailable, ""},
+	{ErrRows, "ROWS_FAILED", ExitUnavailable, ""},
+	{ErrUnknownList, "LIST_FAILED", ExitUnavailable, ""},
+	{ErrUnsupported, "UNSUPPORTED", ExitError, ""},
+
+	{ErrImport, "IMPORT_PARTIAL", ExitPartial, ""},
+
+	{shortener.ErrIsValidURL, "URL_INVALID", ExitInvalid, "url"},
+	{shortener.ErrEmptyURL, "URL_EMPTY", ExitInvalid, "url"},
+	{shortener.ErrTooLong, "URL_TOO_LONG", ExitInvalid, "url"},
+	{shortener.ErrParse, "URL_UNPARSABLE", ExitInvalid, "url"},
+	{shortener.ErrEmptyScheme, "URL_SCHEME_MISSING", ExitInvalid, "url"},
+	{shortener.ErrScheme, "URL_SCHEME_UNSUPPORTED", ExitInvalid, "url"},
+	{shortener.ErrEmptyHost, "URL_HOST_MISSING", ExitInvalid, "url"},
+	{shortener.ErrIDN, "URL_HOST_INVALID", ExitInvalid, "url"},
+	{shortener.ErrMixedScript, "URL_HOST_MIXED_SCRIPT", ExitInvalid, "url"},
+	{shortener.ErrPrivateDestination, "URL_DESTINATION_PRIVATE", ExitInvalid, "url"},
+	{shortener.ErrDomainDenied, "URL_DOMAIN_DENIED", ExitInvalid, "url"},
+	{shortener.ErrDomainNotAllowed, "URL_DOMAIN_NOT_ALLOWED", ExitInvalid, "url"},
+	{shortener.ErrRule, "RULE_INVALID", ExitInvalid, "rule"},
+	{shortener.ErrPassword, "PASSWORD_INVALID", ExitInvalid, "password"},
+	{shortener.ErrPasswordRequired, "PASSWORD_REQUIRED", ExitDenied, "password"},
+	{shortener.ErrWrongPassword, "PASSWORD_WRONG", ExitDenied, "password"},
+	{shortener.ErrShortCode, "SHORT_CODE_REQUIRED", ExitInvalid, "shortCode"},
+	{shortener.ErrParamPolicy, "PARAM_PATTERN_INVALID", ExitInvalid, "keepParams"},
+	{shortener.ErrAlias, "ALIAS_INVALID", ExitInvalid, "alias"},
+	{shortener.ErrAliasLength, "ALIAS_LENGTH", ExitInvalid, "alias"},
+	{shortener.ErrAliasChars, "ALIAS_CHARACTERS", ExitInvalid, "alias"},
+	{shortener.ErrAliasReserved, "ALIAS_RESERVED", ExitInvalid, "alias"},
+	{shortener.ErrExpiresAt, "EXPIRY_IN_PAST", ExitInvalid, "expiresAt"},
+	{shortener.ErrBatchSize, "BATCH_SIZE_INVALID", ExitInvalid, "batchSize"},
+	{shortener.ErrCursor, "CURSOR_INVALID", ExitInvalid, "cursor"},
+	{shortener.ErrSort, "SORT_INVALID", ExitInvalid, "sort"},
+	{shortener.ErrNotFound, "NOT_FOUND", ExitNotFound, ""},
+	{shortener.ErrRevision, "REVISION_NOT_FOUND", ExitNotFound, ""},
+	{shortener.ErrAliasTaken, "ALIAS_TAKEN", ExitConflict, "alias"},
+	{shortener.ErrURLExists, "URL_EXISTS", ExitConflict, "url"},
+	{shortener.ErrDuplicateShortCode, "SHORT_CODE_TAKEN", ExitConflict, ""},
+	{shortener.ErrCollision, "CODE_COLLISION", ExitUnavailable, ""},
+	{shortener.ErrGenerate, "CODE_GENERATION_FAILED", ExitUnavailable, ""},
+	{shortener.ErrExec, "EXEC_FAILED", ExitUnavailable, ""},
+	{shortener.ErrQuery, "QUERY_FAILED", ExitUnavailable, ""},
+	{shortener.ErrQueryRow, "NO_ROWS", ExitUnavailable, ""},
+	{shortener.ErrScan, "SCAN_FAILED", ExitUnavailable, ""},
+	{shortener.ErrRows, "ROWS_FAILED", ExitUnavailable, ""},
+	{shortener.ErrUnavailable, "STORAGE_UNAVAILABLE", ExitUnavailable, ""},
+	{shortener.ErrDBNil, "DB_NIL", ExitError, ""},
+	{shortener.ErrStoreNil, "STORE_NIL", ExitError, ""},
+	{shortener.ErrNanoIDNil, "GENERATOR_NIL", ExitError, ""},
+}
+
+func kindOf(err error) (errorKind, bool) {
+	if err == nil {
+		return errorKind{}, false
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k, true
+		}
+	}
+	return errorKind{}, false
+}
+
+// ExitCode returns the exit code for err: ExitOK for nil, the code of the
+// first sentinel in its chain, or ExitError.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if k, ok := kindOf(err); ok {
+		return k.exit
+	}
+	return ExitError
+}
+
+// ErrorKind returns the stable kind of the first sentinel in the chain of
+// err, or KindUnknown.
+func ErrorKind(err error) string {
+	if k, ok := kindOf(err); ok {
+		return k.kind
+	}
+	return KindUnknown
+}
+
+// fieldError names the input field err is about when the sentinel alone
+// does not say, as with an expiry given either as a TTL or a timestamp.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string { return e.err.Error() }
+func (e *fieldError) Unwrap() error { return e.err }
+
+// WithField marks err as being about the input field. The message of err is
+// unchanged; the field shows up in ErrorResponse.Field.
+func WithField(field string, err error) error {
+	return &fieldError{field: field, err: err}
+}
+
+// NewErrorResponse describes a failure reported as code with the given
+// cause, which may be nil. Code, Kind and Field come from code or,
+// when code is not a known sentinel, from cause.
+func NewErrorResponse(code error, cause error) ErrorResponse {
+	resp := ErrorResponse{Error: code.Error(), Code: ExitError, Kind: KindUnknown}
+	if cause != nil {
+		resp.Details = cause.Error()
+	}
+
+	k, ok := kindOf(code)
+	if !ok {
+		k, ok = kindOf(cause)
+	}
+	if ok {
+		resp.Code, resp.Kind, resp.Field = k.exit, k.kind, k.field
+	}
+
+	var fe *fieldError
+	if errors.As(cause, &fe) {
+		resp.Field = fe.field
+	}
+	return resp
+}
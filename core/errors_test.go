@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: ExitOK},
+		{name: "invalid input", err: ErrURLFormat, expected: ExitInvalid},
+		{name: "invalid limit wrapped", err: fmt.Errorf("%w: limit must be between 1 and 20; got 50", ErrLimit), expected: ExitInvalid},
+		{name: "not found", err: fmt.Errorf("%w: Hpa3t2B", ErrNotFound), expected: ExitNotFound},
+		{name: "nothing deleted", err: ErrUnableToDelete, expected: ExitNotFound},
+		{name: "conflict", err: ErrAliasTaken, expected: ExitConflict},
+		{name: "storage failure", err: ErrUnexpected, expected: ExitUnavailable},
+		{name: "timeout", err: fmt.Errorf("list: %w", context.DeadlineExceeded), expected: ExitUnavailable},
+		{name: "database unreachable", err: fmt.Errorf("db: ping failed: %w: connection refused", shortener.ErrUnavailable), expected: ExitUnavailable},
+		{name: "partial import", err: fmt.Errorf("%w: 1 of 3 rows failed", ErrImport), expected: ExitPartial},
+		{name: "anything else", err: errors.New("boom"), expected: ExitError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ExitCode(tc.err))
+		})
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "core sentinel", err: ErrURLFormat, expected: "URL_INVALID"},
+		{name: "wrapped core sentinel", err: fmt.Errorf("%w: limit must be between 1 and 20; got 50", ErrLimit), expected: "LIMIT_OUT_OF_RANGE"},
+		{name: "shortener sentinel", err: shortener.ErrAliasReserved, expected: "ALIAS_RESERVED"},
+		{name: "core wins over shortener", err: fmt.Errorf("%w: %w", ErrNotFound, shortener.ErrNotFound), expected: "NOT_FOUND"},
+		{name: "timeout", err: fmt.Errorf("list: %w", context.DeadlineExceeded), expected: "TIMEOUT"},
+		{name: "anything else", err: errors.New("boom"), expected: KindUnknown},
+		{name: "nil", err: nil, expected: KindUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ErrorKind(tc.err))
+		})
+	}
+}
+
+func TestErrorKinds_KindsAreStable(t *testing.T) {
+	valid := regexp.MustCompile(`^[A-Z]+(_[A-Z]+)*$`)
+	for _, k := range errorKinds {
+		assert.Regexp(t, valid, k.kind, "kind of %v", k.err)
+	}
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     error
+		cause    error
+		expected ErrorResponse
+	}{
+		{
+			name:     "validation error names its field",
+			code:     ErrLimit,
+			cause:    errors.New("limit must be between 1 and 20; got 50"),
+			expected: ErrorResponse{Error: ErrLimit.Error(), Code: ExitInvalid, Kind: "LIMIT_OUT_OF_RANGE", Field: "limit", Details: "limit must be between 1 and 20; got 50"},
+		},
+		{
+			name:     "field from the cause",
+			code:     ErrExpiry,
+			cause:    WithField("ttl", errors.New("ttl must be positive; got -1h0m0s")),
+			expected: ErrorResponse{Error: ErrExpiry.Error(), Code: ExitInvalid, Kind: "EXPIRY_INVALID", Field: "ttl", Details: "ttl must be positive; got -1h0m0s"},
+		},
+		{
+			name:     "kind from the cause",
+			code:     errors.New("something failed"),
+			cause:    shortener.ErrTooLong,
+			expected: ErrorResponse{Error: "something failed", Code: ExitInvalid, Kind: "URL_TOO_LONG", Field: "url", Details: shortener.ErrTooLong.Error()},
+		},
+		{
+			name:     "no cause",
+			code:     ErrUnableToDelete,
+			expected: ErrorResponse{Error: ErrUnableToDelete.Error(), Code: ExitNotFound, Kind: "NOT_DELETED"},
+		},
+		{
+			name:     "unknown",
+			code:     errors.New("boom"),
+			expected: ErrorResponse{Error: "boom", Code: ExitError, Kind: KindUnknown},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NewErrorResponse(tc.code, tc.cause))
+		})
+	}
+}
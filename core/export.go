@@ -0,0 +1,134 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/jsonutil"
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+var (
+	ErrExport       = errors.New("unable to export links")
+	ErrExportFormat = errors.New("unsupported export format")
+)
+
+// ExportOptions configures ExportAction. Format is FormatCSV or FormatJSONL.
+// When Warnings is set, a note about links that cannot be restored from the
+// export is written to it once the export is done.
+type ExportOptions struct {
+	Format   string
+	Warnings io.Writer
+}
+
+// ExportItem is one line of a JSON Lines export. Unlike ListResponse items it
+// carries the ID and creation time. URL is the canonical destination as
+// stored. As in a listing, the destination and password of a Protected link
+// are left out, so an export is not a complete backup: protected links have
+// to be recreated by hand.
+type ExportItem struct {
+	ID        uint64     `json:"id"`
+	ShortCode string     `json:"shortCode"`
+	URL       string     `json:"url"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Protected bool       `json:"protected,omitempty"`
+}
+
+// exportHeader names the CSV columns. ImportAction accepts them all, so an
+// export can be imported as it is.
+var exportHeader = []string{"id", "short_code", "url", "created_at", "expires_at", "protected"}
+
+// exportURL is the destination an export shows for item: none when a
+// password guards it.
+func exportURL(item shortener.URLItem) string {
+	if item.Protected {
+		return ""
+	}
+	return item.OriginalURL
+}
+
+// ExportAction writes every link, expired ones included, to out as it is read
+// from the store, so memory use stays flat however many links there are.
+// There is no timeout: a large export takes as long as it takes, and ctx
+// still cancels it.
+//
+// Once rows have been written an error cannot be reported in the same stream,
+// so failures are returned wrapped in ErrExport and out may hold a partial
+// export.
+func (a *actions) ExportAction(ctx context.Context, out io.Writer, opts ExportOptions) error {
+	w := bufio.NewWriter(out)
+	protected := 0
+
+	var write func(shortener.URLItem) error
+	var flush func() error
+	switch strings.ToLower(opts.Format) {
+	case FormatJSONL:
+		write = func(item shortener.URLItem) error {
+			return jsonutil.WriteJSON(w, ExportItem{
+				ID:        item.ID,
+				ShortCode: item.ShortCode,
+				URL:       exportURL(item),
+				CreatedAt: item.CreatedAt,
+				ExpiresAt: item.ExpiresAt,
+				Protected: item.Protected,
+			})
+		}
+		flush = w.Flush
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportHeader); err != nil {
+			return fmt.Errorf("%w: %v", ErrExport, err)
+		}
+		write = func(item shortener.URLItem) error {
+			expiresAt := ""
+			if item.ExpiresAt != nil {
+				expiresAt = item.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			return cw.Write([]string{
+				strconv.FormatUint(item.ID, 10),
+				item.ShortCode,
+				exportURL(item),
+				item.CreatedAt.UTC().Format(time.RFC3339),
+				expiresAt,
+				strconv.FormatBool(item.Protected),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return w.Flush()
+		}
+	default:
+		return writeAndReturnError(out, ErrExportFormat,
+			fmt.Errorf("format must be %s or %s; got %q", FormatCSV, FormatJSONL, opts.Format))
+	}
+
+	count := func(item shortener.URLItem) error {
+		if item.Protected {
+			protected++
+		}
+		return write(item)
+	}
+	if err := a.svc.Export(ctx, count); err != nil {
+		_ = flush()
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	if protected > 0 && opts.Warnings != nil {
+		fmt.Fprintf(opts.Warnings, "warning: %d password-protected link(s) exported without their destination; they cannot be restored from this export\n", protected)
+	}
+	return nil
+}
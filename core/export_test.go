@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/jsonutil"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportCreated = time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC)
+
+var exportItems = []shortener.URLItem{
+	{ID: 1, OriginalURL: "https://example.com/a", ShortCode: "GL9VeCa", CreatedAt: exportCreated},
+	{ID: 2, OriginalURL: "https://example.com/b?x=1,2", ShortCode: "GL9VeCb", CreatedAt: exportCreated.Add(time.Minute), ExpiresAt: &exportCreated},
+	{ID: 3, OriginalURL: "https://intranet.example.com/handbook", ShortCode: "GL9VeCc", CreatedAt: exportCreated, Protected: true},
+}
+
+func exportAll(items []shortener.URLItem, err error) func(context.Context, func(shortener.URLItem) error) error {
+	return func(ctx context.Context, fn func(shortener.URLItem) error) error {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func TestExportAction(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			expected: `{"id":1,"shortCode":"GL9VeCa","url":"https://example.com/a","createdAt":"2025-08-20T12:00:00Z"}` + "\n" +
+				`{"id":2,"shortCode":"GL9VeCb","url":"https://example.com/b?x=1,2","createdAt":"2025-08-20T12:01:00Z","expiresAt":"2025-08-20T12:00:00Z"}` + "\n" +
+				`{"id":3,"shortCode":"GL9VeCc","url":"","createdAt":"2025-08-20T12:00:00Z","protected":true}` + "\n",
+		},
+		{
+			name:   "csv",
+			format: "CSV",
+			expected: "id,short_code,url,created_at,expires_at,protected\n" +
+				"1,GL9VeCa,https://example.com/a,2025-08-20T12:00:00Z,,false\n" +
+				"2,GL9VeCb,\"https://example.com/b?x=1,2\",2025-08-20T12:01:00Z,2025-08-20T12:00:00Z,false\n" +
+				"3,GL9VeCc,,2025-08-20T12:00:00Z,,true\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(&mockedShortener{exportFunc: exportAll(exportItems, nil)}, 20)
+
+			var buf, warnings bytes.Buffer
+			require.NoError(t, action.ExportAction(context.Background(), &buf, ExportOptions{Format: tc.format, Warnings: &warnings}))
+			assert.Equal(t, tc.expected, buf.String())
+			assert.Equal(t, "warning: 1 password-protected link(s) exported without their destination; they cannot be restored from this export\n", warnings.String())
+		})
+	}
+}
+
+func TestExportAction_NoProtectedLinksNoWarning(t *testing.T) {
+	action := NewActions(&mockedShortener{exportFunc: exportAll(exportItems[:2], nil)}, 20)
+
+	var buf, warnings bytes.Buffer
+	require.NoError(t, action.ExportAction(context.Background(), &buf, ExportOptions{Format: FormatJSONL, Warnings: &warnings}))
+	assert.Empty(t, warnings.String())
+}
+
+func TestExportAction_Errors(t *testing.T) {
+	t.Run("unknown format", func(t *testing.T) {
+		action := NewActions(&mockedShortener{}, 20)
+
+		var buf bytes.Buffer
+		err := action.ExportAction(context.Background(), &buf, ExportOptions{Format: "xml"})
+
+		assert.ErrorIs(t, err, ErrExportFormat)
+		var actual ErrorResponse
+		require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+		assert.Equal(t, ErrorResponse{Error: ErrExportFormat.Error(), Code: ExitInvalid, Kind: "EXPORT_FORMAT_INVALID", Field: "format", Details: `format must be csv or jsonl; got "xml"`}, actual)
+	})
+
+	t.Run("store fails mid-stream", func(t *testing.T) {
+		action := NewActions(&mockedShortener{exportFunc: exportAll(exportItems[:1], errors.New("connection reset"))}, 20)
+
+		var buf bytes.Buffer
+		err := action.ExportAction(context.Background(), &buf, ExportOptions{Format: FormatJSONL})
+
+		assert.ErrorIs(t, err, ErrExport)
+		assert.ErrorContains(t, err, "connection reset")
+		assert.Contains(t, buf.String(), `"id":1`, "rows read before the failure are still written")
+	})
+}
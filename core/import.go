@@ -0,0 +1,364 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+// File formats accepted by ImportAction and produced by ExportAction.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const (
+	DefaultImportBatchSize = 100
+	defaultImportTimeout   = 30 * time.Second
+	// maxImportLine bounds a single JSON Lines record; URLs are capped far
+	// below this, so anything longer is not a link.
+	maxImportLine = 64 * 1024
+)
+
+// Statuses of an ImportRowResponse.
+const (
+	ImportCreated  = "created"
+	ImportExisting = "existing"
+	ImportFailed   = "failed"
+)
+
+var (
+	ErrImport       = errors.New("some rows could not be imported")
+	ErrImportFormat = errors.New("unsupported import format")
+	ErrImportRead   = errors.New("unable to read import file")
+)
+
+// ImportOptions configures ImportAction. Format is FormatCSV or FormatJSONL.
+type ImportOptions struct {
+	Format    string
+	BatchSize int
+}
+
+type ImportResponse struct {
+	Rows    []ImportRowResponse `json:"rows"`
+	Summary ImportSummary       `json:"summary"`
+}
+
+// ImportRowResponse reports one input record. Line is the record's line in
+// the input file, counting a CSV header.
+type ImportRowResponse struct {
+	Line      int        `json:"line"`
+	RawURL    string     `json:"rawUrl"`
+	Status    string     `json:"status"`
+	ShortCode string     `json:"shortCode,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+type ImportSummary struct {
+	Total    int `json:"total"`
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+	Failed   int `json:"failed"`
+}
+
+// importRecord is one link read from the input, before validation. The JSON
+// tags match the body of POST /api/v1/urls and the fields of ExportItem.
+// ShortCode is the code an export recorded and is restored as the alias; ID
+// and CreatedAt are accepted so an export can be imported as it is, but new
+// rows always get their own.
+type importRecord struct {
+	URL       string `json:"url"`
+	Alias     string `json:"alias"`
+	TTL       string `json:"ttl"`
+	ExpiresAt string `json:"expiresAt"`
+	ShortCode string `json:"shortCode"`
+	Protected bool   `json:"protected"`
+	ID        any    `json:"id"`
+	CreatedAt any    `json:"createdAt"`
+
+	line int
+	err  error
+}
+
+// resolve folds the export fields of rec into the ones Add understands.
+// Protected links are exported without their destination, so they fail
+// with a reason rather than as an empty URL.
+func (rec *importRecord) resolve() {
+	if rec.err != nil {
+		return
+	}
+	switch {
+	case rec.Protected:
+		rec.err = fmt.Errorf("%w: password-protected link %s was exported without its destination", ErrInvalidArgs, rec.ShortCode)
+	case rec.ShortCode != "" && rec.Alias != "" && rec.ShortCode != rec.Alias:
+		rec.err = fmt.Errorf("%w: alias %q and short code %q disagree", ErrInvalidArgs, rec.Alias, rec.ShortCode)
+	case rec.ShortCode != "":
+		rec.Alias = rec.ShortCode
+	}
+}
+
+// ImportAction reads links from in and saves them batchSize at a time. Every
+// record gets a row in the report, whether it was created, already existed,
+// or failed; a bad record never stops the import. The returned error wraps
+// ErrImport when any row failed, after the report has been written.
+//
+// CSV input may start with a header naming the columns url, alias, ttl and
+// expires_at in any order, along with the other columns ExportAction writes.
+// Without a header the columns are url, alias and expires_at. JSON Lines
+// input has one object per line with the fields url, alias, ttl and
+// expiresAt, or is an ExportAction export. An exported link keeps its short
+// code.
+func (a *actions) ImportAction(ctx context.Context, out io.Writer, in io.Reader, opts ImportOptions) error {
+	if opts.BatchSize <= 0 {
+		return writeAndReturnError(out, ErrBatchSize,
+			fmt.Errorf("batch size must be greater than zero; got %d", opts.BatchSize))
+	}
+
+	var next func() (importRecord, error)
+	switch strings.ToLower(opts.Format) {
+	case FormatCSV:
+		next = csvRecords(in)
+	case FormatJSONL:
+		next = jsonlRecords(in)
+	default:
+		return writeAndReturnError(out, ErrImportFormat,
+			fmt.Errorf("format must be %s or %s; got %q", FormatCSV, FormatJSONL, opts.Format))
+	}
+
+	response := ImportResponse{Rows: make([]ImportRowResponse, 0)}
+	batch := make([]importRecord, 0, opts.BatchSize)
+	for {
+		rec, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return writeAndReturnError(out, ErrImportRead, err)
+		}
+
+		batch = append(batch, rec)
+		if len(batch) == opts.BatchSize {
+			response.Rows = append(response.Rows, a.importBatch(ctx, batch)...)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		response.Rows = append(response.Rows, a.importBatch(ctx, batch)...)
+	}
+
+	for _, row := range response.Rows {
+		response.Summary.Total++
+		switch row.Status {
+		case ImportCreated:
+			response.Summary.Created++
+		case ImportExisting:
+			response.Summary.Existing++
+		default:
+			response.Summary.Failed++
+		}
+	}
+
+	if err := output.Write(out, response); err != nil {
+		return err
+	}
+
+	if response.Summary.Failed > 0 {
+		return fmt.Errorf("%w: %d of %d rows failed", ErrImport, response.Summary.Failed, response.Summary.Total)
+	}
+	return nil
+}
+
+// importBatch saves the valid records of batch in one call and returns a row
+// for every record, in input order.
+func (a *actions) importBatch(ctx context.Context, batch []importRecord) []ImportRowResponse {
+	ctx, cancel := context.WithTimeout(ctx, defaultImportTimeout)
+	defer cancel()
+
+	rows := make([]ImportRowResponse, len(batch))
+	items := make([]shortener.BatchItem, 0, len(batch))
+	pending := make([]int, 0, len(batch))
+
+	for i, rec := range batch {
+		rows[i] = ImportRowResponse{Line: rec.line, RawURL: rec.URL}
+		if rec.err != nil {
+			rows[i].Status, rows[i].Error = ImportFailed, rec.err.Error()
+			continue
+		}
+
+		expiresAt, err := recordExpiry(rec)
+		if err != nil {
+			rows[i].Status, rows[i].Error = ImportFailed, fmt.Errorf("%w: %v", ErrExpiry, err).Error()
+			continue
+		}
+
+		items = append(items, shortener.BatchItem{URL: rec.URL, Opts: shortener.AddOptions{Alias: rec.Alias, ExpiresAt: expiresAt}})
+		pending = append(pending, i)
+	}
+
+	if len(items) == 0 {
+		return rows
+	}
+
+	results := a.svc.AddBatch(ctx, items)
+	for j, i := range pending {
+		if j >= len(results) {
+			rows[i].Status, rows[i].Error = ImportFailed, ErrUnexpected.Error()
+			continue
+		}
+
+		res := results[j]
+		if res.Err != nil {
+			code, cause := classifyAddError(res.Err)
+			rows[i].Status, rows[i].Error = ImportFailed, fmt.Errorf("%w: %v", code, cause).Error()
+			continue
+		}
+
+		rows[i].Status = ImportExisting
+		if res.Created {
+			rows[i].Status = ImportCreated
+		}
+		rows[i].ShortCode, rows[i].ExpiresAt = res.ShortCode, res.ExpiresAt
+	}
+
+	return rows
+}
+
+func recordExpiry(rec importRecord) (*time.Time, error) {
+	opts := AddOptions{ExpiresAt: rec.ExpiresAt}
+	if rec.TTL != "" {
+		ttl, err := time.ParseDuration(rec.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("ttl must be a duration such as 72h; got %q", rec.TTL)
+		}
+		opts.TTL = ttl
+	}
+	return resolveExpiry(opts)
+}
+
+// csvRecords returns an iterator over the records of a CSV file. Malformed
+// rows come back as records carrying err so they are reported, not fatal.
+func csvRecords(in io.Reader) func() (importRecord, error) {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	columns := map[string]int{"url": 0, "alias": 1, "expires_at": 2}
+	first := true
+
+	return func() (importRecord, error) {
+		for {
+			fields, err := r.Read()
+			if err == io.EOF {
+				return importRecord{}, io.EOF
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return importRecord{line: parseErr.StartLine, err: fmt.Errorf("%w: %v", ErrInvalidArgs, parseErr.Err)}, nil
+			}
+			if err != nil {
+				return importRecord{}, err
+			}
+			line, _ := r.FieldPos(0)
+
+			if first {
+				first = false
+				if slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(strings.TrimSpace(f), "url") }) {
+					header, err := csvHeader(fields)
+					if err != nil {
+						return importRecord{}, err
+					}
+					columns = header
+					continue
+				}
+			}
+
+			field := func(name string) string {
+				i, ok := columns[name]
+				if !ok || i >= len(fields) {
+					return ""
+				}
+				return strings.TrimSpace(fields[i])
+			}
+			rec := importRecord{
+				URL:       field("url"),
+				Alias:     field("alias"),
+				TTL:       field("ttl"),
+				ExpiresAt: field("expires_at"),
+				ShortCode: field("short_code"),
+				line:      line,
+			}
+			if v := field("protected"); v != "" {
+				protected, err := strconv.ParseBool(v)
+				if err != nil {
+					rec.err = fmt.Errorf("%w: protected must be true or false; got %q", ErrInvalidArgs, v)
+				}
+				rec.Protected = protected
+			}
+			rec.resolve()
+			return rec, nil
+		}
+	}
+}
+
+func csvHeader(fields []string) (map[string]int, error) {
+	columns := make(map[string]int, len(fields))
+	for i, f := range fields {
+		name := strings.ToLower(strings.TrimSpace(f))
+		switch name {
+		case "url", "alias", "ttl", "expires_at":
+		case "id", "short_code", "created_at", "protected":
+		default:
+			return nil, fmt.Errorf("unknown column %q; want url, alias, ttl, expires_at or an export column", f)
+		}
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("column %q appears twice", f)
+		}
+		columns[name] = i
+	}
+	return columns, nil
+}
+
+// jsonlRecords returns an iterator over the objects of a JSON Lines file,
+// skipping blank lines.
+func jsonlRecords(in io.Reader) func() (importRecord, error) {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 4096), maxImportLine)
+	line := 0
+
+	return func() (importRecord, error) {
+		for sc.Scan() {
+			line++
+			text := bytes.TrimSpace(sc.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+
+			rec := importRecord{line: line}
+			dec := json.NewDecoder(bytes.NewReader(text))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&rec); err != nil {
+				rec.err = fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+			}
+			rec.URL = strings.TrimSpace(rec.URL)
+			rec.resolve()
+			return rec, nil
+		}
+		if err := sc.Err(); err != nil {
+			return importRecord{}, fmt.Errorf("line %d: %w", line+1, err)
+		}
+		return importRecord{}, io.EOF
+	}
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/jsonutil"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder answers AddBatch like a store that already holds
+// https://example.com/existing and records the batches it was given.
+type batchRecorder struct {
+	batches [][]shortener.BatchItem
+}
+
+func (b *batchRecorder) addBatch(ctx context.Context, items []shortener.BatchItem) []shortener.BatchResult {
+	b.batches = append(b.batches, items)
+	results := make([]shortener.BatchResult, len(items))
+	for i, item := range items {
+		switch {
+		case item.URL == "https://example.com/existing":
+			results[i].AddResult = shortener.AddResult{ShortCode: "Exist12"}
+		case item.Opts.Alias == "taken":
+			results[i].Err = shortener.ErrAliasTaken
+		default:
+			code := item.Opts.Alias
+			if code == "" {
+				code = "Gen" + strings.Repeat("x", i)
+			}
+			results[i].AddResult = shortener.AddResult{ShortCode: code, Created: true, ExpiresAt: item.Opts.ExpiresAt}
+		}
+	}
+	return results
+}
+
+func TestImportAction(t *testing.T) {
+	testCases := []struct {
+		name            string
+		input           string
+		opts            ImportOptions
+		expectedRows    []ImportRowResponse
+		expectedSummary ImportSummary
+		expectedBatches []int
+		isError         bool
+	}{
+		{
+			name:  "csv with header",
+			input: "alias,url\nspring,https://example.com/spring\n,https://example.com/existing\n",
+			opts:  ImportOptions{Format: FormatCSV, BatchSize: 10},
+			expectedRows: []ImportRowResponse{
+				{Line: 2, RawURL: "https://example.com/spring", Status: ImportCreated, ShortCode: "spring"},
+				{Line: 3, RawURL: "https://example.com/existing", Status: ImportExisting, ShortCode: "Exist12"},
+			},
+			expectedSummary: ImportSummary{Total: 2, Created: 1, Existing: 1},
+			expectedBatches: []int{2},
+		},
+		{
+			name:  "csv without header is batched",
+			input: "https://example.com/1\nhttps://example.com/2,two\nhttps://example.com/3\n",
+			opts:  ImportOptions{Format: FormatCSV, BatchSize: 2},
+			expectedRows: []ImportRowResponse{
+				{Line: 1, RawURL: "https://example.com/1", Status: ImportCreated, ShortCode: "Gen"},
+				{Line: 2, RawURL: "https://example.com/2", Status: ImportCreated, ShortCode: "two"},
+				{Line: 3, RawURL: "https://example.com/3", Status: ImportCreated, ShortCode: "Gen"},
+			},
+			expectedSummary: ImportSummary{Total: 3, Created: 3},
+			expectedBatches: []int{2, 1},
+		},
+		{
+			name:  "jsonl with failures",
+			input: `{"url":"https://example.com/1","alias":"taken"}` + "\n\n" + `{"url":"https://example.com/2","ttl":"soon"}` + "\n" + `{"url":` + "\n",
+			opts:  ImportOptions{Format: FormatJSONL, BatchSize: 10},
+			expectedRows: []ImportRowResponse{
+				{Line: 1, RawURL: "https://example.com/1", Status: ImportFailed, Error: ErrAliasTaken.Error() + ": " + shortener.ErrAliasTaken.Error()},
+				{Line: 3, RawURL: "https://example.com/2", Status: ImportFailed, Error: ErrExpiry.Error() + `: ttl must be a duration such as 72h; got "soon"`},
+				{Line: 4, Status: ImportFailed, Error: ErrInvalidArgs.Error() + ": unexpected EOF"},
+			},
+			expectedSummary: ImportSummary{Total: 3, Failed: 3},
+			expectedBatches: []int{1},
+			isError:         true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &batchRecorder{}
+			action := NewActions(&mockedShortener{addBatchFunc: rec.addBatch}, 20)
+
+			var buf bytes.Buffer
+			err := action.ImportAction(context.Background(), &buf, strings.NewReader(tc.input), tc.opts)
+
+			if tc.isError {
+				assert.ErrorIs(t, err, ErrImport)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var actual ImportResponse
+			require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+			assert.Equal(t, tc.expectedRows, actual.Rows)
+			assert.Equal(t, tc.expectedSummary, actual.Summary)
+
+			sizes := make([]int, 0, len(rec.batches))
+			for _, b := range rec.batches {
+				sizes = append(sizes, len(b))
+			}
+			assert.Equal(t, tc.expectedBatches, sizes)
+		})
+	}
+}
+
+func TestImportAction_Errors(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		input                 string
+		opts                  ImportOptions
+		expectedErr           error
+		expectedErrorResponse ErrorResponse
+	}{
+		{
+			name:                  "bad batch size",
+			opts:                  ImportOptions{Format: FormatCSV},
+			expectedErr:           ErrBatchSize,
+			expectedErrorResponse: ErrorResponse{Error: ErrBatchSize.Error(), Code: ExitInvalid, Kind: "BATCH_SIZE_INVALID", Field: "batchSize", Details: "batch size must be greater than zero; got 0"},
+		},
+		{
+			name:                  "unknown format",
+			opts:                  ImportOptions{Format: "xml", BatchSize: 10},
+			expectedErr:           ErrImportFormat,
+			expectedErrorResponse: ErrorResponse{Error: ErrImportFormat.Error(), Code: ExitInvalid, Kind: "IMPORT_FORMAT_INVALID", Field: "format", Details: `format must be csv or jsonl; got "xml"`},
+		},
+		{
+			name:                  "unknown csv column",
+			input:                 "url,slug\nhttps://example.com,x\n",
+			opts:                  ImportOptions{Format: FormatCSV, BatchSize: 10},
+			expectedErr:           ErrImportRead,
+			expectedErrorResponse: ErrorResponse{Error: ErrImportRead.Error(), Code: ExitInvalid, Kind: "IMPORT_UNREADABLE", Field: "file", Details: `unknown column "slug"; want url, alias, ttl, expires_at or an export column`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			action := NewActions(&mockedShortener{}, 20)
+
+			var buf bytes.Buffer
+			err := action.ImportAction(context.Background(), &buf, strings.NewReader(tc.input), tc.opts)
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			var actual ErrorResponse
+			require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+			assert.Equal(t, tc.expectedErrorResponse, actual)
+		})
+	}
+}
+
+func TestImportAction_ExportRoundTrip(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	items := []shortener.URLItem{
+		{ID: 1, OriginalURL: "https://example.com/a", ShortCode: "GL9VeCa", CreatedAt: exportCreated},
+		{ID: 2, OriginalURL: "https://example.com/b?x=1,2", ShortCode: "GL9VeCb", CreatedAt: exportCreated, ExpiresAt: &expiresAt},
+		{ID: 3, OriginalURL: "https://intranet.example.com/handbook", ShortCode: "GL9VeCc", CreatedAt: exportCreated, Protected: true},
+	}
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			rec := &batchRecorder{}
+			action := NewActions(&mockedShortener{exportFunc: exportAll(items, nil), addBatchFunc: rec.addBatch}, 20)
+
+			var exported bytes.Buffer
+			require.NoError(t, action.ExportAction(context.Background(), &exported, ExportOptions{Format: format}))
+
+			var buf bytes.Buffer
+			err := action.ImportAction(context.Background(), &buf, &exported, ImportOptions{Format: format, BatchSize: 10})
+			assert.ErrorIs(t, err, ErrImport, "the protected link cannot be restored")
+
+			require.Len(t, rec.batches, 1)
+			batch := rec.batches[0]
+			require.Len(t, batch, 2)
+			for i, item := range batch {
+				assert.Equal(t, items[i].OriginalURL, item.URL)
+				assert.Equal(t, items[i].ShortCode, item.Opts.Alias, "an exported link keeps its code")
+			}
+			assert.Nil(t, batch[0].Opts.ExpiresAt)
+			require.NotNil(t, batch[1].Opts.ExpiresAt)
+			assert.True(t, expiresAt.Equal(*batch[1].Opts.ExpiresAt))
+
+			var actual ImportResponse
+			require.NoError(t, jsonutil.ReadJSON(&buf, &actual))
+			assert.Equal(t, ImportSummary{Total: 3, Created: 2, Failed: 1}, actual.Summary)
+			assert.Equal(t, ImportFailed, actual.Rows[2].Status)
+			assert.Contains(t, actual.Rows[2].Error, "password-protected link GL9VeCc")
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The methods below let internal/output render responses as tables, CSV and
+// plain text. JSON and YAML use the struct tags instead.
+
+func (r ResultResponse) Header() []string {
+	return []string{"code", "url", "expires"}
+}
+
+func (r ResultResponse) Rows() [][]string {
+	return [][]string{{r.ShortCode, r.shownURL(), formatTime(r.ExpiresAt)}}
+}
+
+// Plain is the bare short code, handy in scripts.
+func (r ResultResponse) Plain() string {
+	return r.ShortCode
+}
+
+func (r ResultResponse) Footnote() string {
+	if len(r.StrippedParams) == 0 {
+		return ""
+	}
+	return "Stripped query parameters: " + strings.Join(r.StrippedParams, ", ")
+}
+
+func (r ListResponse) Header() []string {
+	return []string{"code", "url", "created", "expires"}
+}
+
+func (r ListResponse) Rows() [][]string {
+	rows := make([][]string, 0, len(r.Items))
+	for _, item := range r.Items {
+		rows = append(rows, []string{item.ShortCode, item.shownURL(), formatTime(item.CreatedAt), formatTime(item.ExpiresAt)})
+	}
+	return rows
+}
+
+func (r ListResponse) Footnote() string {
+	if r.NextCursor == "" {
+		return ""
+	}
+	return "More links follow; resume with --cursor " + r.NextCursor
+}
+
+func (r DeleteResponse) Header() []string {
+	return []string{"code", "deleted"}
+}
+
+func (r DeleteResponse) Rows() [][]string {
+	return [][]string{{r.ShortCode, strconv.FormatBool(r.Deleted)}}
+}
+
+func (r ErrorResponse) Header() []string {
+	return []string{"error", "code", "kind", "field", "details"}
+}
+
+func (r ErrorResponse) Rows() [][]string {
+	return [][]string{{r.Error, strconv.Itoa(r.Code), r.Kind, r.Field, r.Details}}
+}
+
+func (r ErrorResponse) Plain() string {
+	if r.Details == "" {
+		return "error: " + r.Error
+	}
+	return "error: " + r.Error + ": " + r.Details
+}
+
+// shownURL is the destination as people should read it: with a Unicode host
+// when there is one.
+func (r ResultResponse) shownURL() string {
+	if r.Protected {
+		return "(password protected)"
+	}
+	if r.DisplayURL != "" {
+		return r.DisplayURL
+	}
+	return r.RawURL
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,363 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationsTable uses the same name and layout as golang-migrate so
+// databases migrated with the standalone binary keep working.
+const migrationsTable = "schema_migrations"
+
+const (
+	createMigrationsTable = `CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`
+	selectVersion         = `SELECT version, dirty FROM ` + migrationsTable + ` LIMIT 1`
+	selectLockNames       = `SELECT current_database(), current_schema()`
+	advisoryLock          = `SELECT pg_advisory_lock($1)`
+	advisoryUnlock        = `SELECT pg_advisory_unlock($1)`
+)
+
+// advisoryLockSalt is mixed into the advisory lock key the same way
+// golang-migrate does it.
+const advisoryLockSalt uint32 = 1486364155
+
+var (
+	ErrDirty            = errors.New("database is dirty; fix the failed migration and run migrate force")
+	ErrMigrationSteps   = errors.New("number of migrations to revert must be greater than zero")
+	ErrMigrationVersion = errors.New("unknown migration version")
+	ErrMigrationFile    = errors.New("invalid migration file")
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version uint
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationState is the schema version recorded in the database. Version 0
+// means no migration has been applied.
+type MigrationState struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+}
+
+type MigrationStatus struct {
+	MigrationState
+	Latest     uint              `json:"latest"`
+	Migrations []MigrationRecord `json:"migrations"`
+}
+
+type MigrationRecord struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// MigrationResult lists the versions a call to Up or Down ran, in the order
+// they ran, and the version the database ended on.
+type MigrationResult struct {
+	Applied []uint `json:"applied"`
+	Version uint   `json:"version"`
+}
+
+type Migrator interface {
+	Up(ctx context.Context) (MigrationResult, error)
+	Down(ctx context.Context, steps int) (MigrationResult, error)
+	Status(ctx context.Context) (MigrationStatus, error)
+	Force(ctx context.Context, version uint) error
+}
+
+var _ Migrator = (*migrator)(nil)
+
+type migrator struct {
+	db         dbiface.Querier
+	migrations []migration
+}
+
+// NewMigrator returns a Migrator that applies the SQL files embedded from
+// internal/db/migrations through q.
+func NewMigrator(q dbiface.Querier) (Migrator, error) {
+	return newMigrator(q, migrationFiles)
+}
+
+func newMigrator(q dbiface.Querier, fsys fs.FS) (*migrator, error) {
+	if q == nil {
+		return nil, errors.New("db: nil querier")
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: q, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	paths, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migration)
+	for _, p := range paths {
+		base := p[len("migrations/"):]
+		m := migrationName.FindStringSubmatch(base)
+		if m == nil {
+			return nil, fmt.Errorf("%w: %s", ErrMigrationFile, base)
+		}
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrMigrationFile, base)
+		}
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(v)]
+		if !ok {
+			mig = &migration{version: uint(v), name: m[2]}
+			byVersion[uint(v)] = mig
+		} else if mig.name != m[2] {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrMigrationFile, v)
+		}
+		if m[3] == "up" {
+			mig.up = string(body)
+		} else {
+			mig.down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up migration", ErrMigrationFile, mig.version)
+		}
+		migrations = append(migrations, *mig)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int { return cmp.Compare(a.version, b.version) })
+	return migrations, nil
+}
+
+// Up applies every migration newer than the current version.
+func (m *migrator) Up(ctx context.Context) (res MigrationResult, err error) {
+	err = m.locked(ctx, func(m *migrator) error {
+		res, err = m.up(ctx)
+		return err
+	})
+	return res, err
+}
+
+// Down reverts up to steps migrations, newest first.
+func (m *migrator) Down(ctx context.Context, steps int) (res MigrationResult, err error) {
+	if steps <= 0 {
+		return MigrationResult{}, fmt.Errorf("%w: got %d", ErrMigrationSteps, steps)
+	}
+
+	err = m.locked(ctx, func(m *migrator) error {
+		res, err = m.down(ctx, steps)
+		return err
+	})
+	return res, err
+}
+
+func (m *migrator) up(ctx context.Context) (MigrationResult, error) {
+	state, err := m.cleanState(ctx)
+	if err != nil {
+		return MigrationResult{}, err
+	}
+
+	res := MigrationResult{Applied: []uint{}, Version: state.Version}
+	for _, mig := range m.migrations {
+		if mig.version <= state.Version {
+			continue
+		}
+		if err := m.run(ctx, mig.version, mig.up, mig.version); err != nil {
+			return res, err
+		}
+		res.Applied = append(res.Applied, mig.version)
+		res.Version = mig.version
+	}
+	return res, nil
+}
+
+func (m *migrator) down(ctx context.Context, steps int) (MigrationResult, error) {
+	state, err := m.cleanState(ctx)
+	if err != nil {
+		return MigrationResult{}, err
+	}
+
+	res := MigrationResult{Applied: []uint{}, Version: state.Version}
+	i := m.index(state.Version)
+	if state.Version > 0 && i < 0 {
+		return res, fmt.Errorf("%w: database is at %d", ErrMigrationVersion, state.Version)
+	}
+	for ; i >= 0 && len(res.Applied) < steps; i-- {
+		mig := m.migrations[i]
+		var prev uint
+		if i > 0 {
+			prev = m.migrations[i-1].version
+		}
+		if err := m.run(ctx, mig.version, mig.down, prev); err != nil {
+			return res, err
+		}
+		res.Applied = append(res.Applied, mig.version)
+		res.Version = prev
+	}
+	return res, nil
+}
+
+func (m *migrator) Status(ctx context.Context) (MigrationStatus, error) {
+	state, err := m.state(ctx)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	status := MigrationStatus{MigrationState: state, Migrations: make([]MigrationRecord, 0, len(m.migrations))}
+	for _, mig := range m.migrations {
+		status.Latest = mig.version
+		status.Migrations = append(status.Migrations, MigrationRecord{
+			Version: mig.version,
+			Name:    mig.name,
+			Applied: mig.version <= state.Version,
+		})
+	}
+	return status, nil
+}
+
+// Force records version as applied and clears the dirty flag without running
+// any SQL. It is the way out after a migration failed half way and the schema
+// was repaired by hand. Version 0 marks the database as unmigrated.
+func (m *migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrMigrationVersion, version)
+	}
+	return m.locked(ctx, func(m *migrator) error {
+		if _, err := m.db.Exec(ctx, createMigrationsTable); err != nil {
+			return fmt.Errorf("db: create %s: %w", migrationsTable, err)
+		}
+		return m.setVersion(ctx, version, false)
+	})
+}
+
+// locked runs fn while holding the session advisory lock golang-migrate
+// takes, so two processes migrating the same database, whether this one or
+// the standalone binary, wait for each other instead of running a migration
+// twice. A session lock belongs to one connection, so when m.db is a pool fn
+// gets a migrator bound to a connection taken from it.
+func (m *migrator) locked(ctx context.Context, fn func(m *migrator) error) (err error) {
+	conn := m.db
+	if a, ok := m.db.(dbiface.Acquirer); ok {
+		conn, err = a.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("db: acquire connection: %w", err)
+		}
+		defer conn.Close()
+	}
+
+	var database, schema string
+	if err := conn.QueryRow(ctx, selectLockNames).Scan(&database, &schema); err != nil {
+		return fmt.Errorf("db: read lock key: %w", err)
+	}
+	key := advisoryLockKey(database, schema, migrationsTable)
+	if _, err := conn.Exec(ctx, advisoryLock, key); err != nil {
+		return fmt.Errorf("db: lock migrations: %w", err)
+	}
+	defer func() {
+		// Unlock even when ctx is done; the connection outlives this call
+		// when it goes back to a pool.
+		if _, uerr := conn.Exec(context.WithoutCancel(ctx), advisoryUnlock, key); uerr != nil {
+			err = errors.Join(err, fmt.Errorf("db: unlock migrations: %w", uerr))
+		}
+	}()
+
+	return fn(&migrator{db: conn, migrations: m.migrations})
+}
+
+// advisoryLockKey derives the lock key from the database, schema and table
+// names as golang-migrate's postgres driver does.
+func advisoryLockKey(database, schema, table string) int64 {
+	sum := crc32.ChecksumIEEE([]byte(strings.Join([]string{schema, table, database}, "\x00")))
+	return int64(sum * advisoryLockSalt)
+}
+
+// run executes body and moves the recorded version to target. The database
+// is flagged dirty at version while body runs, so a failure part way through
+// is visible to the next caller instead of being retried blindly.
+func (m *migrator) run(ctx context.Context, version uint, body string, target uint) error {
+	if err := m.setVersion(ctx, version, true); err != nil {
+		return err
+	}
+	// Exec without arguments goes over the simple protocol, which allows the
+	// multi-statement files and runs them in a single implicit transaction.
+	if body != "" {
+		if _, err := m.db.Exec(ctx, body); err != nil {
+			return fmt.Errorf("db: migration %d: %w", version, err)
+		}
+	}
+	return m.setVersion(ctx, target, false)
+}
+
+func (m *migrator) state(ctx context.Context) (MigrationState, error) {
+	if _, err := m.db.Exec(ctx, createMigrationsTable); err != nil {
+		return MigrationState{}, fmt.Errorf("db: create %s: %w", migrationsTable, err)
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	if err := m.db.QueryRow(ctx, selectVersion).Scan(&version, &dirty); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return MigrationState{}, nil
+		}
+		return MigrationState{}, fmt.Errorf("db: read %s: %w", migrationsTable, err)
+	}
+	return MigrationState{Version: uint(version), Dirty: dirty}, nil
+}
+
+func (m *migrator) cleanState(ctx context.Context) (MigrationState, error) {
+	state, err := m.state(ctx)
+	if err != nil {
+		return state, err
+	}
+	if state.Dirty {
+		return state, fmt.Errorf("%w: version %d", ErrDirty, state.Version)
+	}
+	return state, nil
+}
+
+func (m *migrator) setVersion(ctx context.Context, version uint, dirty bool) error {
+	// The table holds a single row. Both statements go in one simple-protocol
+	// Exec so they commit together; the values are integers and booleans
+	// formatted by us, never user input.
+	sql := `TRUNCATE ` + migrationsTable + `;`
+	if version > 0 {
+		sql += fmt.Sprintf(` INSERT INTO %s (version, dirty) VALUES (%d, %t);`, migrationsTable, version, dirty)
+	}
+	if _, err := m.db.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("db: set version %d: %w", version, err)
+	}
+	return nil
+}
+
+func (m *migrator) index(version uint) int {
+	return slices.IndexFunc(m.migrations, func(mig migration) bool { return mig.version == version })
+}
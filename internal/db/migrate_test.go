@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var insertVersion = regexp.MustCompile(`VALUES \((\d+), (true|false)\)`)
+
+// fakeDB keeps the schema_migrations row in memory and records every
+// migration body it is asked to run, and every advisory lock taken and
+// released. Writes fail unless the lock is held.
+type fakeDB struct {
+	version uint
+	dirty   bool
+	present bool
+	ran     []string
+	failOn  string
+	held    bool
+	locks   []string
+}
+
+func (f *fakeDB) Exec(_ context.Context, sql string, args ...any) (dbiface.CommandResult, error) {
+	switch {
+	case sql == createMigrationsTable:
+	case sql == advisoryLock:
+		f.held = true
+		f.locks = append(f.locks, fmt.Sprint("lock ", args[0]))
+	case sql == advisoryUnlock:
+		f.held = false
+		f.locks = append(f.locks, fmt.Sprint("unlock ", args[0]))
+	case !f.held:
+		return nil, errors.New("write without the migration lock")
+	case strings.HasPrefix(sql, "TRUNCATE "+migrationsTable):
+		f.version, f.dirty, f.present = 0, false, false
+		if m := insertVersion.FindStringSubmatch(sql); m != nil {
+			v, _ := strconv.ParseUint(m[1], 10, 64)
+			f.version, f.dirty, f.present = uint(v), m[2] == "true", true
+		}
+	default:
+		if f.failOn != "" && strings.Contains(sql, f.failOn) {
+			return nil, errors.New("syntax error")
+		}
+		f.ran = append(f.ran, strings.TrimSpace(sql))
+	}
+	return nil, nil
+}
+
+func (f *fakeDB) QueryRow(_ context.Context, sql string, _ ...any) dbiface.Row {
+	if sql == selectLockNames {
+		return fakeNamesRow{}
+	}
+	return fakeRow{f}
+}
+
+func (f *fakeDB) Query(context.Context, string, ...any) (dbiface.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDB) Close() {}
+
+type fakeRow struct{ f *fakeDB }
+
+func (r fakeRow) Scan(dest ...any) error {
+	if !r.f.present {
+		return fmt.Errorf("%w", pgx.ErrNoRows)
+	}
+	*dest[0].(*int64) = int64(r.f.version)
+	*dest[1].(*bool) = r.f.dirty
+	return nil
+}
+
+type fakeNamesRow struct{}
+
+func (fakeNamesRow) Scan(dest ...any) error {
+	*dest[0].(*string) = "urlshortener"
+	*dest[1].(*string) = "public"
+	return nil
+}
+
+// fakePool hands out its fakeDB as a single connection, counting how often
+// one is taken and given back.
+type fakePool struct {
+	*fakeDB
+	acquired, released int
+}
+
+func (p *fakePool) Acquire(context.Context) (dbiface.Querier, error) {
+	p.acquired++
+	return fakeConn{p}, nil
+}
+
+type fakeConn struct{ p *fakePool }
+
+func (c fakeConn) QueryRow(ctx context.Context, sql string, args ...any) dbiface.Row {
+	return c.p.fakeDB.QueryRow(ctx, sql, args...)
+}
+
+func (c fakeConn) Exec(ctx context.Context, sql string, args ...any) (dbiface.CommandResult, error) {
+	return c.p.fakeDB.Exec(ctx, sql, args...)
+}
+
+func (c fakeConn) Query(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+	return c.p.fakeDB.Query(ctx, sql, args...)
+}
+
+func (c fakeConn) Close() { c.p.released++ }
+
+var testMigrations = fstest.MapFS{
+	"migrations/000001_create_a.up.sql":   {Data: []byte("CREATE a")},
+	"migrations/000001_create_a.down.sql": {Data: []byte("DROP a")},
+	"migrations/000002_create_b.up.sql":   {Data: []byte("CREATE b")},
+	"migrations/000002_create_b.down.sql": {Data: []byte("DROP b")},
+	"migrations/000010_create_c.up.sql":   {Data: []byte("CREATE c")},
+	"migrations/000010_create_c.down.sql": {Data: []byte("DROP c")},
+}
+
+func TestNewMigrator_LoadsEmbeddedMigrations(t *testing.T) {
+	m, err := NewMigrator(&fakeDB{})
+	require.NoError(t, err)
+
+	status, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, status.Migrations)
+	assert.Equal(t, "create_url_shortener_table", status.Migrations[0].Name)
+	assert.Equal(t, uint(1), status.Migrations[0].Version)
+}
+
+func TestLoadMigrations_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "bad file name",
+			fsys: fstest.MapFS{"migrations/create_a.up.sql": {Data: []byte("x")}},
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"migrations/000001_a.down.sql": {Data: []byte("x")}},
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"migrations/000001_a.up.sql": {Data: []byte("x")},
+				"migrations/000001_b.up.sql": {Data: []byte("y")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadMigrations(tc.fsys)
+			assert.ErrorIs(t, err, ErrMigrationFile)
+		})
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	ctx := context.Background()
+	fdb := &fakeDB{}
+	m, err := newMigrator(fdb, testMigrations)
+	require.NoError(t, err)
+
+	res, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationResult{Applied: []uint{1, 2, 10}, Version: 10}, res)
+	assert.Equal(t, []string{"CREATE a", "CREATE b", "CREATE c"}, fdb.ran)
+
+	res, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationResult{Applied: []uint{}, Version: 10}, res, "nothing left to apply")
+
+	res, err = m.Down(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationResult{Applied: []uint{10, 2}, Version: 1}, res)
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationStatus{
+		MigrationState: MigrationState{Version: 1},
+		Latest:         10,
+		Migrations: []MigrationRecord{
+			{Version: 1, Name: "create_a", Applied: true},
+			{Version: 2, Name: "create_b", Applied: false},
+			{Version: 10, Name: "create_c", Applied: false},
+		},
+	}, status)
+
+	res, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, MigrationResult{Applied: []uint{1}, Version: 0}, res)
+	assert.False(t, fdb.present)
+
+	_, err = m.Down(ctx, 0)
+	assert.ErrorIs(t, err, ErrMigrationSteps)
+}
+
+func TestMigrator_FailureLeavesDirty(t *testing.T) {
+	ctx := context.Background()
+	fdb := &fakeDB{failOn: "CREATE b"}
+	m, err := newMigrator(fdb, testMigrations)
+	require.NoError(t, err)
+
+	res, err := m.Up(ctx)
+	require.Error(t, err)
+	assert.Equal(t, []uint{1}, res.Applied)
+	assert.Equal(t, uint(2), fdb.version)
+	assert.True(t, fdb.dirty)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrDirty)
+	_, err = m.Down(ctx, 1)
+	assert.ErrorIs(t, err, ErrDirty)
+
+	require.NoError(t, m.Force(ctx, 1))
+	assert.Equal(t, uint(1), fdb.version)
+	assert.False(t, fdb.dirty)
+
+	assert.ErrorIs(t, m.Force(ctx, 7), ErrMigrationVersion)
+
+	require.NoError(t, m.Force(ctx, 0))
+	assert.False(t, fdb.present)
+}
+
+func TestMigrator_HoldsAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	pool := &fakePool{fakeDB: &fakeDB{failOn: "CREATE c"}}
+	m, err := newMigrator(pool, testMigrations)
+	require.NoError(t, err)
+
+	key := advisoryLockKey("urlshortener", "public", migrationsTable)
+	lockedOnce := []string{fmt.Sprint("lock ", key), fmt.Sprint("unlock ", key)}
+
+	_, err = m.Up(ctx)
+	require.Error(t, err)
+	assert.Equal(t, []string{"CREATE a", "CREATE b"}, pool.ran)
+	assert.Equal(t, lockedOnce, pool.locks, "a failed migration still releases the lock")
+	assert.Equal(t, 1, pool.acquired)
+	assert.Equal(t, 1, pool.released)
+
+	pool.locks = nil
+	require.NoError(t, m.Force(ctx, 2))
+	assert.Equal(t, lockedOnce, pool.locks)
+
+	pool.locks = nil
+	_, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, lockedOnce, pool.locks)
+	assert.Equal(t, 3, pool.acquired)
+	assert.Equal(t, 3, pool.released)
+
+	pool.locks = nil
+	_, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pool.locks, "reading the status does not wait for a running migration")
+}
+
+func TestAdvisoryLockKey(t *testing.T) {
+	key := advisoryLockKey("urlshortener", "public", migrationsTable)
+	assert.Equal(t, key, advisoryLockKey("urlshortener", "public", migrationsTable))
+	assert.NotEqual(t, key, advisoryLockKey("other", "public", migrationsTable))
+	assert.Positive(t, key, "the key fits in 32 bits, as golang-migrate's does")
+	assert.LessOrEqual(t, key, int64(math.MaxUint32))
+}
@@ -0,0 +1,28 @@
+DROP FUNCTION IF EXISTS add_url(text, text, timestamptz);
+
+CREATE OR REPLACE FUNCTION add_url(
+  p_original_url text,
+  p_short_code   text
+) RETURNS text
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  v_short_code text;
+BEGIN
+  INSERT INTO url (original_url, short_code)
+  VALUES (p_original_url, p_short_code)
+  ON CONFLICT (original_url) DO NOTHING
+  RETURNING short_code INTO v_short_code;
+
+  IF v_short_code IS NOT NULL THEN
+    RETURN v_short_code;
+  END IF;
+
+  SELECT short_code
+    INTO v_short_code
+    FROM url
+   WHERE original_url = p_original_url;
+
+  RETURN v_short_code;
+END;
+$$;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS url_click (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES url (id) ON DELETE CASCADE,
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_url_click_url_id_clicked_at ON url_click (url_id, clicked_at);
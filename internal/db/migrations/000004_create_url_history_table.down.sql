@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_url_history ON url;
+DROP FUNCTION IF EXISTS record_url_history();
+DROP TABLE IF EXISTS url_history;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS url_history (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES url (id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    original_url TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (url_id, revision)
+);
+
+-- Every existing link starts with its current destination as revision 1.
+INSERT INTO url_history (url_id, revision, original_url, changed_at)
+SELECT id, 1, original_url, created_at
+  FROM url
+ON CONFLICT (url_id, revision) DO NOTHING;
+
+-- Records a new revision whenever a link is created or its destination
+-- changes, so every write path is audited without the caller's help.
+CREATE OR REPLACE FUNCTION record_url_history() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND OLD.original_url = NEW.original_url THEN
+    RETURN NEW;
+  END IF;
+
+  INSERT INTO url_history (url_id, revision, original_url)
+  SELECT NEW.id, COALESCE(max(revision), 0) + 1, NEW.original_url
+    FROM url_history
+   WHERE url_id = NEW.id;
+
+  RETURN NEW;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_url_history ON url;
+CREATE TRIGGER trg_url_history
+AFTER INSERT OR UPDATE OF original_url ON url
+FOR EACH ROW EXECUTE FUNCTION record_url_history();
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_url_created_at_id;
@@ -0,0 +1,3 @@
+-- Serves list pages, which are ordered newest first and resume from a
+-- (created_at, id) cursor.
+CREATE INDEX IF NOT EXISTS idx_url_created_at_id ON url (created_at DESC, id DESC);
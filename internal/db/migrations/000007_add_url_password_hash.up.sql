@@ -0,0 +1,40 @@
+-- password_hash is the bcrypt hash a password-protected link is opened with.
+-- It is NULL for links anyone may follow.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+DROP FUNCTION IF EXISTS add_url(text, text, timestamptz, text);
+
+-- Function to add a new URL with an optional expiry. When the URL already
+-- exists the existing short code and expiry are returned and o_created is false.
+CREATE OR REPLACE FUNCTION add_url(
+  p_original_url  text,
+  p_short_code    text,
+  p_expires_at    timestamptz,
+  p_raw_url       text,
+  p_password_hash text,
+  OUT o_short_code text,
+  OUT o_expires_at timestamptz,
+  OUT o_created    boolean
+)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+  INSERT INTO url (original_url, short_code, expires_at, raw_url, password_hash)
+  VALUES (p_original_url, p_short_code, p_expires_at, NULLIF(p_raw_url, ''), NULLIF(p_password_hash, ''))
+  ON CONFLICT (original_url) DO NOTHING
+  RETURNING short_code, expires_at INTO o_short_code, o_expires_at;
+
+  IF o_short_code IS NOT NULL THEN
+    o_created := true; -- inserted successfully
+    RETURN;
+  END IF;
+
+  -- Row already existed; return the existing short_code and expiry
+  SELECT short_code, expires_at
+    INTO o_short_code, o_expires_at
+    FROM url
+   WHERE original_url = p_original_url;
+
+  o_created := false;
+END;
+$$;
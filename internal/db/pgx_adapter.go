@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/anewball/urlshortener/config"
 	"github.com/anewball/urlshortener/internal/dbiface"
@@ -12,6 +14,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// NewQuerier connects to the database at cfg.URL. A database that cannot be
+// reached is reported as shortener.ErrUnavailable, so the command exits with
+// the same status as when storage fails later on.
 func NewQuerier(ctx context.Context, cfg config.Config) (dbiface.Querier, error) {
 	if cfg.URL == "" {
 		return nil, fmt.Errorf("db: empty connection URL")
@@ -40,13 +45,13 @@ func NewQuerier(ctx context.Context, cfg config.Config) (dbiface.Querier, error)
 	// Create pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return nil, fmt.Errorf("db: failed to create pool: %w", err)
+		return nil, fmt.Errorf("db: failed to create pool: %w: %w", shortener.ErrUnavailable, err)
 	}
 
 	// Verify connection with a ping
 	if err := pool.Ping(ctx); err != nil {
 		pool.Close()
-		return nil, fmt.Errorf("db: ping failed: %w", err)
+		return nil, fmt.Errorf("db: ping failed: %w: %w", shortener.ErrUnavailable, err)
 	}
 	return &poolAdapter{pool}, nil
 }
@@ -55,6 +60,8 @@ type rowsAdapter struct{ pgx.Rows }
 
 func (r *rowsAdapter) Close() { r.Rows.Close() }
 
+func (r *rowsAdapter) Err() error { return mapPgError(r.Rows.Err()) }
+
 type rowAdapter struct{ pgx.Row }
 
 func (r rowAdapter) Scan(dest ...any) error {
@@ -62,11 +69,32 @@ func (r rowAdapter) Scan(dest ...any) error {
 		if err == pgx.ErrNoRows {
 			return fmt.Errorf("%w: %w", shortener.ErrNotFound, err)
 		}
-		return err
+		return mapPgError(err)
 	}
 	return nil
 }
 
+const uniqueViolation = "23505"
+
+// mapPgError translates Postgres errors the service needs to tell apart into
+// shortener sentinels, keeping the original error in the chain.
+func mapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch {
+	case strings.Contains(pgErr.ConstraintName, "short_code"):
+		return fmt.Errorf("%w: %w", shortener.ErrDuplicateShortCode, err)
+	case strings.Contains(pgErr.ConstraintName, "original_url"):
+		return fmt.Errorf("%w: %w", shortener.ErrURLExists, err)
+	}
+	return err
+}
+
 type poolAdapter struct{ *pgxpool.Pool }
 
 type commandTagAdapter struct{ tag pgconn.CommandTag }
@@ -90,11 +118,94 @@ func (p *poolAdapter) Exec(ctx context.Context, sql string, args ...any) (dbifac
 func (p *poolAdapter) Query(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
 	rows, err := p.Pool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, err
+		return nil, mapPgError(err)
 	}
 	return &rowsAdapter{rows}, nil
 }
 
+// exportCursor is the cursor QueryCursor declares. Each call runs in its own
+// transaction, so the fixed name never clashes.
+const exportCursor = "export_cursor"
+
+func (p *poolAdapter) QueryCursor(ctx context.Context, fetchSize int, fn func(row dbiface.Row) error, sql string, args ...any) error {
+	if fetchSize <= 0 {
+		return fmt.Errorf("db: fetch size must be greater than zero; got %d", fetchSize)
+	}
+
+	// Cursors only live inside a transaction. It is read only, so rolling it
+	// back when we are done is as good as committing.
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return mapPgError(err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	if _, err := tx.Exec(ctx, "DECLARE "+exportCursor+" NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		return mapPgError(err)
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", fetchSize, exportCursor)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return mapPgError(err)
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+			if err := fn(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return mapPgError(err)
+		}
+
+		if n < fetchSize {
+			return nil
+		}
+	}
+}
+
+func (p *poolAdapter) Acquire(ctx context.Context) (dbiface.Querier, error) {
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &connAdapter{conn}, nil
+}
+
 func (p *poolAdapter) Close() {
 	p.Pool.Close()
 }
+
+// connAdapter is a single connection taken from the pool. Close gives it
+// back rather than closing it.
+type connAdapter struct{ *pgxpool.Conn }
+
+func (c *connAdapter) QueryRow(ctx context.Context, sql string, args ...any) dbiface.Row {
+	return &rowAdapter{c.Conn.QueryRow(ctx, sql, args...)}
+}
+
+func (c *connAdapter) Exec(ctx context.Context, sql string, args ...any) (dbiface.CommandResult, error) {
+	tag, err := c.Conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return commandTagAdapter{tag: tag}, nil
+}
+
+func (c *connAdapter) Query(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+	rows, err := c.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, mapPgError(err)
+	}
+	return &rowsAdapter{rows}, nil
+}
+
+func (c *connAdapter) Close() {
+	c.Conn.Release()
+}
@@ -11,6 +11,21 @@ type Querier interface {
 	Close()
 }
 
+// CursorQuerier is implemented by Queriers that can stream a query through a
+// server-side cursor, holding at most fetchSize rows in memory at a time. fn
+// is called once per row and must not retain row.
+type CursorQuerier interface {
+	QueryCursor(ctx context.Context, fetchSize int, fn func(row Row) error, sql string, args ...any) error
+}
+
+// Acquirer is implemented by Queriers backed by a connection pool. Acquire
+// takes one connection out of the pool for work that must stay on a single
+// session, such as holding an advisory lock; closing the returned Querier
+// gives the connection back.
+type Acquirer interface {
+	Acquire(ctx context.Context) (Querier, error)
+}
+
 type Rows interface {
 	Next() bool
 	Scan(dest ...any) error
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package memstore
+
+// lockFile is a no-op where flock is unavailable, so there a file-backed
+// store must not be written by more than one process at a time.
+func lockFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
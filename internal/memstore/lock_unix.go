@@ -0,0 +1,31 @@
+//go:build unix
+
+package memstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until it holds an exclusive advisory lock on path,
+// creating the file if needed. The lock is released by calling unlock, or by
+// the kernel if the process dies first.
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("memstore: %w", err)
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if !errors.Is(err, syscall.EINTR) {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("memstore: lock %s: %w", path, err)
+	}
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,617 @@
+// Package memstore implements shortener.Store without a database server.
+// New keeps links in memory only, which suits tests and demos; Open persists
+// them to a JSON file for single-user use on a laptop. Every write rewrites
+// the whole file, so it is no substitute for Postgres under real traffic.
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+var _ shortener.Store = (*Store)(nil)
+
+// Store is safe for concurrent use by multiple goroutines. A file-backed
+// Store re-reads the file when another process has changed it, so a running
+// server sees links added from the CLI. Writers hold an exclusive lock on a
+// ".lock" file beside it from that re-read until their change is saved, so
+// processes sharing the file never overwrite each other's changes. On
+// systems without flock the file must only be used by one process.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	file   fs.FileInfo
+	state  state
+	byCode map[string]*link
+	byURL  map[string]*link
+	now    func() time.Time
+}
+
+type state struct {
+	NextID uint64  `json:"nextId"`
+	Links  []*link `json:"links"`
+}
+
+type link struct {
+	ID           uint64     `json:"id"`
+	OriginalURL  string     `json:"originalUrl"`
+	RawURL       string     `json:"rawUrl,omitempty"`
+	ShortCode    string     `json:"shortCode"`
+	PasswordHash string     `json:"passwordHash,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	Clicks       []clickDay `json:"dailyClicks,omitempty"`
+	FirstClick   *time.Time `json:"firstClick,omitempty"`
+	LastClick    *time.Time `json:"lastClick,omitempty"`
+	History      []revision `json:"history,omitempty"`
+	// LegacyClicks holds the per-click timestamps older files kept. reload
+	// folds them into Clicks.
+	LegacyClicks []time.Time `json:"clicks,omitempty"`
+}
+
+// clickDay counts the clicks on one day. Day is midnight UTC.
+type clickDay struct {
+	Day    time.Time `json:"day"`
+	Clicks int64     `json:"clicks"`
+}
+
+type revision struct {
+	Number      int       `json:"revision"`
+	OriginalURL string    `json:"originalUrl"`
+	ChangedAt   time.Time `json:"changedAt"`
+}
+
+func New() *Store {
+	s := &Store{now: func() time.Time { return time.Now().UTC() }}
+	s.index()
+	return s
+}
+
+// Open returns a Store persisted at path, creating the file on first write.
+func Open(path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("memstore: empty file path")
+	}
+	s := New()
+	s.path = path
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Add(ctx context.Context, nl shortener.NewLink) (shortener.AddResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return shortener.AddResult{}, err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return shortener.AddResult{}, err
+	}
+
+	if l, ok := s.liveURL(nl.OriginalURL); ok {
+		return shortener.AddResult{ShortCode: l.ShortCode, ExpiresAt: l.ExpiresAt, Created: false}, nil
+	}
+	if _, ok := s.live(nl.ShortCode); ok {
+		return shortener.AddResult{}, fmt.Errorf("%w: %s", shortener.ErrDuplicateShortCode, nl.ShortCode)
+	}
+
+	l := s.insert(nl)
+
+	if err := s.save(); err != nil {
+		return shortener.AddResult{}, err
+	}
+
+	return shortener.AddResult{ShortCode: l.ShortCode, ExpiresAt: l.ExpiresAt, Created: true}, nil
+}
+
+func (s *Store) AddBatch(ctx context.Context, links []shortener.NewLink) ([]shortener.AddResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	// Check every code before touching the state so a taken code leaves the
+	// store exactly as it was.
+	codes := make(map[string]struct{}, len(links))
+	urls := make(map[string]struct{}, len(links))
+	for _, nl := range links {
+		if _, ok := s.liveURL(nl.OriginalURL); ok {
+			continue
+		}
+		if _, ok := urls[nl.OriginalURL]; ok {
+			continue
+		}
+		if _, ok := s.live(nl.ShortCode); ok {
+			return nil, fmt.Errorf("%w: %s", shortener.ErrDuplicateShortCode, nl.ShortCode)
+		}
+		if _, ok := codes[nl.ShortCode]; ok {
+			return nil, fmt.Errorf("%w: %s", shortener.ErrDuplicateShortCode, nl.ShortCode)
+		}
+		codes[nl.ShortCode] = struct{}{}
+		urls[nl.OriginalURL] = struct{}{}
+	}
+
+	results := make([]shortener.AddResult, 0, len(links))
+	for _, nl := range links {
+		if l, ok := s.liveURL(nl.OriginalURL); ok {
+			results = append(results, shortener.AddResult{ShortCode: l.ShortCode, ExpiresAt: l.ExpiresAt, Created: false})
+			continue
+		}
+		l := s.insert(nl)
+		results = append(results, shortener.AddResult{ShortCode: l.ShortCode, ExpiresAt: l.ExpiresAt, Created: true})
+	}
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *Store) Get(ctx context.Context, shortCode string) (shortener.Destination, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return shortener.Destination{}, err
+	}
+
+	l, ok := s.live(shortCode)
+	if !ok {
+		return shortener.Destination{}, fmt.Errorf("%w: %v", shortener.ErrNotFound, shortCode)
+	}
+	return shortener.Destination{URL: l.OriginalURL, PasswordHash: l.PasswordHash}, nil
+}
+
+func (s *Store) List(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	// A URL-sorted cursor carries only the link's ID, see shortener.Cursor.
+	if c := opts.After; c != nil && c.Sort == shortener.SortURL {
+		i := slices.IndexFunc(s.state.Links, func(l *link) bool { return l.ID == c.ID })
+		if i < 0 {
+			return []shortener.URLItem{}, nil
+		}
+		after := *c
+		after.Value = s.state.Links[i].OriginalURL
+		opts.After = &after
+	}
+
+	now := s.now()
+	matched := make([]shortener.URLItem, 0, len(s.state.Links))
+	for _, l := range s.state.Links {
+		if item := toItem(l); opts.Includes(item, now) {
+			matched = append(matched, item)
+		}
+	}
+	slices.SortFunc(matched, opts.Compare)
+
+	// Includes already dropped everything up to the cursor, so a cursor page
+	// starts at the first match.
+	start := opts.Offset
+	if opts.After != nil {
+		start = 0
+	}
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := min(start+opts.Limit, len(matched))
+	return slices.Clip(matched[start:end]), nil
+}
+
+func (s *Store) Export(ctx context.Context, fn func(shortener.URLItem) error) error {
+	s.mu.Lock()
+	if err := s.reload(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	items := make([]shortener.URLItem, 0, len(s.state.Links))
+	for _, l := range s.state.Links {
+		items = append(items, toItem(l))
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(items, func(a, b shortener.URLItem) int { return cmp.Compare(a.ID, b.ID) })
+
+	// fn runs without the lock so a slow writer never blocks the store.
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Update(ctx context.Context, shortCode, originalURL, rawURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	l, ok := s.live(shortCode)
+	if !ok {
+		return fmt.Errorf("%w: %v", shortener.ErrNotFound, shortCode)
+	}
+	if other, ok := s.byURL[originalURL]; ok && other != l {
+		return fmt.Errorf("%w: %s", shortener.ErrURLExists, other.ShortCode)
+	}
+
+	if l.OriginalURL == originalURL {
+		if l.RawURL == rawURL {
+			return nil
+		}
+		l.RawURL = rawURL
+		return s.save()
+	}
+
+	history := l.revisions()
+	l.History = append(history, revision{
+		Number:      history[len(history)-1].Number + 1,
+		OriginalURL: originalURL,
+		ChangedAt:   s.now(),
+	})
+	delete(s.byURL, l.OriginalURL)
+	l.OriginalURL, l.RawURL = originalURL, rawURL
+	s.byURL[l.OriginalURL] = l
+
+	return s.save()
+}
+
+func (s *Store) Delete(ctx context.Context, shortCode string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return false, err
+	}
+
+	l, ok := s.byCode[shortCode]
+	if !ok {
+		return false, fmt.Errorf("%w", shortener.ErrNotFound)
+	}
+	s.remove(l)
+
+	if err := s.save(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Store) History(ctx context.Context, shortCode string) ([]shortener.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	l, ok := s.byCode[shortCode]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", shortener.ErrNotFound, shortCode)
+	}
+
+	history := l.revisions()
+	revisions := make([]shortener.Revision, 0, len(history))
+	for _, r := range history {
+		revisions = append(revisions, shortener.Revision{Number: r.Number, OriginalURL: r.OriginalURL, ChangedAt: r.ChangedAt, Protected: l.PasswordHash != ""})
+	}
+	return revisions, nil
+}
+
+func (s *Store) PurgeExpired(ctx context.Context, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	var purged int64
+	for _, l := range slices.Clone(s.state.Links) {
+		if purged >= int64(limit) {
+			break
+		}
+		if l.ExpiresAt != nil && !l.ExpiresAt.After(now) {
+			s.remove(l)
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		if err := s.save(); err != nil {
+			return 0, err
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) RecordClick(ctx context.Context, shortCode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockWrites()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	l, ok := s.byCode[shortCode]
+	if !ok {
+		return fmt.Errorf("%w: %v", shortener.ErrNotFound, shortCode)
+	}
+	l.addClick(s.now())
+
+	return s.save()
+}
+
+func (s *Store) Stats(ctx context.Context, shortCode string) (shortener.Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return shortener.Stats{}, err
+	}
+
+	l, ok := s.byCode[shortCode]
+	if !ok {
+		return shortener.Stats{}, fmt.Errorf("%w: %v", shortener.ErrNotFound, shortCode)
+	}
+
+	stats := shortener.Stats{FirstClick: l.FirstClick, LastClick: l.LastClick, Daily: make([]shortener.DailyClicks, 0, len(l.Clicks))}
+	for _, d := range l.Clicks {
+		stats.TotalClicks += d.Clicks
+		stats.Daily = append(stats.Daily, shortener.DailyClicks{Day: d.Day, Clicks: d.Clicks})
+	}
+
+	return stats, nil
+}
+
+// live returns the link for shortCode unless it is missing or expired.
+func (s *Store) live(shortCode string) (*link, bool) {
+	l, ok := s.byCode[shortCode]
+	if !ok || expired(l, s.now()) {
+		return nil, false
+	}
+	return l, true
+}
+
+// liveURL returns the link for url unless it is missing or expired.
+func (s *Store) liveURL(url string) (*link, bool) {
+	l, ok := s.byURL[url]
+	if !ok || expired(l, s.now()) {
+		return nil, false
+	}
+	return l, true
+}
+
+// insert adds nl. An expired link still holding its URL or short code is
+// removed first, as the purge would have done, so both can be used again.
+func (s *Store) insert(nl shortener.NewLink) *link {
+	if l, ok := s.byURL[nl.OriginalURL]; ok {
+		s.remove(l)
+	}
+	if l, ok := s.byCode[nl.ShortCode]; ok {
+		s.remove(l)
+	}
+
+	s.state.NextID++
+	l := &link{
+		ID:           s.state.NextID,
+		OriginalURL:  nl.OriginalURL,
+		RawURL:       nl.RawURL,
+		ShortCode:    nl.ShortCode,
+		PasswordHash: nl.PasswordHash,
+		CreatedAt:    s.now(),
+		ExpiresAt:    nl.ExpiresAt,
+	}
+	l.History = []revision{{Number: 1, OriginalURL: l.OriginalURL, ChangedAt: l.CreatedAt}}
+	s.state.Links = append(s.state.Links, l)
+	s.byCode[l.ShortCode] = l
+	s.byURL[l.OriginalURL] = l
+	return l
+}
+
+func (s *Store) remove(l *link) {
+	s.state.Links = slices.DeleteFunc(s.state.Links, func(x *link) bool { return x == l })
+	delete(s.byCode, l.ShortCode)
+	delete(s.byURL, l.OriginalURL)
+}
+
+func (s *Store) index() {
+	s.byCode = make(map[string]*link, len(s.state.Links))
+	s.byURL = make(map[string]*link, len(s.state.Links))
+	for _, l := range s.state.Links {
+		s.byCode[l.ShortCode] = l
+		s.byURL[l.OriginalURL] = l
+	}
+}
+
+// lockWrites takes the cross-process lock a file-backed store's writers
+// hold from reload to save. It is a no-op for in-memory stores.
+func (s *Store) lockWrites() (unlock func(), err error) {
+	if s.path == "" {
+		return func() {}, nil
+	}
+	return lockFile(s.path + ".lock")
+}
+
+// reload reads the backing file if it changed since it was last read or
+// written. It is a no-op for in-memory stores.
+func (s *Store) reload() error {
+	if s.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+	// save always renames a new file into place, so a different file means
+	// another process has written since.
+	if s.file != nil && os.SameFile(info, s.file) && info.ModTime().Equal(s.file.ModTime()) && info.Size() == s.file.Size() {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("memstore: %s is corrupt: %w", s.path, err)
+	}
+
+	for _, l := range st.Links {
+		for _, c := range l.LegacyClicks {
+			l.addClick(c)
+		}
+		l.LegacyClicks = nil
+	}
+
+	s.state = st
+	s.file = info
+	s.index()
+	return nil
+}
+
+// save writes the whole state to a temporary file and renames it over the
+// backing file so a crash never leaves a half-written store behind.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("memstore: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("memstore: %w", err)
+	}
+	s.file = info
+	return nil
+}
+
+// revisions returns the link's history. Files written before history was
+// tracked have none, so the current destination stands in as revision 1.
+func (l *link) revisions() []revision {
+	if len(l.History) == 0 {
+		return []revision{{Number: 1, OriginalURL: l.OriginalURL, ChangedAt: l.CreatedAt}}
+	}
+	return l.History
+}
+
+// addClick counts a click made at the given time.
+func (l *link) addClick(at time.Time) {
+	at = at.UTC()
+	if l.FirstClick == nil || at.Before(*l.FirstClick) {
+		l.FirstClick = &at
+	}
+	if l.LastClick == nil || at.After(*l.LastClick) {
+		l.LastClick = &at
+	}
+
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	i, found := slices.BinarySearchFunc(l.Clicks, day, func(d clickDay, t time.Time) int {
+		return d.Day.Compare(t)
+	})
+	if !found {
+		l.Clicks = slices.Insert(l.Clicks, i, clickDay{Day: day})
+	}
+	l.Clicks[i].Clicks++
+}
+
+func expired(l *link, now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+func toItem(l *link) shortener.URLItem {
+	return shortener.URLItem{
+		ID:          l.ID,
+		OriginalURL: l.OriginalURL,
+		ShortCode:   l.ShortCode,
+		CreatedAt:   l.CreatedAt,
+		ExpiresAt:   l.ExpiresAt,
+		Protected:   l.PasswordHash != "",
+	}
+}
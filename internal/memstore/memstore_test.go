@@ -0,0 +1,639 @@
+package memstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var base = time.Date(2025, time.August, 20, 12, 0, 0, 0, time.UTC)
+
+// newTestStore returns an in-memory store whose clock advances one minute on
+// every read so creation times are distinct and predictable.
+func newTestStore() *Store {
+	s := New()
+	now := base
+	s.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return s
+}
+
+func TestAdd(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	res, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "GL9VeCa"})
+	require.NoError(t, err)
+	assert.Equal(t, shortener.AddResult{ShortCode: "GL9VeCa", Created: true}, res)
+
+	res, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "other12"})
+	require.NoError(t, err)
+	assert.Equal(t, shortener.AddResult{ShortCode: "GL9VeCa", Created: false}, res, "existing URL returns its code")
+
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/2", ShortCode: "GL9VeCa"})
+	assert.ErrorIs(t, err, shortener.ErrDuplicateShortCode)
+}
+
+func TestAdd_DeduplicatesCanonicalURLs(t *testing.T) {
+	ctx := context.Background()
+	n := 0
+	gen := &fakeNanoID{next: func() string { n++; return "code00" + string(rune('0'+n)) }}
+	svc, err := shortener.NewWithStore(newTestStore(), gen)
+	require.NoError(t, err)
+
+	first, err := svc.Add(ctx, "HTTPS://Example.com/", shortener.AddOptions{})
+	require.NoError(t, err)
+	for _, spelling := range []string{"https://example.com", "https://example.com:443/"} {
+		res, err := svc.Add(ctx, spelling, shortener.AddOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, first.ShortCode, res.ShortCode, spelling)
+		assert.False(t, res.Created, spelling)
+	}
+}
+
+func TestUpdate_CanonicalizesDestination(t *testing.T) {
+	ctx := context.Background()
+	n := 0
+	gen := &fakeNanoID{next: func() string { n++; return "code00" + string(rune('0'+n)) }}
+	store := newTestStore()
+	svc, err := shortener.NewWithStore(store, gen)
+	require.NoError(t, err)
+
+	first, err := svc.Add(ctx, "https://example.com/", shortener.AddOptions{})
+	require.NoError(t, err)
+	other, err := svc.Add(ctx, "https://example.org/", shortener.AddOptions{})
+	require.NoError(t, err)
+
+	err = svc.Update(ctx, other.ShortCode, "HTTPS://EXAMPLE.com:443")
+	assert.ErrorIs(t, err, shortener.ErrURLExists, "a differently spelled destination is still a duplicate of %s", first.ShortCode)
+
+	require.NoError(t, svc.Update(ctx, other.ShortCode, "https://Bücher.example/katalog"))
+	got, err := store.Get(ctx, other.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, "https://xn--bcher-kva.example/katalog", got.URL)
+	assert.Equal(t, "https://Bücher.example/katalog", store.byCode[other.ShortCode].RawURL, "raw input follows the new destination")
+}
+
+func TestAdd_ReusesExpiredLinks(t *testing.T) {
+	ctx := context.Background()
+	past := base.Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour).UTC()
+
+	testCases := []struct {
+		name string
+		opts shortener.AddOptions
+	}{
+		{name: "without a new expiry"},
+		{name: "with a new expiry", opts: shortener.AddOptions{ExpiresAt: &future}},
+		{name: "with the expired link's code as alias", opts: shortener.AddOptions{Alias: "dead001"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTestStore()
+			_, err := store.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/", ShortCode: "dead001", ExpiresAt: &past})
+			require.NoError(t, err)
+
+			svc, err := shortener.NewWithStore(store, &fakeNanoID{next: func() string { return "fresh01" }})
+			require.NoError(t, err)
+
+			res, err := svc.Add(ctx, "https://example.com/", tc.opts)
+			require.NoError(t, err)
+			assert.True(t, res.Created)
+			assert.Equal(t, tc.opts.ExpiresAt, res.ExpiresAt)
+
+			got, err := svc.Get(ctx, res.ShortCode, shortener.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/", got)
+
+			var items []shortener.URLItem
+			require.NoError(t, store.Export(ctx, func(item shortener.URLItem) error {
+				items = append(items, item)
+				return nil
+			}))
+			assert.Equal(t, []string{res.ShortCode}, codes(items), "the expired link is gone")
+		})
+	}
+}
+
+type fakeNanoID struct {
+	next func() string
+}
+
+func (f *fakeNanoID) Generate(n int) (string, error) {
+	return f.next(), nil
+}
+
+func TestAddBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/existing", ShortCode: "exist01"})
+	require.NoError(t, err)
+
+	results, err := s.AddBatch(ctx, []shortener.NewLink{
+		{OriginalURL: "https://example.com/1", ShortCode: "code001"},
+		{OriginalURL: "https://example.com/existing", ShortCode: "code002"},
+		{OriginalURL: "https://example.com/1", ShortCode: "code003"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []shortener.AddResult{
+		{ShortCode: "code001", Created: true},
+		{ShortCode: "exist01", Created: false},
+		{ShortCode: "code001", Created: false},
+	}, results)
+
+	_, err = s.AddBatch(ctx, []shortener.NewLink{
+		{OriginalURL: "https://example.com/2", ShortCode: "code004"},
+		{OriginalURL: "https://example.com/3", ShortCode: "code001"},
+	})
+	assert.ErrorIs(t, err, shortener.ErrDuplicateShortCode)
+	_, err = s.Get(ctx, "code004")
+	assert.ErrorIs(t, err, shortener.ErrNotFound, "a failed batch saves nothing")
+
+	_, err = s.AddBatch(ctx, []shortener.NewLink{
+		{OriginalURL: "https://example.com/4", ShortCode: "same001"},
+		{OriginalURL: "https://example.com/5", ShortCode: "same001"},
+	})
+	assert.ErrorIs(t, err, shortener.ErrDuplicateShortCode, "codes must be unique within a batch too")
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+	future := base.Add(24 * time.Hour)
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/live", ShortCode: "live123", ExpiresAt: &future})
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/gone", ShortCode: "gone123", ExpiresAt: &past})
+	require.NoError(t, err)
+
+	got, err := s.Get(ctx, "live123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/live", got.URL)
+	assert.Empty(t, got.PasswordHash)
+
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/locked", ShortCode: "lock123", PasswordHash: "$2a$10$hash"})
+	require.NoError(t, err)
+	got, err = s.Get(ctx, "lock123")
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Destination{URL: "https://example.com/locked", PasswordHash: "$2a$10$hash"}, got)
+
+	history, err := s.History(ctx, "lock123")
+	require.NoError(t, err)
+	assert.True(t, history[0].Protected)
+	items, err := s.List(ctx, shortener.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	for _, item := range items {
+		assert.Equal(t, item.ShortCode == "lock123", item.Protected, item.ShortCode)
+	}
+
+	_, err = s.Get(ctx, "gone123")
+	assert.ErrorIs(t, err, shortener.ErrNotFound, "expired links do not resolve")
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, shortener.ErrNotFound)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+
+	for _, l := range []struct{ url, code string }{
+		{"https://example.com/1", "code001"},
+		{"https://example.com/2", "code002"},
+		{"https://example.com/3", "code003"},
+	} {
+		_, err := s.Add(ctx, shortener.NewLink{OriginalURL: l.url, ShortCode: l.code})
+		require.NoError(t, err)
+	}
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/expired", ShortCode: "code004", ExpiresAt: &past})
+	require.NoError(t, err)
+
+	items, err := s.List(ctx, shortener.ListOptions{Limit: 10, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, []string{"code003", "code002", "code001"}, codes(items), "newest first, expired hidden")
+	assert.Equal(t, uint64(3), items[0].ID)
+
+	items, err = s.List(ctx, shortener.ListOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code002"}, codes(items))
+
+	items, err = s.List(ctx, shortener.ListOptions{Limit: 10, Offset: 5})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestList_FiltersAndSort(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+
+	for _, l := range []struct {
+		url, code string
+		expiresAt *time.Time
+	}{
+		{"https://docs.example.com/b", "bbb0001", nil},
+		{"https://example.org/docs", "ccc0001", nil},
+		{"https://example.com/a", "aaa0001", nil},
+		{"https://example.com/old", "ddd0001", &past},
+	} {
+		_, err := s.Add(ctx, shortener.NewLink{OriginalURL: l.url, ShortCode: l.code, ExpiresAt: l.expiresAt})
+		require.NoError(t, err)
+	}
+	since, until := base.Add(2*time.Minute), base.Add(3*time.Minute)
+
+	testCases := []struct {
+		name     string
+		opts     shortener.ListOptions
+		expected []string
+	}{
+		{name: "host and subdomains", opts: shortener.ListOptions{Filter: shortener.ListFilter{Host: "example.com"}}, expected: []string{"aaa0001", "bbb0001"}},
+		{name: "contains", opts: shortener.ListOptions{Filter: shortener.ListFilter{Contains: "DOCS"}}, expected: []string{"ccc0001", "bbb0001"}},
+		{name: "created range", opts: shortener.ListOptions{Filter: shortener.ListFilter{Since: &since, Until: &until}}, expected: []string{"aaa0001", "ccc0001"}},
+		{name: "with expired", opts: shortener.ListOptions{Filter: shortener.ListFilter{IncludeExpired: true}}, expected: []string{"ddd0001", "aaa0001", "ccc0001", "bbb0001"}},
+		{name: "by code", opts: shortener.ListOptions{Sort: shortener.SortCode, Ascending: true}, expected: []string{"aaa0001", "bbb0001", "ccc0001"}},
+		{name: "by url descending", opts: shortener.ListOptions{Sort: shortener.SortURL}, expected: []string{"ccc0001", "aaa0001", "bbb0001"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Limit = 10
+			items, err := s.List(ctx, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, codes(items))
+		})
+	}
+
+	// A cursor resumes in the order it was taken from.
+	byCode := shortener.ListOptions{Limit: 2, Sort: shortener.SortCode, Ascending: true}
+	page, err := s.List(ctx, byCode)
+	require.NoError(t, err)
+	after := shortener.CursorFor(page[1], byCode)
+	byCode.After = &after
+	page, err = s.List(ctx, byCode)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ccc0001"}, codes(page))
+
+	byURL := shortener.ListOptions{Limit: 2, Sort: shortener.SortURL}
+	page, err = s.List(ctx, byURL)
+	require.NoError(t, err)
+	after = shortener.CursorFor(page[1], byURL)
+	token, err := base64.RawURLEncoding.DecodeString(after.String())
+	require.NoError(t, err)
+	assert.NotContains(t, string(token), "example", "the token does not carry the URL")
+	byURL.After = &after
+	page, err = s.List(ctx, byURL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bbb0001"}, codes(page))
+}
+
+func TestList_Cursor(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	// Every link shares one creation time, so only the ID orders them.
+	s.now = func() time.Time { return base }
+
+	for _, code := range []string{"code001", "code002", "code003"} {
+		_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/" + code, ShortCode: code})
+		require.NoError(t, err)
+	}
+
+	first, err := s.List(ctx, shortener.ListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code003", "code002"}, codes(first))
+
+	// A link added between pages does not shift the next page.
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/new", ShortCode: "code004"})
+	require.NoError(t, err)
+
+	after := shortener.CursorFor(first[1], shortener.ListOptions{})
+	rest, err := s.List(ctx, shortener.ListOptions{Limit: 2, After: &after})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code001"}, codes(rest))
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com", ShortCode: "GL9VeCa"})
+	require.NoError(t, err)
+
+	deleted, err := s.Delete(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	_, err = s.Get(ctx, "GL9VeCa")
+	assert.ErrorIs(t, err, shortener.ErrNotFound)
+
+	deleted, err = s.Delete(ctx, "GL9VeCa")
+	assert.False(t, deleted)
+	assert.ErrorIs(t, err, shortener.ErrNotFound)
+
+	res, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com", ShortCode: "newcode"})
+	require.NoError(t, err)
+	assert.True(t, res.Created, "URL can be re-added after delete")
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "code001"})
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/2", ShortCode: "code002"})
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/gone", ShortCode: "gone001", ExpiresAt: &past})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Update(ctx, "code001", "https://example.com/new", "https://example.com/new"))
+	got, err := s.Get(ctx, "code001")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", got.URL)
+
+	res, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "code003"})
+	require.NoError(t, err)
+	assert.True(t, res.Created, "the old destination is free again")
+
+	assert.NoError(t, s.Update(ctx, "code002", "https://example.com/2", "https://example.com/2"), "unchanged URL is fine")
+	assert.ErrorIs(t, s.Update(ctx, "code002", "https://example.com/new", "https://example.com/new"), shortener.ErrURLExists)
+	assert.ErrorIs(t, s.Update(ctx, "gone001", "https://example.com/3", "https://example.com/3"), shortener.ErrNotFound)
+	assert.ErrorIs(t, s.Update(ctx, "missing", "https://example.com/3", "https://example.com/3"), shortener.ErrNotFound)
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "code001"})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(ctx, "code001", "https://example.com/2", "https://example.com/2"))
+	require.NoError(t, s.Update(ctx, "code001", "https://example.com/2", "https://example.com/2"), "no-op updates are not recorded")
+	require.NoError(t, s.Update(ctx, "code001", "https://example.com/1", "https://example.com/1"))
+
+	revisions, err := s.History(ctx, "code001")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	for i, want := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/1"} {
+		assert.Equal(t, i+1, revisions[i].Number)
+		assert.Equal(t, want, revisions[i].OriginalURL)
+	}
+	assert.True(t, revisions[1].ChangedAt.After(revisions[0].ChangedAt))
+
+	_, err = s.History(ctx, "missing")
+	assert.ErrorIs(t, err, shortener.ErrNotFound)
+}
+
+func TestHistory_LegacyFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	legacy := `{"nextId":1,"links":[{"id":1,"originalUrl":"https://example.com","shortCode":"GL9VeCa","createdAt":"2025-08-20T12:00:00Z"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o600))
+
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	revisions, err := s.History(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, []shortener.Revision{{Number: 1, OriginalURL: "https://example.com", ChangedAt: base}}, revisions)
+
+	require.NoError(t, s.Update(ctx, "GL9VeCa", "https://example.org", "https://example.org"))
+	revisions, err = s.History(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+
+	for i, code := range []string{"gone001", "gone002", "gone003"} {
+		_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/gone/" + string(rune('a'+i)), ShortCode: code, ExpiresAt: &past})
+		require.NoError(t, err)
+	}
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/live", ShortCode: "live001"})
+	require.NoError(t, err)
+
+	purged, err := s.PurgeExpired(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+
+	purged, err = s.PurgeExpired(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = s.Get(ctx, "live001")
+	assert.NoError(t, err)
+}
+
+func TestClicksAndStats(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com", ShortCode: "GL9VeCa"})
+	require.NoError(t, err)
+
+	stats, err := s.Stats(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Stats{Daily: []shortener.DailyClicks{}}, stats)
+
+	clicks := []time.Time{
+		time.Date(2025, time.August, 21, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, time.August, 20, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, time.August, 21, 18, 0, 0, 0, time.UTC),
+	}
+	for _, c := range clicks {
+		s.now = func() time.Time { return c }
+		require.NoError(t, s.RecordClick(ctx, "GL9VeCa"))
+	}
+
+	stats, err = s.Stats(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalClicks)
+	assert.Equal(t, clicks[1], *stats.FirstClick)
+	assert.Equal(t, clicks[2], *stats.LastClick)
+	assert.Equal(t, []shortener.DailyClicks{
+		{Day: time.Date(2025, time.August, 20, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		{Day: time.Date(2025, time.August, 21, 0, 0, 0, 0, time.UTC), Clicks: 2},
+	}, stats.Daily)
+
+	assert.ErrorIs(t, s.RecordClick(ctx, "missing"), shortener.ErrNotFound)
+	_, err = s.Stats(ctx, "missing")
+	assert.ErrorIs(t, err, shortener.ErrNotFound)
+}
+
+func TestOpen_PersistsAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+
+	first, err := Open(path)
+	require.NoError(t, err)
+	_, err = first.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com", ShortCode: "GL9VeCa"})
+	require.NoError(t, err)
+
+	second, err := Open(path)
+	require.NoError(t, err)
+	got, err := second.Get(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got.URL)
+
+	// Writes through one instance become visible to the other.
+	_, err = second.Add(ctx, shortener.NewLink{OriginalURL: "https://example.org", ShortCode: "Other12"})
+	require.NoError(t, err)
+	got, err = first.Get(ctx, "Other12")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", got.URL)
+
+	res, err := second.Add(ctx, shortener.NewLink{OriginalURL: "https://example.org/new", ShortCode: "newcode"})
+	require.NoError(t, err)
+	assert.True(t, res.Created)
+	items, err := first.List(ctx, shortener.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, items, 3, "IDs keep increasing across instances")
+}
+
+func TestOpen_ConcurrentWritersKeepEveryChange(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+
+	stores := make([]*Store, 8)
+	for i := range stores {
+		s, err := Open(path)
+		require.NoError(t, err)
+		stores[i] = s
+	}
+
+	const perStore = 25
+	var wg sync.WaitGroup
+	for i, s := range stores {
+		for j := range perStore {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.Add(ctx, shortener.NewLink{
+					OriginalURL: fmt.Sprintf("https://example.com/%d/%d", i, j),
+					ShortCode:   fmt.Sprintf("code%d%02d", i, j),
+				})
+				assert.NoError(t, err)
+			}()
+		}
+	}
+	wg.Wait()
+
+	reader, err := Open(path)
+	require.NoError(t, err)
+	items, err := reader.List(ctx, shortener.ListOptions{Limit: len(stores) * perStore})
+	require.NoError(t, err)
+	assert.Len(t, items, len(stores)*perStore)
+}
+
+func TestOpen_PersistsDailyClicks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+
+	s, err := Open(path)
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com", ShortCode: "GL9VeCa"})
+	require.NoError(t, err)
+	s.now = func() time.Time { return base }
+	for range 3 {
+		require.NoError(t, s.RecordClick(ctx, "GL9VeCa"))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"dailyClicks":[{"day":"2025-08-20T00:00:00Z","clicks":3}]`)
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	stats, err := reopened.Stats(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalClicks)
+	assert.Equal(t, base, *stats.FirstClick)
+}
+
+func TestOpen_LegacyClicks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	legacy := `{"nextId":1,"links":[{"id":1,"originalUrl":"https://example.com","shortCode":"GL9VeCa","createdAt":"2025-08-20T12:00:00Z",` +
+		`"clicks":["2025-08-21T09:00:00Z","2025-08-20T23:59:00Z","2025-08-21T18:00:00Z"]}]}`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0o600))
+
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	stats, err := s.Stats(ctx, "GL9VeCa")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalClicks)
+	assert.Equal(t, time.Date(2025, time.August, 20, 23, 59, 0, 0, time.UTC), *stats.FirstClick)
+	assert.Equal(t, time.Date(2025, time.August, 21, 18, 0, 0, 0, time.UTC), *stats.LastClick)
+	assert.Equal(t, []shortener.DailyClicks{
+		{Day: time.Date(2025, time.August, 20, 0, 0, 0, 0, time.UTC), Clicks: 1},
+		{Day: time.Date(2025, time.August, 21, 0, 0, 0, 0, time.UTC), Clicks: 2},
+	}, stats.Daily)
+}
+
+func TestOpen_Errors(t *testing.T) {
+	_, err := Open("")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "links.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+	_, err = Open(path)
+	assert.ErrorContains(t, err, "corrupt")
+}
+
+func codes(items []shortener.URLItem) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.ShortCode)
+	}
+	return out
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore()
+	past := base.Add(-time.Hour)
+
+	_, err := s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/1", ShortCode: "code001"})
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/expired", ShortCode: "code002", ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = s.Add(ctx, shortener.NewLink{OriginalURL: "https://example.com/3", ShortCode: "code003"})
+	require.NoError(t, err)
+
+	var items []shortener.URLItem
+	require.NoError(t, s.Export(ctx, func(item shortener.URLItem) error {
+		items = append(items, item)
+		return nil
+	}))
+	assert.Equal(t, []string{"code001", "code002", "code003"}, codes(items), "oldest first, expired included")
+
+	stop := assert.AnError
+	calls := 0
+	err = s.Export(ctx, func(shortener.URLItem) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
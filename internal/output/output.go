@@ -0,0 +1,255 @@
+// Package output renders command responses as JSON, YAML, CSV, an aligned
+// table or plain text.
+//
+// Actions write every response through Write. Unless out was made by
+// NewWriter with a format other than JSON, Write produces exactly the JSON
+// jsonutil.WriteJSON would, so the HTTP API and scripts are unaffected.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/anewball/urlshortener/internal/jsonutil"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	JSON  = "json"
+	Table = "table"
+	YAML  = "yaml"
+	CSV   = "csv"
+	Plain = "plain"
+)
+
+// Formats lists every supported format, JSON first as the default.
+var Formats = []string{JSON, Table, YAML, CSV, Plain}
+
+var ErrFormat = errors.New("unsupported output format")
+
+// Tabular is implemented by responses that read naturally as rows. Header
+// names the columns; an empty cell is shown as "-" in tables.
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+// Plainer is implemented by responses with a terser plain form than their
+// rows, such as the bare short code of a new link.
+type Plainer interface {
+	Plain() string
+}
+
+// Footnoter is implemented by responses that carry something a table has
+// no column for, such as the cursor of the next page.
+type Footnoter interface {
+	Footnote() string
+}
+
+// Format is the value of an --output flag. It implements pflag.Value, so an
+// unknown format is rejected while flags are parsed.
+type Format string
+
+func (f *Format) String() string {
+	if *f == "" {
+		return JSON
+	}
+	return string(*f)
+}
+
+func (f *Format) Set(s string) error {
+	s = strings.ToLower(s)
+	if !slices.Contains(Formats, s) {
+		return fmt.Errorf("%w %q; want one of %s", ErrFormat, s, strings.Join(Formats, ", "))
+	}
+	*f = Format(s)
+	return nil
+}
+
+func (f *Format) Type() string {
+	return "format"
+}
+
+// Writer is an io.Writer that remembers how responses written to it through
+// Write should be rendered. Bytes written to it directly pass through.
+type Writer struct {
+	w      io.Writer
+	format string
+}
+
+// NewWriter returns out itself for JSON, so callers that compare writers
+// still see the one they passed, and a Writer for any other format.
+func NewWriter(out io.Writer, format string) io.Writer {
+	if format == "" || format == JSON {
+		return out
+	}
+	return &Writer{w: out, format: format}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Write renders v to out in the format out was made with, or as JSON.
+func Write(out io.Writer, v any) error {
+	w, ok := out.(*Writer)
+	if !ok {
+		return jsonutil.WriteJSON(out, v)
+	}
+
+	switch w.format {
+	case YAML:
+		return writeYAML(w.w, v)
+	case Table:
+		return writeTable(w.w, v)
+	case CSV:
+		return writeCSV(w.w, v)
+	case Plain:
+		return writePlain(w.w, v)
+	default:
+		return jsonutil.WriteJSON(w.w, v)
+	}
+}
+
+// writeYAML goes through JSON so field names and omitempty follow the json
+// tags, and through yaml.Node so fields keep their order.
+func writeYAML(out io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle drops the flow style and quoting yaml.v3 keeps from JSON input.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+func writeTable(out io.Writer, v any) error {
+	header, rows, err := tabulate(v)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	upper := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+	}
+	fmt.Fprintln(tw, strings.Join(upper, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = c
+			if c == "" {
+				cells[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if f, ok := v.(Footnoter); ok {
+		if note := f.Footnote(); note != "" {
+			_, err = fmt.Fprintf(out, "\n%s\n", note)
+		}
+	}
+	return err
+}
+
+func writeCSV(out io.Writer, v any) error {
+	header, rows, err := tabulate(v)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(out)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func writePlain(out io.Writer, v any) error {
+	if p, ok := v.(Plainer); ok {
+		_, err := fmt.Fprintln(out, p.Plain())
+		return err
+	}
+
+	_, rows, err := tabulate(v)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(out, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tabulate returns the rows of v. Responses that are not Tabular become one
+// field/value row per top-level JSON field, nested values as compact JSON.
+func tabulate(v any) ([]string, [][]string, error) {
+	if t, ok := v.(Tabular); ok {
+		return t.Header(), t.Rows(), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return []string{"value"}, [][]string{{string(data)}}, nil
+	}
+
+	var rows [][]string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+
+		value := string(raw)
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		rows = append(rows, []string{fmt.Sprint(key), value})
+	}
+	return []string{"field", "value"}, rows, nil
+}
@@ -0,0 +1,151 @@
+package output_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	created := time.Date(2025, time.August, 20, 12, 0, 0, 0, time.UTC)
+	expires := created.Add(72 * time.Hour)
+
+	list := core.ListResponse{
+		Items: []core.ResultResponse{
+			{ShortCode: "GL9VeCa", RawURL: "https://example.com/a", CreatedAt: &created, ExpiresAt: &expires},
+			{ShortCode: "docs", RawURL: "https://example.com/docs,v2", CreatedAt: &created},
+		},
+		Count: 2, Limit: 2, NextCursor: "abc",
+	}
+	result := core.ResultResponse{ShortCode: "GL9VeCa", RawURL: "https://example.com/a"}
+
+	testCases := []struct {
+		name     string
+		format   string
+		value    any
+		expected string
+	}{
+		{
+			name:     "json is unchanged",
+			format:   output.JSON,
+			value:    result,
+			expected: `{"shortCode":"GL9VeCa","rawUrl":"https://example.com/a"}` + "\n",
+		},
+		{
+			name:   "list table",
+			format: output.Table,
+			value:  list,
+			expected: "CODE     URL                          CREATED               EXPIRES\n" +
+				"GL9VeCa  https://example.com/a        2025-08-20T12:00:00Z  2025-08-23T12:00:00Z\n" +
+				"docs     https://example.com/docs,v2  2025-08-20T12:00:00Z  -\n" +
+				"\nMore links follow; resume with --cursor abc\n",
+		},
+		{
+			name:   "stripped params as footnote",
+			format: output.Table,
+			value:  core.ResultResponse{ShortCode: "GL9VeCa", RawURL: "https://example.com/a?utm_source=x&fbclid=y", StrippedParams: []string{"utm_source", "fbclid"}},
+			expected: "CODE     URL                                          EXPIRES\n" +
+				"GL9VeCa  https://example.com/a?utm_source=x&fbclid=y  -\n" +
+				"\nStripped query parameters: utm_source, fbclid\n",
+		},
+		{
+			name:     "unicode host shown in tables",
+			format:   output.Plain,
+			value:    core.ListResponse{Items: []core.ResultResponse{{ShortCode: "docs", RawURL: "https://xn--bcher-kva.example/", DisplayURL: "https://bücher.example/"}}},
+			expected: "docs\thttps://bücher.example/\t\t\n",
+		},
+		{
+			name:     "protected link in tables",
+			format:   output.Plain,
+			value:    core.ListResponse{Items: []core.ResultResponse{{ShortCode: "docs", Protected: true}}},
+			expected: "docs\t(password protected)\t\t\n",
+		},
+		{
+			name:   "list csv",
+			format: output.CSV,
+			value:  list,
+			expected: "code,url,created,expires\n" +
+				"GL9VeCa,https://example.com/a,2025-08-20T12:00:00Z,2025-08-23T12:00:00Z\n" +
+				"docs,\"https://example.com/docs,v2\",2025-08-20T12:00:00Z,\n",
+		},
+		{
+			name:   "list plain",
+			format: output.Plain,
+			value:  list,
+			expected: "GL9VeCa\thttps://example.com/a\t2025-08-20T12:00:00Z\t2025-08-23T12:00:00Z\n" +
+				"docs\thttps://example.com/docs,v2\t2025-08-20T12:00:00Z\t\n",
+		},
+		{
+			name:     "result plain is the code",
+			format:   output.Plain,
+			value:    result,
+			expected: "GL9VeCa\n",
+		},
+		{
+			name:     "result yaml keeps field order",
+			format:   output.YAML,
+			value:    result,
+			expected: "shortCode: GL9VeCa\nrawUrl: https://example.com/a\n",
+		},
+		{
+			name:     "delete table",
+			format:   output.Table,
+			value:    core.DeleteResponse{Deleted: true, ShortCode: "GL9VeCa"},
+			expected: "CODE     DELETED\nGL9VeCa  true\n",
+		},
+		{
+			name:     "error plain",
+			format:   output.Plain,
+			value:    core.ErrorResponse{Error: "invalid limit", Details: "limit must be between 1 and 20; got 50"},
+			expected: "error: invalid limit: limit must be between 1 and 20; got 50\n",
+		},
+		{
+			name:     "error csv",
+			format:   output.CSV,
+			value:    core.ErrorResponse{Error: "invalid limit", Code: core.ExitInvalid, Kind: "LIMIT_OUT_OF_RANGE", Field: "limit", Details: "limit must be between 1 and 20; got 50"},
+			expected: "error,code,kind,field,details\ninvalid limit,2,LIMIT_OUT_OF_RANGE,limit,limit must be between 1 and 20; got 50\n",
+		},
+		{
+			name:     "error json keeps code numeric",
+			format:   output.JSON,
+			value:    core.ErrorResponse{Error: "invalid limit", Code: core.ExitInvalid, Kind: "LIMIT_OUT_OF_RANGE", Field: "limit"},
+			expected: `{"error":"invalid limit","code":2,"kind":"LIMIT_OUT_OF_RANGE","field":"limit"}` + "\n",
+		},
+		{
+			name:     "other responses as fields",
+			format:   output.Table,
+			value:    core.PurgeResponse{Purged: 3},
+			expected: "FIELD   VALUE\npurged  3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, output.Write(output.NewWriter(&buf, tc.format), tc.value))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestNewWriter_JSONIsPassthrough(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Same(t, &buf, output.NewWriter(&buf, output.JSON))
+	assert.Same(t, &buf, output.NewWriter(&buf, ""))
+}
+
+func TestFormat_Set(t *testing.T) {
+	var f output.Format
+	assert.Equal(t, output.JSON, f.String())
+
+	require.NoError(t, f.Set("TABLE"))
+	assert.Equal(t, output.Table, f.String())
+
+	assert.ErrorIs(t, f.Set("xml"), output.ErrFormat)
+	assert.Equal(t, output.Table, f.String(), "a rejected value leaves the flag unchanged")
+}
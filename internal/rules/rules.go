@@ -0,0 +1,222 @@
+// Package rules keeps the domain allow and deny rules of the shortener in a
+// plain text file, one rule per line:
+//
+//	# Only our own sites, never the old campaign domain.
+//	allow example.com
+//	allow *.example.com
+//	deny  promo.example.com
+//
+// Blank lines and lines starting with # are ignored and kept as they are
+// when rules are added or removed.
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+// Manager lists and edits a set of domain rules.
+type Manager interface {
+	shortener.RuleSource
+	// Path names where the rules are kept.
+	Path() string
+	// Add appends rule and reports false when it is already present.
+	Add(rule shortener.DomainRule) (bool, error)
+	// Remove deletes rule and reports false when it was not present.
+	Remove(rule shortener.DomainRule) (bool, error)
+}
+
+// Change is the result of adding or removing a rule.
+type Change struct {
+	Rule    string `json:"rule"`
+	Changed bool   `json:"changed"`
+}
+
+// List is the response of listing the rules.
+type List struct {
+	Path  string                 `json:"path"`
+	Rules []shortener.DomainRule `json:"rules"`
+}
+
+func (l List) Header() []string {
+	return []string{"action", "pattern"}
+}
+
+func (l List) Rows() [][]string {
+	rows := make([][]string, 0, len(l.Rules))
+	for _, r := range l.Rules {
+		rows = append(rows, []string{string(r.Action), r.Pattern})
+	}
+	return rows
+}
+
+var _ Manager = (*File)(nil)
+
+// File is a Manager backed by a rules file. It re-reads the file when it
+// changes, so a running server enforces rules edited from the CLI. A missing
+// file holds no rules. File is safe for concurrent use.
+type File struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	lines   []string
+	rules   shortener.DomainRules
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Path returns the file the rules are kept in.
+func (f *File) Path() string {
+	return f.path
+}
+
+func (f *File) DomainRules() (shortener.DomainRules, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(f.rules), nil
+}
+
+func (f *File) Add(rule shortener.DomainRule) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reload(); err != nil {
+		return false, err
+	}
+	if slices.Contains(f.rules, rule) {
+		return false, nil
+	}
+
+	f.lines = append(f.lines, rule.String())
+	f.rules = append(f.rules, rule)
+	return true, f.save()
+}
+
+func (f *File) Remove(rule shortener.DomainRule) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.reload(); err != nil {
+		return false, err
+	}
+	if !slices.Contains(f.rules, rule) {
+		return false, nil
+	}
+
+	f.lines = slices.DeleteFunc(f.lines, func(line string) bool {
+		r, ok, _ := parseLine(line)
+		return ok && r == rule
+	})
+	f.rules = slices.DeleteFunc(f.rules, func(r shortener.DomainRule) bool { return r == rule })
+	return true, f.save()
+}
+
+func (f *File) reload() error {
+	info, err := os.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f.lines, f.rules = nil, nil
+		f.modTime, f.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+
+	var lines []string
+	var rules shortener.DomainRules
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		r, ok, err := parseLine(line)
+		if err != nil {
+			return fmt.Errorf("rules: %s:%d: %w", f.path, n, err)
+		}
+		if ok {
+			rules = append(rules, r)
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+
+	f.lines, f.rules = lines, rules
+	f.modTime, f.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// parseLine returns the rule on line, or false for a blank or comment line.
+func parseLine(line string) (shortener.DomainRule, bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return shortener.DomainRule{}, false, nil
+	}
+	if len(fields) != 2 {
+		return shortener.DomainRule{}, false, fmt.Errorf("%w: want \"allow|deny <pattern>\"; got %q", shortener.ErrRule, line)
+	}
+	r, err := shortener.ParseRule(fields[0], fields[1])
+	return r, err == nil, err
+}
+
+// save writes the file through a temporary file renamed over it, so a crash
+// never leaves half the rules behind.
+func (f *File) save() error {
+	var buf bytes.Buffer
+	for _, line := range f.lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("rules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+	f.modTime, f.size = info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_MissingFileHasNoRules(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "rules"))
+
+	rules, err := f.DomainRules()
+
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestFile_AddAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "rules")
+	f := NewFile(path)
+	allow := shortener.DomainRule{Action: shortener.RuleAllow, Pattern: "*.example.com"}
+	deny := shortener.DomainRule{Action: shortener.RuleDeny, Pattern: "promo.example.com"}
+
+	added, err := f.Add(allow)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = f.Add(deny)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = f.Add(allow)
+	require.NoError(t, err)
+	assert.False(t, added, "a rule is only added once")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "allow *.example.com\ndeny promo.example.com\n", string(data))
+
+	removed, err := f.Remove(allow)
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = f.Remove(allow)
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	rules, err := NewFile(path).DomainRules()
+	require.NoError(t, err)
+	assert.Equal(t, shortener.DomainRules{deny}, rules)
+}
+
+func TestFile_KeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules")
+	require.NoError(t, os.WriteFile(path, []byte("# campaign domains\ndeny  *.promo.example\n\nallow example.com\n"), 0o644))
+	f := NewFile(path)
+
+	removed, err := f.Remove(shortener.DomainRule{Action: shortener.RuleAllow, Pattern: "example.com"})
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# campaign domains\ndeny  *.promo.example\n\n", string(data))
+}
+
+func TestFile_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules")
+	require.NoError(t, os.WriteFile(path, []byte("deny a.example\n"), 0o644))
+	f := NewFile(path)
+
+	rules, err := f.DomainRules()
+	require.NoError(t, err)
+	assert.Equal(t, shortener.DomainRules{{Action: shortener.RuleDeny, Pattern: "a.example"}}, rules)
+
+	require.NoError(t, os.WriteFile(path, []byte("deny b.example\n"), 0o644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	rules, err = f.DomainRules()
+	require.NoError(t, err)
+	assert.Equal(t, shortener.DomainRules{{Action: shortener.RuleDeny, Pattern: "b.example"}}, rules)
+}
+
+func TestFile_RejectsBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules")
+	require.NoError(t, os.WriteFile(path, []byte("allow example.com\nblock example.org\n"), 0o644))
+
+	_, err := NewFile(path).DomainRules()
+
+	assert.ErrorIs(t, err, shortener.ErrRule)
+	assert.ErrorContains(t, err, path+":2")
+}
+
+func TestList_Rows(t *testing.T) {
+	l := List{Path: "rules", Rules: []shortener.DomainRule{{Action: shortener.RuleDeny, Pattern: "*.phish.example"}}}
+
+	assert.Equal(t, []string{"action", "pattern"}, l.Header())
+	assert.Equal(t, [][]string{{"deny", "*.phish.example"}}, l.Rows())
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/jsonutil"
+)
+
+const (
+	defaultListLimit = 50
+	maxBodyBytes     = 1 << 20
+	// passwordHeader carries the password of a protected link to the get
+	// endpoint.
+	passwordHeader = "X-Link-Password"
+)
+
+type addRequest struct {
+	URL        string   `json:"url"`
+	Alias      string   `json:"alias,omitempty"`
+	TTL        string   `json:"ttl,omitempty"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	KeepParams []string `json:"keepParams,omitempty"`
+	Password   string   `json:"password,omitempty"`
+}
+
+func (s *server) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req addRequest
+	if err := jsonutil.ReadJSON(http.MaxBytesReader(w, r.Body, maxBodyBytes), &req); err != nil {
+		writeError(w, http.StatusBadRequest, core.ErrInvalidArgs, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	opts := core.AddOptions{Alias: req.Alias, ExpiresAt: req.ExpiresAt, KeepParams: req.KeepParams, Password: req.Password}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, core.ErrExpiry, fmt.Errorf("ttl must be a duration such as 72h; got %q", req.TTL))
+			return
+		}
+		opts.TTL = ttl
+	}
+
+	var buf bytes.Buffer
+	err := s.acts.AddAction(r.Context(), &buf, []string{req.URL}, opts)
+
+	// Adding a URL that is already shortened creates nothing, so it answers
+	// 200 with the existing link rather than 201.
+	status := http.StatusCreated
+	var res core.ResultResponse
+	if err == nil && jsonutil.ReadJSON(bytes.NewReader(buf.Bytes()), &res) == nil && res.Existing {
+		status = http.StatusOK
+	}
+	writeResult(w, &buf, err, status)
+}
+
+func (s *server) handleGet(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := s.acts.GetAction(r.Context(), &buf, []string{r.PathValue("code")}, core.GetOptions{Password: r.Header.Get(passwordHeader)})
+	writeResult(w, &buf, err, http.StatusOK)
+}
+
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultListLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, core.ErrLimit, err)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, core.ErrOffset, err)
+		return
+	}
+
+	q := r.URL.Query()
+
+	var expired bool
+	if v := q.Get("expired"); v != "" {
+		if expired, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, core.ErrFilter, core.WithField("expired", fmt.Errorf("expired must be true or false; got %q", v)))
+			return
+		}
+	}
+
+	opts := core.ListOptions{
+		Limit:    limit,
+		Offset:   offset,
+		Cursor:   q.Get("cursor"),
+		Host:     q.Get("host"),
+		Contains: q.Get("contains"),
+		Since:    q.Get("since"),
+		Until:    q.Get("until"),
+		Expired:  expired,
+		Sort:     q.Get("sort"),
+		Order:    q.Get("order"),
+	}
+
+	var buf bytes.Buffer
+	err = s.acts.ListAction(r.Context(), &buf, opts)
+	writeResult(w, &buf, err, http.StatusOK)
+}
+
+func (s *server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := s.acts.DeleteAction(r.Context(), &buf, []string{r.PathValue("code")})
+	writeResult(w, &buf, err, http.StatusOK)
+}
+
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := s.acts.StatsAction(r.Context(), &buf, []string{r.PathValue("code")})
+	writeResult(w, &buf, err, http.StatusOK)
+}
+
+func queryInt(r *http.Request, key string, fallback int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer; got %q", key, v)
+	}
+	return n, nil
+}
+
+// writeResult sends the body an action produced. Actions write an
+// ErrorResponse on failure, so only the status code depends on err.
+func writeResult(w http.ResponseWriter, body *bytes.Buffer, err error, okStatus int) {
+	status := okStatus
+	if err != nil {
+		status = statusFor(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = body.WriteTo(w)
+}
+
+func writeError(w http.ResponseWriter, status int, code error, cause error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = jsonutil.WriteJSON(w, core.NewErrorResponse(code, cause))
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, core.ErrNotFound), errors.Is(err, core.ErrUnableToDelete):
+		return http.StatusNotFound
+	case errors.Is(err, core.ErrURLFormat),
+		errors.Is(err, core.ErrDestination),
+		errors.Is(err, core.ErrDomain),
+		errors.Is(err, core.ErrLimit),
+		errors.Is(err, core.ErrOffset),
+		errors.Is(err, core.ErrCursor),
+		errors.Is(err, core.ErrSort),
+		errors.Is(err, core.ErrFilter),
+		errors.Is(err, core.ErrLenZero),
+		errors.Is(err, core.ErrShortCode),
+		errors.Is(err, core.ErrAliasFormat),
+		errors.Is(err, core.ErrExpiry),
+		errors.Is(err, core.ErrInvalidArgs):
+		return http.StatusBadRequest
+	case errors.Is(err, core.ErrPasswordRequired), errors.Is(err, core.ErrWrongPassword):
+		return http.StatusUnauthorized
+	case errors.Is(err, core.ErrAliasTaken), errors.Is(err, core.ErrURLExists):
+		return http.StatusConflict
+	case errors.Is(err, core.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,449 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/jsonutil"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleAdd(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		body                   string
+		expectedStatus         int
+		expectedResultResponse core.ResultResponse
+		expectedError          string
+		svc                    shortener.URLShortener
+	}{
+		{
+			name:                   "success",
+			body:                   `{"url":"https://example.com"}`,
+			expectedStatus:         http.StatusCreated,
+			expectedResultResponse: core.ResultResponse{ShortCode: "Hpa3t2B", RawURL: "https://example.com"},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: "Hpa3t2B", Created: true}, nil
+				},
+			},
+		},
+		{
+			name:                   "with password",
+			body:                   `{"url":"https://example.com","password":"s3cret"}`,
+			expectedStatus:         http.StatusCreated,
+			expectedResultResponse: core.ResultResponse{ShortCode: "Hpa3t2B", RawURL: "https://example.com"},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					if opts.Password != "s3cret" {
+						return shortener.AddResult{}, errors.New("password not passed on")
+					}
+					return shortener.AddResult{ShortCode: "Hpa3t2B", Created: true}, nil
+				},
+			},
+		},
+		{
+			name:                   "already shortened",
+			body:                   `{"url":"https://example.com"}`,
+			expectedStatus:         http.StatusOK,
+			expectedResultResponse: core.ResultResponse{ShortCode: "Other12", RawURL: "https://example.com", Existing: true},
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{ShortCode: "Other12", Created: false}, nil
+				},
+			},
+		},
+		{
+			name:           "malformed body",
+			body:           `{"url":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.ErrInvalidArgs.Error(),
+			svc:            &mockedShortener{},
+		},
+		{
+			name:           "invalid url",
+			body:           `{"url":"ftp://example.com"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.ErrURLFormat.Error(),
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrIsValidURL
+				},
+			},
+		},
+		{
+			name:           "malformed ttl",
+			body:           `{"url":"https://example.com","ttl":"3 days"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.ErrExpiry.Error(),
+			svc:            &mockedShortener{},
+		},
+		{
+			name:           "private destination",
+			body:           `{"url":"http://127.0.0.1/"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  core.ErrDestination.Error(),
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrPrivateDestination
+				},
+			},
+		},
+		{
+			name:           "alias taken",
+			body:           `{"url":"https://example.com","alias":"spring-sale"}`,
+			expectedStatus: http.StatusConflict,
+			expectedError:  core.ErrAliasTaken.Error(),
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrAliasTaken
+				},
+			},
+		},
+		{
+			name:           "database error",
+			body:           `{"url":"https://example.com"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  core.ErrAdd.Error(),
+			svc: &mockedShortener{
+				addFunc: func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+					return shortener.AddResult{}, shortener.ErrQueryRow
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := New(tc.svc, core.NewActions(tc.svc, 20), nil).(*server)
+
+			rec := httptest.NewRecorder()
+			srv.api.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/urls", strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			if tc.expectedError != "" {
+				var actualErrorResponse core.ErrorResponse
+				require.NoError(t, jsonutil.ReadJSON(rec.Body, &actualErrorResponse))
+				assert.Equal(t, tc.expectedError, actualErrorResponse.Error)
+				return
+			}
+
+			var actualResultResponse core.ResultResponse
+			require.NoError(t, jsonutil.ReadJSON(rec.Body, &actualResultResponse))
+			assert.Equal(t, tc.expectedResultResponse, actualResultResponse)
+		})
+	}
+}
+
+func TestHandleGet(t *testing.T) {
+	testCases := []struct {
+		name           string
+		password       string
+		expectedStatus int
+		svc            shortener.URLShortener
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "https://example.com", nil
+				},
+			},
+		},
+		{
+			name:           "not found",
+			expectedStatus: http.StatusNotFound,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", shortener.ErrNotFound
+				},
+			},
+		},
+		{
+			name:           "query error",
+			expectedStatus: http.StatusInternalServerError,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", shortener.ErrQuery
+				},
+			},
+		},
+		{
+			name:           "password from header",
+			password:       "s3cret",
+			expectedStatus: http.StatusOK,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					if opts.Password != "s3cret" {
+						return "", shortener.ErrWrongPassword
+					}
+					return "https://example.com", nil
+				},
+			},
+		},
+		{
+			name:           "wrong password",
+			password:       "guess",
+			expectedStatus: http.StatusUnauthorized,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", shortener.ErrWrongPassword
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := New(tc.svc, core.NewActions(tc.svc, 20), nil).(*server)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/urls/Hpa3t2B", nil)
+			if tc.password != "" {
+				req.Header.Set("X-Link-Password", tc.password)
+			}
+			rec := httptest.NewRecorder()
+			srv.api.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestHandleList(t *testing.T) {
+	items := []shortener.URLItem{
+		{ID: 1, OriginalURL: "https://anewball.com", ShortCode: "nMHdgTh", CreatedAt: time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC)},
+	}
+
+	testCases := []struct {
+		name                 string
+		query                string
+		expectedStatus       int
+		expectedLimit        int
+		expectedOffset       int
+		expectedListResponse core.ListResponse
+	}{
+		{
+			name:           "defaults",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedLimit:  defaultListLimit,
+			expectedOffset: 0,
+			expectedListResponse: core.ListResponse{
+				Items: []core.ResultResponse{{ShortCode: "nMHdgTh", RawURL: "https://anewball.com", CreatedAt: &items[0].CreatedAt}},
+				Count: 1, Limit: defaultListLimit, Offset: 0,
+			},
+		},
+		{
+			name:           "explicit limit and offset",
+			query:          "?limit=5&offset=10",
+			expectedStatus: http.StatusOK,
+			expectedLimit:  5,
+			expectedOffset: 10,
+			expectedListResponse: core.ListResponse{
+				Items: []core.ResultResponse{{ShortCode: "nMHdgTh", RawURL: "https://anewball.com", CreatedAt: &items[0].CreatedAt}},
+				Count: 1, Limit: 5, Offset: 10,
+			},
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit not a number",
+			query:          "?limit=ten",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "offset not a number",
+			query:          "?offset=-",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expired not a bool",
+			query:          "?expired=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown sort",
+			query:          "?sort=clicks",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=!!",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotLimit, gotOffset int
+			svc := &mockedShortener{
+				listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+					gotLimit, gotOffset = opts.Limit, opts.Offset
+					return items, nil
+				},
+			}
+			srv := New(svc, core.NewActions(svc, 500), nil).(*server)
+
+			rec := httptest.NewRecorder()
+			srv.api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/urls"+tc.query, nil))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tc.expectedLimit+1, gotLimit, "one extra row tells whether another page follows")
+			assert.Equal(t, tc.expectedOffset, gotOffset)
+
+			var actualListResponse core.ListResponse
+			require.NoError(t, jsonutil.ReadJSON(rec.Body, &actualListResponse))
+			assert.Equal(t, tc.expectedListResponse, actualListResponse)
+		})
+	}
+}
+
+func TestHandleList_HidesProtectedDestinations(t *testing.T) {
+	svc := &mockedShortener{
+		listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+			return []shortener.URLItem{
+				{ID: 1, OriginalURL: "https://intranet.example.com/handbook", ShortCode: "nMHdgTh", CreatedAt: time.Date(2025, time.August, 25, 14, 30, 0, 0, time.UTC), Protected: true},
+			}, nil
+		},
+	}
+	srv := New(svc, core.NewActions(svc, 20), nil).(*server)
+
+	rec := httptest.NewRecorder()
+	srv.api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/urls?limit=10", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"protected":true`)
+	assert.NotContains(t, rec.Body.String(), "intranet.example.com")
+}
+
+func TestHandleDelete(t *testing.T) {
+	testCases := []struct {
+		name           string
+		expectedStatus int
+		svc            shortener.URLShortener
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			svc: &mockedShortener{
+				deleteFunc: func(ctx context.Context, shortCode string) (bool, error) {
+					return true, nil
+				},
+			},
+		},
+		{
+			name:           "not found",
+			expectedStatus: http.StatusNotFound,
+			svc: &mockedShortener{
+				deleteFunc: func(ctx context.Context, shortCode string) (bool, error) {
+					return false, shortener.ErrNotFound
+				},
+			},
+		},
+		{
+			name:           "exec error",
+			expectedStatus: http.StatusInternalServerError,
+			svc: &mockedShortener{
+				deleteFunc: func(ctx context.Context, shortCode string) (bool, error) {
+					return false, shortener.ErrExec
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := New(tc.svc, core.NewActions(tc.svc, 20), nil).(*server)
+
+			rec := httptest.NewRecorder()
+			srv.api.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/urls/Hpa3t2B", nil))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected int
+	}{
+		{core.ErrNotFound, http.StatusNotFound},
+		{core.ErrUnableToDelete, http.StatusNotFound},
+		{core.ErrURLFormat, http.StatusBadRequest},
+		{core.ErrLimit, http.StatusBadRequest},
+		{core.ErrOffset, http.StatusBadRequest},
+		{core.ErrAliasFormat, http.StatusBadRequest},
+		{core.ErrExpiry, http.StatusBadRequest},
+		{core.ErrAliasTaken, http.StatusConflict},
+		{core.ErrURLExists, http.StatusConflict},
+		{core.ErrPasswordRequired, http.StatusUnauthorized},
+		{core.ErrWrongPassword, http.StatusUnauthorized},
+		{core.ErrTimeout, http.StatusGatewayTimeout},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{core.ErrUnexpected, http.StatusInternalServerError},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, statusFor(tc.err))
+		})
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	testCases := []struct {
+		name           string
+		expectedStatus int
+		svc            shortener.URLShortener
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{TotalClicks: 1}, nil
+				},
+			},
+		},
+		{
+			name:           "not found",
+			expectedStatus: http.StatusNotFound,
+			svc: &mockedShortener{
+				statsFunc: func(ctx context.Context, shortCode string) (shortener.Stats, error) {
+					return shortener.Stats{}, shortener.ErrNotFound
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := New(tc.svc, core.NewActions(tc.svc, 20), nil).(*server)
+
+			rec := httptest.NewRecorder()
+			srv.api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/urls/Hpa3t2B/stats", nil))
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
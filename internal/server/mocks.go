@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+var _ shortener.URLShortener = (*mockedShortener)(nil)
+
+type mockedShortener struct {
+	addFunc      func(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error)
+	addBatchFunc func(ctx context.Context, items []shortener.BatchItem) []shortener.BatchResult
+	getFunc      func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error)
+	listFunc     func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error)
+	exportFunc   func(ctx context.Context, fn func(shortener.URLItem) error) error
+	updateFunc   func(ctx context.Context, shortCode, newURL string) error
+	deleteFunc   func(ctx context.Context, shortCode string) (bool, error)
+	historyFunc  func(ctx context.Context, shortCode string) ([]shortener.Revision, error)
+	rollbackFunc func(ctx context.Context, shortCode string, revision int) (shortener.Revision, error)
+	purgeFunc    func(ctx context.Context, batchSize int) (int64, error)
+	clickFunc    func(ctx context.Context, shortCode string) error
+	statsFunc    func(ctx context.Context, shortCode string) (shortener.Stats, error)
+}
+
+func (m *mockedShortener) Add(ctx context.Context, url string, opts shortener.AddOptions) (shortener.AddResult, error) {
+	return m.addFunc(ctx, url, opts)
+}
+
+func (m *mockedShortener) AddBatch(ctx context.Context, items []shortener.BatchItem) []shortener.BatchResult {
+	return m.addBatchFunc(ctx, items)
+}
+
+func (m *mockedShortener) Get(ctx context.Context, code string, opts shortener.GetOptions) (string, error) {
+	return m.getFunc(ctx, code, opts)
+}
+
+func (m *mockedShortener) List(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+	return m.listFunc(ctx, opts)
+}
+
+func (m *mockedShortener) Export(ctx context.Context, fn func(shortener.URLItem) error) error {
+	return m.exportFunc(ctx, fn)
+}
+
+func (m *mockedShortener) Update(ctx context.Context, code, newURL string) error {
+	return m.updateFunc(ctx, code, newURL)
+}
+
+func (m *mockedShortener) Delete(ctx context.Context, code string) (bool, error) {
+	return m.deleteFunc(ctx, code)
+}
+
+func (m *mockedShortener) History(ctx context.Context, code string) ([]shortener.Revision, error) {
+	return m.historyFunc(ctx, code)
+}
+
+func (m *mockedShortener) Rollback(ctx context.Context, code string, revision int) (shortener.Revision, error) {
+	return m.rollbackFunc(ctx, code, revision)
+}
+
+func (m *mockedShortener) PurgeExpired(ctx context.Context, batchSize int) (int64, error) {
+	return m.purgeFunc(ctx, batchSize)
+}
+
+func (m *mockedShortener) RecordClick(ctx context.Context, code string) error {
+	return m.clickFunc(ctx, code)
+}
+
+func (m *mockedShortener) Stats(ctx context.Context, code string) (shortener.Stats, error) {
+	return m.statsFunc(ctx, code)
+}
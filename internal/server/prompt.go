@@ -0,0 +1,43 @@
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// promptPage asks for the password of a protected link. It posts back to
+// the link itself, so the password never appears in a URL.
+var promptPage = template.Must(template.New("prompt").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<main>
+<h1>Password required</h1>
+<p>The link <code>/{{.Code}}</code> is password protected.</p>
+{{if .Message}}<p role="alert">{{.Message}}</p>
+{{end}}<form method="post">
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+// writePasswordPrompt answers a request for a protected link with the prompt
+// page. message explains why the last attempt failed, if there was one.
+func writePasswordPrompt(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := promptPage.Execute(w, struct{ Code, Message string }{code, message}); err != nil {
+		log.Printf("password prompt %q: %v", code, err)
+	}
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/shortener"
+)
+
+const (
+	defaultResolveTimeout  = 5 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
+	readHeaderTimeout      = 5 * time.Second
+)
+
+type Server interface {
+	Serve(ctx context.Context, addrs Addrs) error
+}
+
+// Addrs are the addresses Serve listens on. Redirect is public. API serves
+// the management API, which has no authentication of its own, so it should
+// stay on a loopback or otherwise private address; it is not served when
+// empty. Debug serves expvar at /debug/vars, which reveals the process's
+// command line and memory stats, so it is only served when set and belongs on
+// loopback.
+type Addrs struct {
+	Redirect string
+	API      string
+	Debug    string
+}
+
+var _ Server = (*server)(nil)
+
+type server struct {
+	svc     shortener.URLShortener
+	acts    core.Actions
+	sweeper *shortener.Sweeper
+	mux     *http.ServeMux
+	api     *http.ServeMux
+	debug   *http.ServeMux
+}
+
+// New builds the HTTP server. When sweeper is non-nil it runs alongside the
+// server and stops with it.
+func New(svc shortener.URLShortener, acts core.Actions, sweeper *shortener.Sweeper) Server {
+	s := &server{svc: svc, acts: acts, sweeper: sweeper, mux: http.NewServeMux(), api: http.NewServeMux(), debug: http.NewServeMux()}
+	s.routes()
+	return s
+}
+
+func (s *server) routes() {
+	s.mux.HandleFunc("GET /{code}", s.handleRedirect)
+	s.mux.HandleFunc("POST /{code}", s.handleRedirect)
+
+	s.api.HandleFunc("POST /api/v1/urls", s.handleAdd)
+	s.api.HandleFunc("GET /api/v1/urls", s.handleList)
+	s.api.HandleFunc("GET /api/v1/urls/{code}", s.handleGet)
+	s.api.HandleFunc("DELETE /api/v1/urls/{code}", s.handleDelete)
+	s.api.HandleFunc("GET /api/v1/urls/{code}/stats", s.handleStats)
+
+	s.debug.Handle("GET /debug/vars", expvar.Handler())
+}
+
+// ServeHTTP serves the public redirects.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve listens on addrs until ctx is cancelled, then shuts every listener
+// down gracefully, giving in-flight requests a bounded amount of time to
+// finish. If any listener fails, the others are shut down too.
+func (s *server) Serve(ctx context.Context, addrs Addrs) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	listeners := []struct {
+		name    string
+		addr    string
+		handler http.Handler
+	}{
+		{"redirects", addrs.Redirect, s},
+		{"API", addrs.API, s.api},
+		{"debug variables", addrs.Debug, s.debug},
+	}
+
+	var servers []*http.Server
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		if l.addr == "" {
+			continue
+		}
+		httpServer := &http.Server{
+			Addr:              l.addr,
+			Handler:           l.handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+			// Requests keep ctx's values but not its cancellation, which
+			// starts the shutdown that in-flight requests are meant to
+			// finish within.
+			BaseContext: func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
+		}
+		servers = append(servers, httpServer)
+
+		go func(name string) {
+			log.Printf("Serving %s on %s", name, httpServer.Addr)
+			err := httpServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				err = fmt.Errorf("server: %s: %w", name, err)
+			}
+			errCh <- err
+		}(l.name)
+	}
+
+	if s.sweeper != nil {
+		go s.sweeper.Run(ctx)
+	}
+
+	var serveErr error
+	select {
+	case serveErr = <-errCh:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+	defer cancelShutdown()
+
+	log.Println("Shutting down server")
+	var errs []error
+	if serveErr != nil {
+		errs = append(errs, serveErr)
+	}
+	for _, httpServer := range servers {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("server: shutdown failed: %w", err))
+		}
+	}
+	// One result is already in hand when a listener failed first.
+	pending := len(servers)
+	if serveErr != nil {
+		pending--
+	}
+	for range pending {
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleRedirect sends the visitor on to the destination of a link. For a
+// password-protected link it shows the password prompt instead, which posts
+// the password back here.
+func (s *server) handleRedirect(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultResolveTimeout)
+	defer cancel()
+
+	code := r.PathValue("code")
+	var password string
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		password = r.PostFormValue("password")
+	}
+
+	originalURL, err := s.svc.Get(ctx, code, shortener.GetOptions{Password: password})
+	if err != nil {
+		switch {
+		case errors.Is(err, shortener.ErrShortCode), errors.Is(err, shortener.ErrNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, shortener.ErrPasswordRequired):
+			writePasswordPrompt(w, code, "")
+		case errors.Is(err, shortener.ErrWrongPassword):
+			writePasswordPrompt(w, code, "Wrong password. Please try again.")
+		default:
+			log.Printf("resolve %q: %v", code, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// A lost click must never cost the visitor their redirect.
+	if err := s.svc.RecordClick(ctx, code); err != nil {
+		log.Printf("record click %q: %v", code, err)
+	}
+
+	// After the prompt's POST, See Other makes the browser follow with a GET.
+	status := http.StatusFound
+	if r.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	http.Redirect(w, r, originalURL, status)
+}
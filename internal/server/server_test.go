@@ -0,0 +1,390 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/core"
+	"github.com/anewball/urlshortener/internal/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRedirect(t *testing.T) {
+	testCases := []struct {
+		name             string
+		method           string
+		path             string
+		form             url.Values
+		expectedStatus   int
+		expectedLocation string
+		expectedClicks   int
+		expectedBody     string
+		svc              shortener.URLShortener
+	}{
+		{
+			name:             "success",
+			method:           http.MethodGet,
+			path:             "/Hpa3t2B",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com",
+			expectedClicks:   1,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "https://example.com", nil
+				},
+			},
+		},
+		{
+			name:             "redirects even when recording the click fails",
+			method:           http.MethodGet,
+			path:             "/Hpa3t2B",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com",
+			expectedClicks:   1,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "https://example.com", nil
+				},
+				clickFunc: func(ctx context.Context, shortCode string) error {
+					return shortener.ErrExec
+				},
+			},
+		},
+		{
+			name:           "not found",
+			method:         http.MethodGet,
+			path:           "/Hpa3t2B",
+			expectedStatus: http.StatusNotFound,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", fmt.Errorf("%w: %s", shortener.ErrNotFound, shortCode)
+				},
+			},
+		},
+		{
+			name:           "empty short code",
+			method:         http.MethodGet,
+			path:           "/",
+			expectedStatus: http.StatusNotFound,
+			svc:            &mockedShortener{},
+		},
+		{
+			name:           "query error",
+			method:         http.MethodGet,
+			path:           "/Hpa3t2B",
+			expectedStatus: http.StatusInternalServerError,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", shortener.ErrQuery
+				},
+			},
+		},
+		{
+			name:           "password prompt",
+			method:         http.MethodGet,
+			path:           "/Hpa3t2B",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `<input id="password" name="password" type="password"`,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", fmt.Errorf("%w: %s", shortener.ErrPasswordRequired, shortCode)
+				},
+			},
+		},
+		{
+			name:           "wrong password prompts again",
+			method:         http.MethodPost,
+			path:           "/Hpa3t2B",
+			form:           url.Values{"password": {"guess"}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Wrong password",
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					return "", fmt.Errorf("%w: %s", shortener.ErrWrongPassword, shortCode)
+				},
+			},
+		},
+		{
+			name:             "password from the prompt",
+			method:           http.MethodPost,
+			path:             "/Hpa3t2B",
+			form:             url.Values{"password": {"s3cret"}},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "https://example.com",
+			expectedClicks:   1,
+			svc: &mockedShortener{
+				getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+					if opts.Password != "s3cret" {
+						return "", shortener.ErrWrongPassword
+					}
+					return "https://example.com", nil
+				},
+			},
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPut,
+			path:           "/Hpa3t2B",
+			expectedStatus: http.StatusMethodNotAllowed,
+			svc:            &mockedShortener{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clicks := 0
+			svc := tc.svc.(*mockedShortener)
+			recordClick := svc.clickFunc
+			svc.clickFunc = func(ctx context.Context, shortCode string) error {
+				clicks++
+				if recordClick != nil {
+					return recordClick(ctx, shortCode)
+				}
+				return nil
+			}
+
+			srv := New(svc, core.NewActions(svc, 20), nil).(*server)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.form.Encode()))
+			if tc.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedLocation, rec.Header().Get("Location"))
+			assert.Equal(t, tc.expectedClicks, clicks)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestDebugVarsIsNotServed(t *testing.T) {
+	srv := New(&mockedShortener{}, core.NewActions(&mockedShortener{}, 20), nil).(*server)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "cmdline")
+}
+
+func TestServe_APIHasItsOwnListener(t *testing.T) {
+	redirectAddr, apiAddr := freeAddr(t), freeAddr(t)
+	svc := &mockedShortener{
+		getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+			return "", shortener.ErrNotFound
+		},
+		listFunc: func(ctx context.Context, opts shortener.ListOptions) ([]shortener.URLItem, error) {
+			return []shortener.URLItem{}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(svc, core.NewActions(svc, 20), nil).Serve(ctx, Addrs{Redirect: redirectAddr, API: apiAddr})
+	}()
+	waitForListener(t, redirectAddr)
+	waitForListener(t, apiAddr)
+
+	for _, tc := range []struct {
+		addr, path string
+		status     int
+	}{
+		{redirectAddr, "/api/v1/urls?limit=10", http.StatusNotFound},
+		{redirectAddr, "/api/v1/urls/abc/stats", http.StatusNotFound},
+		{apiAddr, "/api/v1/urls?limit=10", http.StatusOK},
+		{apiAddr, "/abc", http.StatusNotFound},
+	} {
+		resp, err := http.Get("http://" + tc.addr + tc.path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tc.status, resp.StatusCode, tc.addr+tc.path)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, "http://"+redirectAddr+"/api/v1/urls/abc", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode, "links cannot be deleted through the public listener")
+
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestServe_DebugVarsOnItsOwnListener(t *testing.T) {
+	redirectAddr, debugAddr := freeAddr(t), freeAddr(t)
+	svc := &mockedShortener{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(svc, core.NewActions(svc, 20), nil).Serve(ctx, Addrs{Redirect: redirectAddr, Debug: debugAddr})
+	}()
+	waitForListener(t, redirectAddr)
+	waitForListener(t, debugAddr)
+
+	resp, err := http.Get("http://" + debugAddr + "/debug/vars")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"shortener_code_collisions"`)
+
+	resp, err = http.Get("http://" + redirectAddr + "/debug/vars")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "the public listener does not serve expvar")
+
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+// freeAddr returns a loopback address with a port that was free a moment ago.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestServe_ShutsDownWhenContextIsCancelled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(&mockedShortener{}, core.NewActions(&mockedShortener{}, 20), nil).Serve(ctx, Addrs{Redirect: addr})
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestServe_FinishesInFlightRequestsOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	started, release := make(chan struct{}), make(chan struct{})
+	svc := &mockedShortener{
+		getFunc: func(ctx context.Context, shortCode string, opts shortener.GetOptions) (string, error) {
+			close(started)
+			select {
+			case <-release:
+				return "https://example.com", nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		},
+		clickFunc: func(ctx context.Context, shortCode string) error { return nil },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(svc, core.NewActions(svc, 20), nil).Serve(ctx, Addrs{Redirect: addr})
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		resp, err := client.Get("http://" + addr + "/abc")
+		if err != nil {
+			respCh <- nil
+			return
+		}
+		resp.Body.Close()
+		respCh <- resp
+	}()
+
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	resp := <-respCh
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://example.com", resp.Header.Get("Location"))
+
+	select {
+	case err := <-errCh:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+func TestServe_ReturnsListenError(t *testing.T) {
+	err := New(&mockedShortener{}, core.NewActions(&mockedShortener{}, 20), nil).Serve(context.Background(), Addrs{Redirect: "invalid-address"})
+
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, http.ErrServerClosed))
+}
@@ -0,0 +1,134 @@
+package shortener
+
+import (
+	"cmp"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultPorts are the ports a scheme implies, dropped from canonical URLs.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalize rewrites rawURL into the form links are stored and
+// deduplicated under, so spellings of the same destination share one code:
+//
+//   - the scheme and host are lowercased and an internationalized host is
+//     converted to punycode
+//   - the port is dropped when it is the scheme's default
+//   - an empty path becomes "/"
+//   - percent-escapes of unreserved characters are decoded and all other
+//     escapes use uppercase hex
+//   - with sortQuery, query parameters are ordered by name; values of a
+//     repeated name keep their order
+//
+// The user info and fragment are left as they are. rawURL must already have
+// passed isValidURL.
+func Canonicalize(rawURL string, sortQuery bool) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return empty, err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, err := asciiHost(u.Hostname())
+	if err != nil {
+		return empty, err
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = empty
+	}
+	switch {
+	case port != empty:
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	path := normalizeEscapes(u.EscapedPath())
+	if path == empty {
+		path = "/"
+	}
+	if u.Path, err = url.PathUnescape(path); err != nil {
+		return empty, err
+	}
+	u.RawPath = path
+
+	query := normalizeEscapes(u.RawQuery)
+	if sortQuery && query != empty {
+		query = sortQueryParams(query)
+	}
+	u.RawQuery = query
+
+	return u.String(), nil
+}
+
+// normalizeEscapes decodes percent-escapes of unreserved characters (RFC
+// 3986, section 2.3) and uppercases the hex digits of the rest. Malformed
+// escapes are copied unchanged.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// sortQueryParams orders the parameters of an encoded query by name. The
+// sort is stable, as the order of repeated names can be meaningful.
+func sortQueryParams(query string) string {
+	params := strings.Split(query, "&")
+	slices.SortStableFunc(params, func(a, b string) int {
+		return cmp.Compare(paramName(a), paramName(b))
+	})
+	return strings.Join(params, "&")
+}
+
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	return name
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
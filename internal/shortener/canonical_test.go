@@ -0,0 +1,53 @@
+package shortener
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	testCases := []struct {
+		name      string
+		rawURL    string
+		sortQuery bool
+		expected  string
+	}{
+		{name: "already canonical", rawURL: "https://example.com/a?b=1", expected: "https://example.com/a?b=1"},
+		{name: "uppercase scheme and host", rawURL: "HTTPS://Example.COM/Path", expected: "https://example.com/Path"},
+		{name: "empty path", rawURL: "https://example.com", expected: "https://example.com/"},
+		{name: "empty path with query", rawURL: "https://example.com?q=1", expected: "https://example.com/?q=1"},
+		{name: "default https port", rawURL: "https://example.com:443/", expected: "https://example.com/"},
+		{name: "default http port", rawURL: "http://example.com:80/a", expected: "http://example.com/a"},
+		{name: "other port kept", rawURL: "http://example.com:8080/a", expected: "http://example.com:8080/a"},
+		{name: "port of the other scheme kept", rawURL: "http://example.com:443/", expected: "http://example.com:443/"},
+		{name: "internationalized host", rawURL: "https://Bücher.example:443", expected: "https://xn--bcher-kva.example/"},
+		{name: "ipv6 host", rawURL: "https://[2001:DB8::1]:443/", expected: "https://[2001:db8::1]/"},
+		{name: "surrounding space", rawURL: "  https://example.com/a  ", expected: "https://example.com/a"},
+		{name: "unreserved escapes decoded", rawURL: "https://example.com/%7Euser/%61b", expected: "https://example.com/~user/ab"},
+		{name: "reserved escapes uppercased", rawURL: "https://example.com/a%2fb?q=a%2bb", expected: "https://example.com/a%2Fb?q=a%2Bb"},
+		{name: "query order kept by default", rawURL: "https://example.com/?b=2&a=1", expected: "https://example.com/?b=2&a=1"},
+		{name: "query sorted", rawURL: "https://example.com/?b=2&a=1&b=1", sortQuery: true, expected: "https://example.com/?a=1&b=2&b=1"},
+		{name: "fragment kept", rawURL: "https://Example.com#Top", expected: "https://example.com/#Top"},
+		{name: "user info kept", rawURL: "https://User@Example.com/", expected: "https://User@example.com/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := Canonicalize(tc.rawURL, tc.sortQuery)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCanonicalize_SpellingsShareOneForm(t *testing.T) {
+	spellings := []string{"HTTPS://Example.com/", "https://example.com", "https://example.com:443/"}
+	for _, s := range spellings {
+		actual, err := Canonicalize(s, false)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/", actual, s)
+	}
+}
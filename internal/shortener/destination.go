@@ -0,0 +1,153 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+var ErrPrivateDestination = errors.New("URL points to a private, loopback or link-local address")
+
+// internalSuffixes are DNS suffixes reserved for, or conventionally used by,
+// hosts that are only reachable inside a network. Public resolvers never
+// answer for them, so a link to one is only useful to reach internal hosts.
+var internalSuffixes = []string{
+	"localhost",
+	"local",
+	"localdomain",
+	"internal",
+	"intranet",
+	"lan",
+	"home.arpa",
+}
+
+// checkDestination rejects a host that is a loopback, private, link-local or
+// unspecified address, or a name under internalSuffixes. It does not resolve
+// names through DNS: a public name pointing at a private address passes, as
+// its records can change after the link is saved anyway.
+func checkDestination(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if addr, ok := parseHostAddr(host); ok {
+		if isInternalAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateDestination, host)
+		}
+		return nil
+	}
+
+	for _, suffix := range internalSuffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return fmt.Errorf("%w: %s", ErrPrivateDestination, host)
+		}
+	}
+	return nil
+}
+
+// internalPrefixes are IPv4 ranges the netip predicates do not cover but
+// that never lead to a host on the public internet.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // shared address space; some clouds serve metadata here
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+// Ranges of IPv6 addresses that carry an IPv4 address in their low 32 bits.
+var (
+	nat64Prefix      = netip.MustParsePrefix("64:ff9b::/96")
+	nat64LocalPrefix = netip.MustParsePrefix("64:ff9b:1::/48")
+	compatPrefix     = netip.MustParsePrefix("::/96")
+)
+
+func isInternalAddr(addr netip.Addr) bool {
+	addr = unwrapIPv4(addr)
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+	for _, p := range internalPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapIPv4 returns the IPv4 address embedded in an IPv4-mapped,
+// IPv4-compatible or NAT64 address, so it is judged by where it really
+// leads. Any other address is returned unchanged.
+func unwrapIPv4(addr netip.Addr) netip.Addr {
+	addr = addr.Unmap()
+	if !addr.Is6() || addr.IsLoopback() || addr.IsUnspecified() {
+		return addr
+	}
+	if nat64Prefix.Contains(addr) || nat64LocalPrefix.Contains(addr) || compatPrefix.Contains(addr) {
+		b := addr.As16()
+		return netip.AddrFrom4([4]byte(b[12:]))
+	}
+	return addr
+}
+
+// parseHostAddr parses host as an IP address. Besides the usual forms it
+// accepts the shorthand IPv4 notations browsers still resolve, such as
+// 2130706433, 0x7f.1 or 0177.0.0.1 for 127.0.0.1, which would otherwise
+// slip past as names.
+func parseHostAddr(host string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return addr.WithZone(""), true
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+	nums := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, ok := parseIPv4Part(p)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		nums[i] = n
+	}
+
+	// The last part fills every byte the earlier ones leave.
+	last := len(nums) - 1
+	var ip uint64
+	for i, n := range nums[:last] {
+		if n > 0xff {
+			return netip.Addr{}, false
+		}
+		ip |= n << (8 * (3 - i))
+	}
+	if nums[last] >= 1<<(8*(4-last)) {
+		return netip.Addr{}, false
+	}
+	ip |= nums[last]
+
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}), true
+}
+
+// parseIPv4Part parses one part of an IPv4 address as inet_aton does: hex
+// with a 0x prefix, octal with a leading 0 and decimal otherwise.
+func parseIPv4Part(p string) (uint64, bool) {
+	base := 10
+	switch {
+	case strings.HasPrefix(p, "0x"):
+		p, base = p[2:], 16
+		if p == "" {
+			return 0, true
+		}
+	case len(p) > 1 && p[0] == '0':
+		p, base = p[1:], 8
+	}
+	if p == "" || strings.ContainsAny(p, "+-_") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(p, base, 32)
+	return n, err == nil
+}
@@ -0,0 +1,100 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDestination(t *testing.T) {
+	testCases := []struct {
+		name        string
+		host        string
+		expectedErr error
+	}{
+		{name: "public name", host: "example.com"},
+		{name: "public ipv4", host: "93.184.216.34"},
+		{name: "public ipv6", host: "2606:2800:220:1:248:1893:25c8:1946"},
+		{name: "name ending like a suffix", host: "mylocal.example"},
+		{name: "loopback", host: "127.0.0.1", expectedErr: ErrPrivateDestination},
+		{name: "loopback range", host: "127.8.9.10", expectedErr: ErrPrivateDestination},
+		{name: "private 10/8", host: "10.0.0.5", expectedErr: ErrPrivateDestination},
+		{name: "private 172.16/12", host: "172.20.1.1", expectedErr: ErrPrivateDestination},
+		{name: "private 192.168/16", host: "192.168.1.1", expectedErr: ErrPrivateDestination},
+		{name: "link-local metadata", host: "169.254.169.254", expectedErr: ErrPrivateDestination},
+		{name: "unspecified", host: "0.0.0.0", expectedErr: ErrPrivateDestination},
+		{name: "ipv6 loopback", host: "::1", expectedErr: ErrPrivateDestination},
+		{name: "ipv6 link-local", host: "fe80::1", expectedErr: ErrPrivateDestination},
+		{name: "ipv6 unique local", host: "fd00::1", expectedErr: ErrPrivateDestination},
+		{name: "ipv4-mapped ipv6", host: "::ffff:10.0.0.1", expectedErr: ErrPrivateDestination},
+		{name: "this network", host: "0.1.2.3", expectedErr: ErrPrivateDestination},
+		{name: "shared address space", host: "100.100.100.200", expectedErr: ErrPrivateDestination},
+		{name: "benchmarking", host: "198.18.0.1", expectedErr: ErrPrivateDestination},
+		{name: "nat64 link-local", host: "[64:ff9b::a9fe:a9fe]", expectedErr: ErrPrivateDestination},
+		{name: "nat64 private", host: "64:ff9b::10.0.0.1", expectedErr: ErrPrivateDestination},
+		{name: "local nat64 loopback", host: "64:ff9b:1::7f00:1", expectedErr: ErrPrivateDestination},
+		{name: "ipv4-compatible ipv6", host: "::127.0.0.1", expectedErr: ErrPrivateDestination},
+		{name: "nat64 public", host: "64:ff9b::5db8:d822"},
+		{name: "decimal ipv4", host: "2130706433", expectedErr: ErrPrivateDestination},
+		{name: "hex ipv4", host: "0x7f.1", expectedErr: ErrPrivateDestination},
+		{name: "octal ipv4", host: "0177.0.0.1", expectedErr: ErrPrivateDestination},
+		{name: "short ipv4", host: "10.1", expectedErr: ErrPrivateDestination},
+		{name: "localhost", host: "localhost", expectedErr: ErrPrivateDestination},
+		{name: "localhost subdomain", host: "api.localhost", expectedErr: ErrPrivateDestination},
+		{name: "trailing dot", host: "LOCALHOST.", expectedErr: ErrPrivateDestination},
+		{name: "mdns", host: "printer.local", expectedErr: ErrPrivateDestination},
+		{name: "cloud metadata name", host: "metadata.google.internal", expectedErr: ErrPrivateDestination},
+		{name: "home network", host: "nas.home.arpa", expectedErr: ErrPrivateDestination},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkDestination(tc.host)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseHostAddr_RejectsNonAddresses(t *testing.T) {
+	for _, host := range []string{"example.com", "1.2.3.4.5", "256.1.1.1", "1.2.3.256", "1_0.0.0.1", "+1.2.3.4", "08.1.1.1"} {
+		_, ok := parseHostAddr(host)
+		assert.False(t, ok, host)
+	}
+}
+
+func TestAddAndUpdate_RejectPrivateDestinations(t *testing.T) {
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			return &mockRow{result: []any{args[1], nil, true}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+
+	service, err := New(querier, gen)
+	require.NoError(t, err)
+
+	for _, rawURL := range []string{"http://127.0.0.1", "http://169.254.169.254/latest/meta-data/", "http://10.0.0.5:8080/admin", "http://[::1]/", "http://[64:ff9b::a9fe:a9fe]/", "http://100.100.100.200/", "http://localhost:3000"} {
+		_, err := service.Add(context.Background(), rawURL, AddOptions{})
+		assert.ErrorIs(t, err, ErrPrivateDestination, rawURL)
+		assert.NotErrorIs(t, err, ErrIsValidURL, rawURL)
+	}
+
+	err = service.Update(context.Background(), "abc1234", "http://192.168.0.1/")
+	assert.ErrorIs(t, err, ErrPrivateDestination)
+
+	results := service.AddBatch(context.Background(), []BatchItem{{URL: "http://10.0.0.5"}})
+	assert.ErrorIs(t, results[0].Err, ErrPrivateDestination)
+
+	permissive, err := New(querier, gen, WithDestinationCheck(false))
+	require.NoError(t, err)
+	_, err = permissive.Add(context.Background(), "http://127.0.0.1", AddOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,139 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrIDN         = errors.New("host is not a valid internationalized domain name")
+	ErrMixedScript = errors.New("host mixes scripts in a way typical of lookalike domains")
+)
+
+// idnProfile converts hosts the way browsers look them up, but still allows
+// underscores, which some real hostnames carry.
+var idnProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+)
+
+// asciiHost returns the punycode form of host, lowercased. IP addresses and
+// hosts that are already ASCII only have their case folded.
+func asciiHost(host string) (string, error) {
+	if net.ParseIP(host) != nil || isASCII(host) {
+		return strings.ToLower(host), nil
+	}
+	ascii, err := idnProfile.ToASCII(host)
+	if err != nil {
+		return empty, fmt.Errorf("%w: %v", ErrIDN, err)
+	}
+	return ascii, nil
+}
+
+// DisplayURL returns rawURL with a punycode host shown in Unicode, for
+// people to read. Anything it cannot convert is returned unchanged.
+func DisplayURL(rawURL string) string {
+	if !strings.Contains(rawURL, "xn--") {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host := u.Hostname()
+	unicodeHost, err := idna.Display.ToUnicode(host)
+	if err != nil || unicodeHost == host {
+		return rawURL
+	}
+
+	// url.URL.String would percent-encode the Unicode host, so splice it
+	// into rawURL instead. The host follows the scheme and any user info.
+	start := strings.Index(rawURL, "://") + len("://")
+	authority := rawURL[start:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	start += strings.LastIndex(authority, "@") + 1
+	if !strings.EqualFold(rawURL[start:start+len(host)], host) {
+		return rawURL
+	}
+	return rawURL[:start] + unicodeHost + rawURL[start+len(host):]
+}
+
+// allowedScriptMixes are the combinations of scripts a label may use, as in
+// the "highly restrictive" profile of Unicode TS #39: Japanese, Chinese and
+// Korean routinely mix Han with their own scripts and with Latin.
+var allowedScriptMixes = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// checkScripts rejects a host any label of which mixes the letters of
+// several scripts, such as a Cyrillic "а" among Latin ones, unless
+// allowedScriptMixes permits the combination.
+func checkScripts(host string) error {
+	unicodeHost, err := idna.Display.ToUnicode(host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIDN, err)
+	}
+
+	for _, label := range strings.Split(unicodeHost, ".") {
+		scripts := labelScripts(label)
+		if len(scripts) > 1 && !allowedMix(scripts) {
+			return fmt.Errorf("%w: %q uses %s", ErrMixedScript, label, strings.Join(scripts, ", "))
+		}
+	}
+	return nil
+}
+
+// labelScripts names the scripts of the letters in label, in order of first
+// use. Digits, hyphens and combining marks belong to no script of their own.
+func labelScripts(label string) []string {
+	var scripts []string
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if name := scriptOf(r); name != empty && !slices.Contains(scripts, name) {
+			scripts = append(scripts, name)
+		}
+	}
+	return scripts
+}
+
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name
+		}
+	}
+	return empty
+}
+
+func allowedMix(scripts []string) bool {
+	for _, mix := range allowedScriptMixes {
+		if !slices.ContainsFunc(scripts, func(s string) bool { return !slices.Contains(mix, s) }) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsciiHost(t *testing.T) {
+	testCases := []struct {
+		name        string
+		host        string
+		expected    string
+		expectedErr error
+	}{
+		{name: "ascii", host: "Example.COM", expected: "example.com"},
+		{name: "unicode", host: "bücher.example", expected: "xn--bcher-kva.example"},
+		{name: "unicode uppercase", host: "BÜCHER.example", expected: "xn--bcher-kva.example"},
+		{name: "punycode kept", host: "xn--bcher-kva.example", expected: "xn--bcher-kva.example"},
+		{name: "ipv6", host: "2001:DB8::1", expected: "2001:db8::1"},
+		{name: "underscore allowed", host: "my_host.example", expected: "my_host.example"},
+		{name: "invalid", host: "a\u200d.例", expectedErr: ErrIDN},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := asciiHost(tc.host)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDisplayURL(t *testing.T) {
+	assert.Equal(t, "https://bücher.example/a?q=1", DisplayURL("https://xn--bcher-kva.example/a?q=1"))
+	assert.Equal(t, "https://bücher.example:8443/", DisplayURL("https://xn--bcher-kva.example:8443/"))
+	assert.Equal(t, "https://example.com/xn--a", DisplayURL("https://example.com/xn--a"))
+	assert.Equal(t, "https://user@bücher.example/", DisplayURL("https://user@xn--bcher-kva.example/"))
+	assert.Equal(t, "https://example.com/", DisplayURL("https://example.com/"))
+}
+
+func TestCheckScripts(t *testing.T) {
+	testCases := []struct {
+		name        string
+		host        string
+		expectedErr error
+	}{
+		{name: "latin", host: "example.com"},
+		{name: "latin with accents", host: "bücher.example"},
+		{name: "cyrillic", host: "пример.рф"},
+		{name: "scripts in separate labels", host: "пример.example"},
+		{name: "japanese", host: "ひらがなカタカナ漢字.jp"},
+		{name: "korean with han", host: "한국漢字.kr"},
+		{name: "latin with han", host: "abc中文.cn"},
+		{name: "cyrillic a among latin", host: "pаypal.com", expectedErr: ErrMixedScript},
+		{name: "punycode of a mix", host: "xn--pypal-4ve.com", expectedErr: ErrMixedScript},
+		{name: "greek among latin", host: "gοogle.com", expectedErr: ErrMixedScript},
+		{name: "hangul with hiragana", host: "한국ひらがな.kr", expectedErr: ErrMixedScript},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkScripts(tc.host)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAdd_IDN(t *testing.T) {
+	var gotURL any
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			gotURL = args[0]
+			return &mockRow{result: []any{args[1], nil, true}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+
+	service, err := New(querier, gen)
+	require.NoError(t, err)
+	res, err := service.Add(context.Background(), "https://Bücher.example/katalog", AddOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://xn--bcher-kva.example/katalog", res.URL)
+	assert.Equal(t, "https://xn--bcher-kva.example/katalog", gotURL, "stored in punycode")
+
+	res, err = service.Add(context.Background(), "https://pаypal.com", AddOptions{})
+	require.NoError(t, err, "mixed scripts pass unless the check is on")
+	assert.Equal(t, "https://xn--pypal-4ve.com/", res.URL)
+
+	strict, err := New(querier, gen, WithMixedScriptCheck(true))
+	require.NoError(t, err)
+	_, err = strict.Add(context.Background(), "https://pаypal.com", AddOptions{})
+	assert.ErrorIs(t, err, ErrIsValidURL)
+	assert.ErrorIs(t, err, ErrMixedScript)
+
+	_, err = strict.Add(context.Background(), "https://bücher.example", AddOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,209 @@
+package shortener
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCursor = errors.New("invalid cursor")
+	ErrSort   = errors.New("invalid sort")
+)
+
+// SortField names the key List orders links by. Ties are broken by ID in the
+// same direction, so every order is total and cursors never skip a link.
+type SortField string
+
+const (
+	SortCreated SortField = "created"
+	SortCode    SortField = "code"
+	SortURL     SortField = "url"
+)
+
+// SortFields lists every supported SortField.
+var SortFields = []SortField{SortCreated, SortCode, SortURL}
+
+// ListOptions selects a page of links. A page starts either Offset links in
+// or, when After is set, right after the link After points at. After is
+// stable while links are added; Offset is not.
+//
+// The zero value lists unexpired links newest first.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+	Filter ListFilter
+	// Sort defaults to SortCreated. Links come largest first unless
+	// Ascending is set.
+	Sort      SortField
+	Ascending bool
+}
+
+// ListFilter narrows List. Empty fields match every link.
+type ListFilter struct {
+	// Host matches links whose destination host is Host or a subdomain of
+	// it, ignoring case.
+	Host string
+	// Contains matches links whose destination contains it, ignoring case.
+	Contains string
+	// Since and Until bound the creation time, both inclusive.
+	Since *time.Time
+	Until *time.Time
+	// IncludeExpired lists expired links alongside live ones.
+	IncludeExpired bool
+}
+
+func (o ListOptions) sortField() SortField {
+	if o.Sort == "" {
+		return SortCreated
+	}
+	return o.Sort
+}
+
+func (o ListOptions) validate() error {
+	sort := o.sortField()
+	switch sort {
+	case SortCreated, SortCode, SortURL:
+	default:
+		return fmt.Errorf("%w: %q", ErrSort, o.Sort)
+	}
+
+	if c := o.After; c != nil && (c.Sort != sort || c.Ascending != o.Ascending) {
+		return fmt.Errorf("%w: cursor belongs to a listing in a different order", ErrCursor)
+	}
+	return nil
+}
+
+// Compare orders a and b the way List does: negative when a comes first.
+func (o ListOptions) Compare(a, b URLItem) int {
+	var n int
+	switch o.sortField() {
+	case SortCode:
+		n = strings.Compare(a.ShortCode, b.ShortCode)
+	case SortURL:
+		n = strings.Compare(a.OriginalURL, b.OriginalURL)
+	default:
+		n = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if n == 0 {
+		n = cmp.Compare(a.ID, b.ID)
+	}
+	if !o.Ascending {
+		n = -n
+	}
+	return n
+}
+
+// Includes reports whether item passes the filter at time now and, when
+// After is set, comes after the cursor. Stores that cannot push the filter
+// into a query use it to filter in memory.
+func (o ListOptions) Includes(item URLItem, now time.Time) bool {
+	f := o.Filter
+	if !f.IncludeExpired && item.ExpiresAt != nil && !item.ExpiresAt.After(now) {
+		return false
+	}
+	if f.Since != nil && item.CreatedAt.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && item.CreatedAt.After(*f.Until) {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(strings.ToLower(item.OriginalURL), strings.ToLower(f.Contains)) {
+		return false
+	}
+	if f.Host != "" && !hostMatches(item.OriginalURL, f.Host) {
+		return false
+	}
+	if o.After != nil && o.Compare(o.After.item(), item) >= 0 {
+		return false
+	}
+	return true
+}
+
+func hostMatches(rawURL, host string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	h, want := strings.ToLower(u.Hostname()), strings.ToLower(host)
+	return h == want || strings.HasSuffix(h, "."+want)
+}
+
+// Cursor marks a position in a List order. It records the order it was
+// taken from, since a position is meaningless in any other.
+type Cursor struct {
+	Sort      SortField
+	Ascending bool
+	CreatedAt time.Time
+	// Value is the short code of the link when sorting by code. When sorting
+	// by URL it is left out of the token, which would otherwise reveal the
+	// destination of a password-protected link; stores look the URL up by
+	// ID instead, and a listing whose cursor link was deleted ends there.
+	Value string
+	ID    uint64
+}
+
+// CursorFor returns the cursor that resumes a listing in the order of opts
+// right after item.
+func CursorFor(item URLItem, opts ListOptions) Cursor {
+	c := Cursor{Sort: opts.sortField(), Ascending: opts.Ascending, CreatedAt: item.CreatedAt, ID: item.ID}
+	if c.Sort == SortCode {
+		c.Value = item.ShortCode
+	}
+	return c
+}
+
+func (c Cursor) item() URLItem {
+	return URLItem{ID: c.ID, CreatedAt: c.CreatedAt, ShortCode: c.Value, OriginalURL: c.Value}
+}
+
+// String encodes c as an opaque token safe to pass in URLs and flags.
+func (c Cursor) String() string {
+	dir := "d"
+	if c.Ascending {
+		dir = "a"
+	}
+	raw := strings.Join([]string{
+		string(c.Sort),
+		dir,
+		strconv.FormatInt(c.CreatedAt.UnixNano(), 10),
+		strconv.FormatUint(c.ID, 10),
+		c.Value, // last, as a URL may contain the separator
+	}, ".")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token produced by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrCursor, err)
+	}
+
+	parts := strings.SplitN(string(raw), ".", 5)
+	if len(parts) != 5 || (parts[1] != "a" && parts[1] != "d") {
+		return Cursor{}, fmt.Errorf("%w: malformed token", ErrCursor)
+	}
+	nanos, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed token", ErrCursor)
+	}
+	id, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed token", ErrCursor)
+	}
+
+	return Cursor{
+		Sort:      SortField(parts[0]),
+		Ascending: parts[1] == "a",
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		Value:     parts[4],
+		ID:        id,
+	}, nil
+}
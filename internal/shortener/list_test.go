@@ -0,0 +1,187 @@
+package shortener
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	item := URLItem{ID: 42, OriginalURL: "https://example.com/a.b?c=d.e", ShortCode: "GL9VeCa", CreatedAt: time.Date(2025, 8, 20, 12, 0, 0, 123456789, time.UTC)}
+
+	testCases := []struct {
+		name     string
+		opts     ListOptions
+		expected Cursor
+	}{
+		{
+			name:     "default order",
+			opts:     ListOptions{},
+			expected: Cursor{Sort: SortCreated, CreatedAt: item.CreatedAt, ID: 42},
+		},
+		{
+			name:     "by url ascending",
+			opts:     ListOptions{Sort: SortURL, Ascending: true},
+			expected: Cursor{Sort: SortURL, Ascending: true, CreatedAt: item.CreatedAt, ID: 42},
+		},
+		{
+			name:     "by code",
+			opts:     ListOptions{Sort: SortCode},
+			expected: Cursor{Sort: SortCode, CreatedAt: item.CreatedAt, Value: item.ShortCode, ID: 42},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := CursorFor(item, tc.opts)
+			assert.Equal(t, tc.expected, c)
+
+			parsed, err := ParseCursor(c.String())
+			require.NoError(t, err)
+			assert.Equal(t, c, parsed)
+		})
+	}
+}
+
+func TestParseCursor_Errors(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "***"},
+		{name: "too few parts", token: encode("created.d.12345")},
+		{name: "bad direction", token: encode("created.x.1.1.")},
+		{name: "bad time", token: encode("created.d.x.1.")},
+		{name: "bad id", token: encode("created.d.1.-1.")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCursor(tc.token)
+			assert.ErrorIs(t, err, ErrCursor)
+		})
+	}
+}
+
+func TestListOptions_Includes(t *testing.T) {
+	now := time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC)
+	past, since, until := now.Add(-time.Hour), now.Add(-48*time.Hour), now.Add(-24*time.Hour)
+	item := URLItem{ID: 5, OriginalURL: "https://Docs.Example.com/Guide", ShortCode: "GL9VeCa", CreatedAt: now.Add(-36 * time.Hour)}
+	expired := item
+	expired.ExpiresAt = &past
+
+	testCases := []struct {
+		name     string
+		opts     ListOptions
+		item     URLItem
+		expected bool
+	}{
+		{name: "no filter", item: item, expected: true},
+		{name: "expired hidden", item: expired, expected: false},
+		{name: "expired included", opts: ListOptions{Filter: ListFilter{IncludeExpired: true}}, item: expired, expected: true},
+		{name: "exact host", opts: ListOptions{Filter: ListFilter{Host: "docs.example.com"}}, item: item, expected: true},
+		{name: "parent host", opts: ListOptions{Filter: ListFilter{Host: "EXAMPLE.com"}}, item: item, expected: true},
+		{name: "host suffix is not a subdomain", opts: ListOptions{Filter: ListFilter{Host: "ample.com"}}, item: item, expected: false},
+		{name: "contains ignores case", opts: ListOptions{Filter: ListFilter{Contains: "guide"}}, item: item, expected: true},
+		{name: "contains misses", opts: ListOptions{Filter: ListFilter{Contains: "blog"}}, item: item, expected: false},
+		{name: "inside range", opts: ListOptions{Filter: ListFilter{Since: &since, Until: &until}}, item: item, expected: true},
+		{name: "before since", opts: ListOptions{Filter: ListFilter{Since: &until}}, item: item, expected: false},
+		{name: "after until", opts: ListOptions{Filter: ListFilter{Until: &since}}, item: item, expected: false},
+		{name: "after cursor", opts: ListOptions{After: &Cursor{Sort: SortCreated, CreatedAt: now, ID: 9}}, item: item, expected: true},
+		{name: "at cursor", opts: ListOptions{After: &Cursor{Sort: SortCreated, CreatedAt: item.CreatedAt, ID: 5}}, item: item, expected: false},
+		{name: "before cursor ascending", opts: ListOptions{Sort: SortCode, Ascending: true, After: &Cursor{Sort: SortCode, Ascending: true, Value: "ZZZ"}}, item: item, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.opts.Includes(tc.item, now))
+		})
+	}
+}
+
+func TestList_Validation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        ListOptions
+		expectedErr error
+	}{
+		{name: "unknown sort", opts: ListOptions{Limit: 1, Sort: "clicks"}, expectedErr: ErrSort},
+		{name: "cursor from another sort", opts: ListOptions{Limit: 1, Sort: SortCode, After: &Cursor{Sort: SortCreated}}, expectedErr: ErrCursor},
+		{name: "cursor from another direction", opts: ListOptions{Limit: 1, After: &Cursor{Sort: SortCreated, Ascending: true}}, expectedErr: ErrCursor},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			service, _ := New(&mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					called = true
+					return &mockRows{}, nil
+				},
+			}, &mockNanoID{})
+
+			_, err := service.List(context.Background(), tc.opts)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.False(t, called, "invalid options never reach the store")
+		})
+	}
+}
+
+func TestListQuery(t *testing.T) {
+	since := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		opts         ListOptions
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			name:         "defaults",
+			opts:         ListOptions{Limit: 10, Offset: 20},
+			expectedSQL:  ListQuery + " WHERE (expires_at IS NULL OR expires_at > now()) ORDER BY created_at DESC, id DESC LIMIT $1::int OFFSET $2::int;",
+			expectedArgs: []any{10, 20},
+		},
+		{
+			name: "every filter",
+			opts: ListOptions{
+				Limit:  5,
+				Filter: ListFilter{Host: "Example.com", Contains: "'; DROP TABLE url; --", Since: &since, Until: &created, IncludeExpired: true},
+				Sort:   SortCode, Ascending: true,
+			},
+			expectedSQL: ListQuery + " WHERE (" + urlHost + " = $1::text OR right(" + urlHost + ", length($1::text) + 1) = '.' || $1::text)" +
+				" AND strpos(lower(original_url), lower($2::text)) > 0" +
+				" AND created_at >= $3::timestamptz AND created_at <= $4::timestamptz" +
+				" ORDER BY short_code ASC, id ASC LIMIT $5::int OFFSET $6::int;",
+			expectedArgs: []any{"example.com", "'; DROP TABLE url; --", since, created, 5, 0},
+		},
+		{
+			name:         "cursor by created",
+			opts:         ListOptions{Limit: 10, After: &Cursor{Sort: SortCreated, CreatedAt: created, ID: 7}},
+			expectedSQL:  ListQuery + " WHERE (expires_at IS NULL OR expires_at > now()) AND (created_at, id) < ($1::timestamptz, $2::bigint) ORDER BY created_at DESC, id DESC LIMIT $3::int;",
+			expectedArgs: []any{created, uint64(7), 10},
+		},
+		{
+			name:         "cursor by url ascending",
+			opts:         ListOptions{Limit: 10, Sort: SortURL, Ascending: true, After: &Cursor{Sort: SortURL, Ascending: true, ID: 7}},
+			expectedSQL:  ListQuery + " WHERE (expires_at IS NULL OR expires_at > now()) AND (original_url, id) > ((SELECT original_url FROM url WHERE id = $1::bigint), $2::bigint) ORDER BY original_url ASC, id ASC LIMIT $3::int;",
+			expectedArgs: []any{uint64(7), uint64(7), 10},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sql, args := listQuery(tc.opts)
+			assert.Equal(t, tc.expectedSQL, sql)
+			assert.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
@@ -43,12 +43,31 @@ func (m *mockRow) Scan(dest ...any) error {
 	}
 
 	for i := range dest {
+		if i >= len(m.result) {
+			break
+		}
 		v := m.result[i]
 		switch d := dest[i].(type) {
 		case *string:
 			if s, ok := v.(string); ok {
 				*d = s
 			}
+		case *bool:
+			if b, ok := v.(bool); ok {
+				*d = b
+			}
+		case *int64:
+			if n, ok := v.(int64); ok {
+				*d = n
+			}
+		case *uint64:
+			if n, ok := v.(uint64); ok {
+				*d = n
+			}
+		case **time.Time: // nullable
+			if tt, ok := v.(*time.Time); ok {
+				*d = tt
+			}
 		}
 	}
 	return nil
@@ -88,6 +107,18 @@ func (m *mockRows) Scan(dest ...any) error {
 			case uint64:
 				*d = x
 			}
+		case *int64:
+			if n, ok := v.(int64); ok {
+				*d = n
+			}
+		case *int:
+			if n, ok := v.(int); ok {
+				*d = n
+			}
+		case *bool:
+			if b, ok := v.(bool); ok {
+				*d = b
+			}
 		case *time.Time:
 			if tt, ok := v.(time.Time); ok {
 				*d = tt
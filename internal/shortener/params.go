@@ -0,0 +1,131 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+var ErrParamPolicy = errors.New("invalid query parameter policy")
+
+// ParamMode decides which query parameters Add keeps.
+type ParamMode string
+
+const (
+	// ParamsKeep keeps every parameter.
+	ParamsKeep ParamMode = "keep"
+	// ParamsStrip removes the parameters matching the policy's patterns.
+	ParamsStrip ParamMode = "strip"
+	// ParamsAllow keeps only the parameters matching the policy's patterns.
+	ParamsAllow ParamMode = "allow"
+)
+
+// ParamModes lists every supported ParamMode.
+var ParamModes = []ParamMode{ParamsKeep, ParamsStrip, ParamsAllow}
+
+// TrackingParams are the patterns ParamsStrip uses when a policy names none:
+// analytics and ad-click identifiers that never change what a page shows.
+var TrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"gbraid",
+	"wbraid",
+	"msclkid",
+	"yclid",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_gl",
+}
+
+// ParamPolicy selects the query parameters Add drops before a URL is
+// canonicalized, so links differing only in tracking noise share a code.
+// Patterns are matched against parameter names, ignoring case, with
+// path.Match syntax such as "utm_*". The zero value keeps everything.
+type ParamPolicy struct {
+	Mode     ParamMode
+	Patterns []string
+}
+
+// Validate reports an unknown mode or a malformed pattern.
+func (p ParamPolicy) Validate() error {
+	switch p.Mode {
+	case "", ParamsKeep, ParamsStrip, ParamsAllow:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrParamPolicy, p.Mode)
+	}
+	for _, pattern := range p.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: pattern %q: %v", ErrParamPolicy, pattern, err)
+		}
+	}
+	return nil
+}
+
+func (p ParamPolicy) patterns() []string {
+	if p.Mode == ParamsStrip && len(p.Patterns) == 0 {
+		return TrackingParams
+	}
+	return p.Patterns
+}
+
+// Apply removes the parameters the policy drops from rawURL and returns the
+// result with the names it removed, each once and in order of appearance.
+// Parameters matching a pattern in keep survive whatever the policy says.
+// rawURL is returned unchanged when nothing is removed.
+func (p ParamPolicy) Apply(rawURL string, keep []string) (string, []string, error) {
+	if p.Mode == "" || p.Mode == ParamsKeep {
+		return rawURL, nil, nil
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return empty, nil, err
+	}
+	if u.RawQuery == empty {
+		return rawURL, nil, nil
+	}
+
+	patterns := p.patterns()
+	var kept, stripped []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		name := paramName(param)
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+
+		drop := matchesAny(name, patterns)
+		if p.Mode == ParamsAllow {
+			drop = !drop
+		}
+		if !drop || param == empty || matchesAny(name, keep) {
+			kept = append(kept, param)
+			continue
+		}
+		if !slices.Contains(stripped, name) {
+			stripped = append(stripped, name)
+		}
+	}
+
+	if len(stripped) == 0 {
+		return rawURL, nil, nil
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String(), stripped, nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamPolicy_Apply(t *testing.T) {
+	testCases := []struct {
+		name             string
+		policy           ParamPolicy
+		rawURL           string
+		keep             []string
+		expectedURL      string
+		expectedStripped []string
+	}{
+		{
+			name:        "zero value keeps everything",
+			rawURL:      "https://example.com/a?utm_source=x&id=1",
+			expectedURL: "https://example.com/a?utm_source=x&id=1",
+		},
+		{
+			name:        "keep mode",
+			policy:      ParamPolicy{Mode: ParamsKeep, Patterns: []string{"id"}},
+			rawURL:      "https://example.com/a?utm_source=x&id=1",
+			expectedURL: "https://example.com/a?utm_source=x&id=1",
+		},
+		{
+			name:             "strip defaults to tracking parameters",
+			policy:           ParamPolicy{Mode: ParamsStrip},
+			rawURL:           "https://example.com/a?utm_source=x&id=1&UTM_Medium=y&fbclid=z&utm_source=w",
+			expectedURL:      "https://example.com/a?id=1",
+			expectedStripped: []string{"utm_source", "UTM_Medium", "fbclid"},
+		},
+		{
+			name:             "strip configured patterns",
+			policy:           ParamPolicy{Mode: ParamsStrip, Patterns: []string{"ref", "s?"}},
+			rawURL:           "https://example.com/?ref=home&si=1&utm_source=x",
+			expectedURL:      "https://example.com/?utm_source=x",
+			expectedStripped: []string{"ref", "si"},
+		},
+		{
+			name:             "allow list",
+			policy:           ParamPolicy{Mode: ParamsAllow, Patterns: []string{"id", "page"}},
+			rawURL:           "https://example.com/?id=1&session=abc&page=2#top",
+			expectedURL:      "https://example.com/?id=1&page=2#top",
+			expectedStripped: []string{"session"},
+		},
+		{
+			name:             "every parameter stripped",
+			policy:           ParamPolicy{Mode: ParamsStrip},
+			rawURL:           "https://example.com/a?gclid=1",
+			expectedURL:      "https://example.com/a",
+			expectedStripped: []string{"gclid"},
+		},
+		{
+			name:             "per-call keep wins",
+			policy:           ParamPolicy{Mode: ParamsStrip},
+			rawURL:           "https://example.com/?utm_source=x&utm_medium=y",
+			keep:             []string{"UTM_SOURCE"},
+			expectedURL:      "https://example.com/?utm_source=x",
+			expectedStripped: []string{"utm_medium"},
+		},
+		{
+			name:        "no query",
+			policy:      ParamPolicy{Mode: ParamsAllow},
+			rawURL:      "https://example.com/a",
+			expectedURL: "https://example.com/a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualURL, actualStripped, err := tc.policy.Apply(tc.rawURL, tc.keep)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedURL, actualURL)
+			assert.Equal(t, tc.expectedStripped, actualStripped)
+		})
+	}
+}
+
+func TestParamPolicy_Validate(t *testing.T) {
+	assert.NoError(t, ParamPolicy{}.Validate())
+	assert.NoError(t, ParamPolicy{Mode: ParamsAllow, Patterns: []string{"utm_*", "id"}}.Validate())
+	assert.ErrorIs(t, ParamPolicy{Mode: "drop"}.Validate(), ErrParamPolicy)
+	assert.ErrorIs(t, ParamPolicy{Mode: ParamsStrip, Patterns: []string{"utm_["}}.Validate(), ErrParamPolicy)
+}
+
+func TestAdd_StripsParams(t *testing.T) {
+	var gotArgs []any
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			gotArgs = args
+			return &mockRow{result: []any{args[1], nil, true}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+
+	service, err := New(querier, gen, WithParamPolicy(ParamPolicy{Mode: ParamsStrip}))
+	require.NoError(t, err)
+
+	rawURL := "https://Example.com/post?utm_source=mail&id=7&fbclid=abc"
+	res, err := service.Add(context.Background(), rawURL, AddOptions{KeepParams: []string{"fbclid"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/post?id=7&fbclid=abc", res.URL)
+	assert.Equal(t, []string{"utm_source"}, res.StrippedParams)
+	assert.Equal(t, []any{"https://example.com/post?id=7&fbclid=abc", "abc1234", (*time.Time)(nil), rawURL, ""}, gotArgs)
+
+	_, err = service.Add(context.Background(), rawURL, AddOptions{KeepParams: []string{"["}})
+	assert.ErrorIs(t, err, ErrParamPolicy)
+}
+
+func TestNew_RejectsInvalidParamPolicy(t *testing.T) {
+	_, err := New(&mockQuerier{}, &mockNanoID{}, WithParamPolicy(ParamPolicy{Mode: "drop"}))
+	assert.ErrorIs(t, err, ErrParamPolicy)
+}
@@ -0,0 +1,59 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPassword         = errors.New("invalid password")
+	ErrPasswordRequired = errors.New("link is password protected")
+	ErrWrongPassword    = errors.New("wrong password")
+)
+
+// maxPasswordLength is the most bcrypt reads of a password. Longer ones are
+// refused rather than silently cut short.
+const maxPasswordLength = 72
+
+// passwordCost is the bcrypt work factor of new password hashes.
+var passwordCost = bcrypt.DefaultCost
+
+func validatePassword(password string) error {
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: at most %d bytes; got %d", ErrPassword, maxPasswordLength, len(password))
+	}
+	return nil
+}
+
+// hashPassword returns the salted bcrypt hash stored for a protected link, or
+// "" when there is no password.
+func hashPassword(password string) (string, error) {
+	if password == empty {
+		return empty, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return empty, fmt.Errorf("%w: %v", ErrPassword, err)
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password opens a link stored with hash. A
+// link without a hash is open to everyone.
+func checkPassword(hash, password string) error {
+	switch {
+	case hash == empty:
+		return nil
+	case password == empty:
+		return ErrPasswordRequired
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrWrongPassword
+		}
+		return fmt.Errorf("%w: stored password hash: %v", ErrScan, err)
+	}
+	return nil
+}
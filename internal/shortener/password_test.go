@@ -0,0 +1,106 @@
+package shortener
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	// Hashing at the default cost would slow every test that adds a
+	// protected link without testing anything more.
+	passwordCost = bcrypt.MinCost
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := hashPassword("s3cret")
+	require.NoError(t, err)
+	assert.NotContains(t, hash, "s3cret")
+
+	other, err := hashPassword("s3cret")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash has its own salt")
+
+	testCases := []struct {
+		name     string
+		hash     string
+		password string
+		err      error
+	}{
+		{name: "open link", hash: "", password: ""},
+		{name: "open link ignores a password", hash: "", password: "anything"},
+		{name: "right password", hash: hash, password: "s3cret"},
+		{name: "missing password", hash: hash, password: "", err: ErrPasswordRequired},
+		{name: "wrong password", hash: hash, password: "S3cret", err: ErrWrongPassword},
+		{name: "corrupt hash", hash: "not-a-hash", password: "s3cret", err: ErrScan},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPassword(tc.hash, tc.password)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAdd_HashesPassword(t *testing.T) {
+	var gotArgs []any
+	created := true
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			gotArgs = args
+			return &mockRow{result: []any{args[1], nil, created}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+
+	service, err := New(querier, gen)
+	require.NoError(t, err)
+
+	_, err = service.Add(context.Background(), "https://example.com/handbook", AddOptions{Password: "s3cret"})
+	require.NoError(t, err)
+	require.Len(t, gotArgs, 5)
+	hash := gotArgs[4].(string)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("s3cret")))
+
+	created = false
+	_, err = service.Add(context.Background(), "https://example.com/handbook", AddOptions{Password: "s3cret"})
+	assert.ErrorIs(t, err, ErrURLExists, "an existing link never silently loses its protection")
+
+	_, err = service.Add(context.Background(), "https://example.com/handbook", AddOptions{Password: strings.Repeat("x", maxPasswordLength+1)})
+	assert.ErrorIs(t, err, ErrPassword)
+}
+
+func TestGet_ProtectedLink(t *testing.T) {
+	hash, err := hashPassword("s3cret")
+	require.NoError(t, err)
+
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			return &mockRow{result: []any{"https://example.com/handbook", hash}}
+		},
+	}
+	service, err := New(querier, &mockNanoID{})
+	require.NoError(t, err)
+
+	_, err = service.Get(context.Background(), "abc1234", GetOptions{})
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	_, err = service.Get(context.Background(), "abc1234", GetOptions{Password: "guess"})
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	got, err := service.Get(context.Background(), "abc1234", GetOptions{Password: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/handbook", got)
+}
@@ -0,0 +1,349 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+)
+
+const (
+	AddQuery      = "SELECT o_short_code, o_expires_at, o_created FROM add_url($1, $2, $3, $4, $5);"
+	AddBatchQuery = "SELECT r.o_short_code, r.o_expires_at, r.o_created FROM unnest($1::text[], $2::text[], $3::timestamptz[], $4::text[], $5::text[]) WITH ORDINALITY AS t(original_url, short_code, expires_at, raw_url, password_hash, ord) CROSS JOIN LATERAL add_url(t.original_url, t.short_code, t.expires_at, t.raw_url, t.password_hash) AS r ORDER BY t.ord;"
+	GetQuery      = "SELECT original_url, coalesce(password_hash, '') FROM url WHERE short_code = $1 AND (expires_at IS NULL OR expires_at > now());"
+	ListQuery     = "SELECT id, original_url, short_code, created_at, expires_at, password_hash IS NOT NULL FROM url"
+	UpdateQuery   = "UPDATE url SET original_url = $2, raw_url = NULLIF($3, '') WHERE short_code = $1 AND (expires_at IS NULL OR expires_at > now()) RETURNING id;"
+	DeleteQuery   = "DELETE FROM url WHERE short_code = $1;"
+	HistoryQuery  = "SELECT h.revision, h.original_url, h.changed_at, u.password_hash IS NOT NULL FROM url_history h JOIN url u ON u.id = h.url_id WHERE u.short_code = $1 ORDER BY h.revision;"
+	PurgeQuery    = "DELETE FROM url WHERE id IN (SELECT id FROM url WHERE expires_at <= now() ORDER BY expires_at LIMIT $1);"
+	ClickQuery    = "INSERT INTO url_click (url_id) SELECT id FROM url WHERE short_code = $1;"
+	StatsQuery    = "SELECT u.id, count(c.id), min(c.clicked_at), max(c.clicked_at) FROM url u LEFT JOIN url_click c ON c.url_id = u.id WHERE u.short_code = $1 GROUP BY u.id;"
+	// ExportQuery has no trailing semicolon because it is wrapped in a
+	// DECLARE ... CURSOR FOR statement.
+	ExportQuery = "SELECT id, original_url, short_code, created_at, expires_at, password_hash IS NOT NULL FROM url ORDER BY id"
+	DailyQuery  = "SELECT date_trunc('day', clicked_at AT TIME ZONE 'UTC'), count(*) FROM url_click WHERE url_id = $1 GROUP BY 1 ORDER BY 1;"
+)
+
+// urlHost extracts the lowercased host of original_url, skipping any
+// userinfo, for the host filter of List.
+const urlHost = `lower(substring(original_url from '^[^:/?#]+://(?:[^/?#]*@)?([^/?#:]*)'))`
+
+// sortColumns maps each SortField to the column List orders by. Only these
+// fixed names are ever written into the SQL; every value is a parameter.
+var sortColumns = map[SortField]struct{ column, cast string }{
+	SortCreated: {"created_at", "timestamptz"},
+	SortCode:    {"short_code", "text"},
+	SortURL:     {"original_url", "text"},
+}
+
+// exportFetchSize is how many rows Export pulls from the cursor at a time.
+const exportFetchSize = 500
+
+var _ Store = (*postgresStore)(nil)
+
+type postgresStore struct {
+	db dbiface.Querier
+}
+
+// NewPostgresStore returns a Store backed by the schema in
+// internal/db/migrations.
+func NewPostgresStore(q dbiface.Querier) Store {
+	return &postgresStore{db: q}
+}
+
+func (p *postgresStore) Add(ctx context.Context, link NewLink) (AddResult, error) {
+	var res AddResult
+	err := p.db.QueryRow(ctx, AddQuery, link.OriginalURL, link.ShortCode, link.ExpiresAt, link.RawURL, link.PasswordHash).Scan(&res.ShortCode, &res.ExpiresAt, &res.Created)
+	if err != nil {
+		return AddResult{}, err
+	}
+	return res, nil
+}
+
+func (p *postgresStore) AddBatch(ctx context.Context, links []NewLink) ([]AddResult, error) {
+	urls := make([]string, len(links))
+	codes := make([]string, len(links))
+	expiries := make([]*time.Time, len(links))
+	raws := make([]string, len(links))
+	hashes := make([]string, len(links))
+	for i, l := range links {
+		urls[i], codes[i], expiries[i], raws[i], hashes[i] = l.OriginalURL, l.ShortCode, l.ExpiresAt, l.RawURL, l.PasswordHash
+	}
+
+	rows, err := p.db.Query(ctx, AddBatchQuery, urls, codes, expiries, raws, hashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]AddResult, 0, len(links))
+	for rows.Next() {
+		var res AddResult
+		if err := rows.Scan(&res.ShortCode, &res.ExpiresAt, &res.Created); err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (p *postgresStore) Get(ctx context.Context, shortCode string) (Destination, error) {
+	var dest Destination
+	err := p.db.QueryRow(ctx, GetQuery, shortCode).Scan(&dest.URL, &dest.PasswordHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Destination{}, fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+		}
+		return Destination{}, fmt.Errorf("%w: %v", ErrQuery, shortCode)
+	}
+
+	return dest, nil
+}
+
+func (p *postgresStore) List(ctx context.Context, opts ListOptions) ([]URLItem, error) {
+	sql, args := listQuery(opts)
+	rows, err := p.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQuery, empty)
+	}
+	defer rows.Close()
+
+	items := make([]URLItem, 0, opts.Limit)
+	for rows.Next() {
+		var item URLItem
+		if err := rows.Scan(&item.ID, &item.OriginalURL, &item.ShortCode, &item.CreatedAt, &item.ExpiresAt, &item.Protected); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrScan, err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRows, err)
+	}
+
+	return items, nil
+}
+
+// listQuery builds the List statement for opts. Filters only add fixed SQL
+// fragments; their values are always passed as parameters.
+func listQuery(opts ListOptions) (string, []any) {
+	var where []string
+	var args []any
+	param := func(v any, cast string) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d::%s", len(args), cast)
+	}
+
+	f := opts.Filter
+	if !f.IncludeExpired {
+		where = append(where, "(expires_at IS NULL OR expires_at > now())")
+	}
+	if f.Host != "" {
+		h := param(strings.ToLower(f.Host), "text")
+		where = append(where, fmt.Sprintf("(%[1]s = %[2]s OR right(%[1]s, length(%[2]s) + 1) = '.' || %[2]s)", urlHost, h))
+	}
+	if f.Contains != "" {
+		where = append(where, fmt.Sprintf("strpos(lower(original_url), lower(%s)) > 0", param(f.Contains, "text")))
+	}
+	if f.Since != nil {
+		where = append(where, "created_at >= "+param(*f.Since, "timestamptz"))
+	}
+	if f.Until != nil {
+		where = append(where, "created_at <= "+param(*f.Until, "timestamptz"))
+	}
+
+	sort := sortColumns[opts.sortField()]
+	dir, cmp := "DESC", "<"
+	if opts.Ascending {
+		dir, cmp = "ASC", ">"
+	}
+	if c := opts.After; c != nil {
+		var key string
+		switch opts.sortField() {
+		case SortCreated:
+			key = param(c.CreatedAt, sort.cast)
+		case SortURL:
+			// The token carries no URL, see Cursor.
+			key = fmt.Sprintf("(SELECT original_url FROM url WHERE id = %s)", param(c.ID, "bigint"))
+		default:
+			key = param(c.Value, sort.cast)
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", sort.column, cmp, key, param(c.ID, "bigint")))
+	}
+
+	var b strings.Builder
+	b.WriteString(ListQuery)
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	fmt.Fprintf(&b, " ORDER BY %s %s, id %s", sort.column, dir, dir)
+	fmt.Fprintf(&b, " LIMIT %s", param(opts.Limit, "int"))
+	if opts.After == nil {
+		fmt.Fprintf(&b, " OFFSET %s", param(opts.Offset, "int"))
+	}
+	b.WriteString(";")
+
+	return b.String(), args
+}
+
+func (p *postgresStore) Update(ctx context.Context, shortCode, originalURL, rawURL string) error {
+	var id uint64
+	err := p.db.QueryRow(ctx, UpdateQuery, shortCode, originalURL, rawURL).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+		case errors.Is(err, ErrURLExists):
+			return err
+		default:
+			return fmt.Errorf("%w: %v", ErrQuery, err)
+		}
+	}
+
+	return nil
+}
+
+// Export streams through a server-side cursor when the Querier supports one,
+// so at most exportFetchSize rows are held at a time. Otherwise it falls back
+// to a plain query, whose rows pgx still reads off the wire one by one.
+func (p *postgresStore) Export(ctx context.Context, fn func(URLItem) error) error {
+	var fnErr error
+	scan := func(row dbiface.Row) error {
+		var item URLItem
+		if err := row.Scan(&item.ID, &item.OriginalURL, &item.ShortCode, &item.CreatedAt, &item.ExpiresAt, &item.Protected); err != nil {
+			fnErr = fmt.Errorf("%w: %v", ErrScan, err)
+			return fnErr
+		}
+		fnErr = fn(item)
+		return fnErr
+	}
+
+	if c, ok := p.db.(dbiface.CursorQuerier); ok {
+		err := c.QueryCursor(ctx, exportFetchSize, scan, ExportQuery)
+		if err != nil && fnErr == nil {
+			return fmt.Errorf("%w: %v", ErrQuery, err)
+		}
+		return err
+	}
+
+	rows, err := p.db.Query(ctx, ExportQuery)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrQuery, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrRows, err)
+	}
+
+	return nil
+}
+
+func (p *postgresStore) Delete(ctx context.Context, shortCode string) (bool, error) {
+	cmdTag, err := p.db.Exec(ctx, DeleteQuery, shortCode)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrExec, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return false, fmt.Errorf("%w", ErrNotFound)
+	}
+
+	return true, nil
+}
+
+func (p *postgresStore) History(ctx context.Context, shortCode string) ([]Revision, error) {
+	rows, err := p.db.Query(ctx, HistoryQuery, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQuery, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]Revision, 0)
+	for rows.Next() {
+		var rev Revision
+		if err := rows.Scan(&rev.Number, &rev.OriginalURL, &rev.ChangedAt, &rev.Protected); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrScan, err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRows, err)
+	}
+
+	// Every link has at least the revision it was created with.
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+	}
+
+	return revisions, nil
+}
+
+func (p *postgresStore) PurgeExpired(ctx context.Context, limit int) (int64, error) {
+	cmdTag, err := p.db.Exec(ctx, PurgeQuery, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrExec, err)
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+func (p *postgresStore) RecordClick(ctx context.Context, shortCode string) error {
+	cmdTag, err := p.db.Exec(ctx, ClickQuery, shortCode)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExec, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+	}
+
+	return nil
+}
+
+func (p *postgresStore) Stats(ctx context.Context, shortCode string) (Stats, error) {
+	var id uint64
+	var stats Stats
+	err := p.db.QueryRow(ctx, StatsQuery, shortCode).Scan(&id, &stats.TotalClicks, &stats.FirstClick, &stats.LastClick)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Stats{}, fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+		}
+		return Stats{}, fmt.Errorf("%w: %v", ErrQuery, err)
+	}
+
+	rows, err := p.db.Query(ctx, DailyQuery, id)
+	if err != nil {
+		return Stats{}, fmt.Errorf("%w: %v", ErrQuery, err)
+	}
+	defer rows.Close()
+
+	stats.Daily = make([]DailyClicks, 0)
+	for rows.Next() {
+		var day DailyClicks
+		if err := rows.Scan(&day.Day, &day.Clicks); err != nil {
+			return Stats{}, fmt.Errorf("%w: %v", ErrScan, err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return Stats{}, fmt.Errorf("%w: %v", ErrRows, err)
+	}
+
+	return stats, nil
+}
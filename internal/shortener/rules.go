@@ -0,0 +1,106 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrRule             = errors.New("invalid domain rule")
+	ErrDomainDenied     = errors.New("destination domain is blocked")
+	ErrDomainNotAllowed = errors.New("destination domain is not on the allow list")
+)
+
+// RuleAction is what a DomainRule does with the hosts it matches.
+type RuleAction string
+
+const (
+	RuleAllow RuleAction = "allow"
+	RuleDeny  RuleAction = "deny"
+)
+
+// DomainRule allows or denies destinations by host. A Pattern of
+// "example.com" matches that host only; "*.example.com" matches every
+// subdomain of it, at any depth, but not example.com itself.
+type DomainRule struct {
+	Action  RuleAction `json:"action"`
+	Pattern string     `json:"pattern"`
+}
+
+// ParseRule validates action and pattern and returns the rule in canonical
+// form: the pattern lowercased, without a trailing dot and with an
+// internationalized name in punycode, so it compares equal to stored hosts.
+func ParseRule(action, pattern string) (DomainRule, error) {
+	r := DomainRule{Action: RuleAction(strings.ToLower(action))}
+	if r.Action != RuleAllow && r.Action != RuleDeny {
+		return DomainRule{}, fmt.Errorf("%w: action must be %s or %s; got %q", ErrRule, RuleAllow, RuleDeny, action)
+	}
+
+	host, wildcard := strings.CutPrefix(strings.TrimSuffix(strings.TrimSpace(pattern), "."), "*.")
+	if host == empty || strings.ContainsAny(host, "*/:@ ") {
+		return DomainRule{}, fmt.Errorf("%w: %q is not a domain or *.domain pattern", ErrRule, pattern)
+	}
+	host, err := asciiHost(host)
+	if err != nil {
+		return DomainRule{}, fmt.Errorf("%w: %q: %v", ErrRule, pattern, err)
+	}
+
+	r.Pattern = host
+	if wildcard {
+		r.Pattern = "*." + host
+	}
+	return r, nil
+}
+
+// Matches reports whether host, lowercased and in punycode, is covered by
+// the rule.
+func (r DomainRule) Matches(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if parent, ok := strings.CutPrefix(r.Pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+parent)
+	}
+	return host == r.Pattern
+}
+
+// String returns the rule as it is written in a rules file.
+func (r DomainRule) String() string {
+	return string(r.Action) + " " + r.Pattern
+}
+
+// DomainRules is a set of rules applied together. Deny rules win: a host
+// matching any of them is refused. When there is at least one allow rule, a
+// host must also match one of those. Without rules every host passes.
+type DomainRules []DomainRule
+
+// Check returns ErrDomainDenied or ErrDomainNotAllowed when the rules refuse
+// host. The error names the deny rule that matched.
+func (rs DomainRules) Check(host string) error {
+	hasAllow, allowed := false, false
+	for _, r := range rs {
+		switch r.Action {
+		case RuleDeny:
+			if r.Matches(host) {
+				return fmt.Errorf("%w: %s matches rule %q", ErrDomainDenied, host, r.String())
+			}
+		case RuleAllow:
+			hasAllow = true
+			allowed = allowed || r.Matches(host)
+		}
+	}
+	if hasAllow && !allowed {
+		return fmt.Errorf("%w: %s matches no allow rule", ErrDomainNotAllowed, host)
+	}
+	return nil
+}
+
+// DomainRules lets a fixed set of rules serve as a RuleSource.
+func (rs DomainRules) DomainRules() (DomainRules, error) {
+	return rs, nil
+}
+
+// RuleSource supplies the domain rules Add and Update enforce. It is asked on
+// every call, so an implementation may pick up changes while running.
+type RuleSource interface {
+	DomainRules() (DomainRules, error)
+}
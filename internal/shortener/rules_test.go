@@ -0,0 +1,141 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	testCases := []struct {
+		name     string
+		action   string
+		pattern  string
+		expected DomainRule
+		err      error
+	}{
+		{name: "exact", action: "allow", pattern: "example.com", expected: DomainRule{Action: RuleAllow, Pattern: "example.com"}},
+		{name: "wildcard", action: "DENY", pattern: "*.Phish.Example.", expected: DomainRule{Action: RuleDeny, Pattern: "*.phish.example"}},
+		{name: "unicode in punycode", action: "deny", pattern: "*.bücher.example", expected: DomainRule{Action: RuleDeny, Pattern: "*.xn--bcher-kva.example"}},
+		{name: "unknown action", action: "block", pattern: "example.com", err: ErrRule},
+		{name: "empty pattern", action: "deny", pattern: "*.", err: ErrRule},
+		{name: "inner wildcard", action: "deny", pattern: "a.*.example.com", err: ErrRule},
+		{name: "url instead of domain", action: "deny", pattern: "https://example.com", err: ErrRule},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.action, tc.pattern)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rule)
+		})
+	}
+}
+
+func TestDomainRule_Matches(t *testing.T) {
+	exact := DomainRule{Action: RuleAllow, Pattern: "example.com"}
+	wildcard := DomainRule{Action: RuleAllow, Pattern: "*.example.com"}
+
+	assert.True(t, exact.Matches("example.com"))
+	assert.True(t, exact.Matches("example.com."))
+	assert.False(t, exact.Matches("www.example.com"))
+
+	assert.True(t, wildcard.Matches("www.example.com"))
+	assert.True(t, wildcard.Matches("a.b.example.com"))
+	assert.False(t, wildcard.Matches("example.com"))
+	assert.False(t, wildcard.Matches("badexample.com"))
+}
+
+func TestDomainRules_Check(t *testing.T) {
+	rules := DomainRules{
+		{Action: RuleAllow, Pattern: "example.com"},
+		{Action: RuleAllow, Pattern: "*.example.com"},
+		{Action: RuleDeny, Pattern: "promo.example.com"},
+	}
+
+	testCases := []struct {
+		name    string
+		rules   DomainRules
+		host    string
+		err     error
+		message string
+	}{
+		{name: "no rules allow everything", host: "example.org"},
+		{name: "allowed exactly", rules: rules, host: "example.com"},
+		{name: "allowed by wildcard", rules: rules, host: "docs.example.com"},
+		{
+			name:    "deny wins over allow",
+			rules:   rules,
+			host:    "promo.example.com",
+			err:     ErrDomainDenied,
+			message: `destination domain is blocked: promo.example.com matches rule "deny promo.example.com"`,
+		},
+		{
+			name:    "outside the allow list",
+			rules:   rules,
+			host:    "example.org",
+			err:     ErrDomainNotAllowed,
+			message: "destination domain is not on the allow list: example.org matches no allow rule",
+		},
+		{name: "deny only", rules: DomainRules{{Action: RuleDeny, Pattern: "*.phish.example"}}, host: "example.org"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rules.Check(tc.host)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.EqualError(t, err, tc.message)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type ruleSourceFunc func() (DomainRules, error)
+
+func (f ruleSourceFunc) DomainRules() (DomainRules, error) {
+	return f()
+}
+
+func TestAddAndUpdate_EnforceDomainRules(t *testing.T) {
+	querier := &mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			return &mockRow{result: []any{args[1], nil, true}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+	rules := DomainRules{{Action: RuleDeny, Pattern: "*.phish.example"}}
+
+	service, err := New(querier, gen, WithDomainRules(rules))
+	require.NoError(t, err)
+
+	_, err = service.Add(context.Background(), "https://login.PHISH.example/account", AddOptions{})
+	assert.ErrorIs(t, err, ErrDomainDenied)
+	assert.ErrorContains(t, err, `"deny *.phish.example"`)
+
+	_, err = service.Add(context.Background(), "https://example.com/", AddOptions{})
+	assert.NoError(t, err)
+
+	err = service.Update(context.Background(), "abc1234", "https://www.phish.example/")
+	assert.ErrorIs(t, err, ErrDomainDenied)
+
+	results := service.AddBatch(context.Background(), []BatchItem{{URL: "https://a.phish.example"}})
+	assert.ErrorIs(t, results[0].Err, ErrDomainDenied)
+
+	broken, err := New(querier, gen, WithDomainRules(ruleSourceFunc(func() (DomainRules, error) {
+		return nil, errors.New("permission denied")
+	})))
+	require.NoError(t, err)
+	_, err = broken.Add(context.Background(), "https://example.com/", AddOptions{})
+	assert.ErrorIs(t, err, ErrRule)
+}
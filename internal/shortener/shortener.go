@@ -3,15 +3,27 @@ package shortener
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
+	"log"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/anewball/urlshortener/internal/dbiface"
 )
 
-const maxURLLength = 2048
+const (
+	maxURLLength       = 2048
+	minAliasLen        = 3
+	maxAliasLen        = 16
+	maxCodeLen         = 16
+	defaultMaxAttempts = 5
+	// escalateAfter is how many collisions at the base length Add tolerates
+	// before it starts generating longer codes.
+	escalateAfter = 2
+)
 
 var (
 	ErrNotFound    = errors.New("short URL not found")
@@ -28,110 +40,575 @@ var (
 	ErrQuery       = errors.New("failed to execute query")
 	ErrScan        = errors.New("failed to scan row")
 	ErrDBNil       = errors.New("database connection is nil")
+	ErrStoreNil    = errors.New("store is nil")
 	ErrNanoIDNil   = errors.New("NanoID generator is nil")
 	ErrQueryRow    = errors.New("no rows in result set")
 	ErrRows        = errors.New("rows produced an error")
+	ErrUnavailable = errors.New("storage is unavailable")
+
+	ErrAlias              = errors.New("invalid alias")
+	ErrAliasLength        = fmt.Errorf("alias must be between %d and %d characters", minAliasLen, maxAliasLen)
+	ErrAliasChars         = errors.New("alias may only contain letters, digits, '-' and '_' and must start and end with a letter or digit")
+	ErrAliasReserved      = errors.New("alias is a reserved word")
+	ErrAliasTaken         = errors.New("alias already in use")
+	ErrURLExists          = errors.New("URL is already shortened under a different code")
+	ErrDuplicateShortCode = errors.New("short code already exists")
+	ErrExpiresAt          = errors.New("expiration must be in the future")
+	ErrBatchSize          = errors.New("batch size must be greater than zero")
+	ErrCollision          = errors.New("could not find an unused short code")
+	ErrRevision           = errors.New("revision not found")
 )
 
+// codeCollisions counts generated codes rejected because they were already
+// taken. It is published through expvar, which `serve --debug-addr` exposes;
+// the public redirect listener does not, as expvar also reveals the process's
+// command line and memory stats.
+var codeCollisions = expvar.NewInt("shortener_code_collisions")
+
+// reservedAliases are paths the HTTP server routes itself or is likely to in
+// the future, so they can never be handed out as short codes.
+var reservedAliases = map[string]struct{}{
+	"admin":   {},
+	"api":     {},
+	"assets":  {},
+	"debug":   {},
+	"docs":    {},
+	"health":  {},
+	"healthz": {},
+	"help":    {},
+	"login":   {},
+	"logout":  {},
+	"metrics": {},
+	"static":  {},
+}
+
 type URLShortener interface {
-	Add(ctx context.Context, url string) (string, error)
-	Get(ctx context.Context, shortCode string) (string, error)
-	List(ctx context.Context, limit, offset int) ([]URLItem, error)
+	Add(ctx context.Context, url string, opts AddOptions) (AddResult, error)
+	AddBatch(ctx context.Context, items []BatchItem) []BatchResult
+	Get(ctx context.Context, shortCode string, opts GetOptions) (string, error)
+	List(ctx context.Context, opts ListOptions) ([]URLItem, error)
+	Export(ctx context.Context, fn func(URLItem) error) error
+	Update(ctx context.Context, shortCode, newURL string) error
 	Delete(ctx context.Context, shortCode string) (bool, error)
+	History(ctx context.Context, shortCode string) ([]Revision, error)
+	Rollback(ctx context.Context, shortCode string, revision int) (Revision, error)
+	PurgeExpired(ctx context.Context, batchSize int) (int64, error)
+	RecordClick(ctx context.Context, shortCode string) error
+	Stats(ctx context.Context, shortCode string) (Stats, error)
 }
 
 var _ URLShortener = (*shortener)(nil)
 
 type shortener struct {
-	db  dbiface.Querier
-	gen NanoID
+	store       Store
+	gen         NanoID
+	maxAttempts int
+	sortQuery   bool
+	params      ParamPolicy
+	// rejectMixedScripts makes Add refuse hosts that look like homograph
+	// attacks; see checkScripts.
+	rejectMixedScripts bool
+	// checkDestinations makes Add and Update refuse internal hosts; see
+	// checkDestination.
+	checkDestinations bool
+	rules             RuleSource
+}
+
+type Option func(*shortener)
+
+// WithMaxAttempts sets how many codes Add generates before giving up when
+// they keep colliding with existing ones. Values below 1 are ignored.
+func WithMaxAttempts(n int) Option {
+	return func(s *shortener) {
+		if n > 0 {
+			s.maxAttempts = n
+		}
+	}
+}
+
+// WithQuerySort makes Add order query parameters by name before storing a
+// URL, so links differing only in parameter order share a code. It is off by
+// default because some sites depend on the order.
+func WithQuerySort(enabled bool) Option {
+	return func(s *shortener) {
+		s.sortQuery = enabled
+	}
+}
+
+// WithParamPolicy sets which query parameters Add strips before storing a
+// URL. Without it every parameter is kept.
+func WithParamPolicy(p ParamPolicy) Option {
+	return func(s *shortener) {
+		s.params = p
+	}
+}
+
+// WithMixedScriptCheck makes Add reject hosts with a label mixing scripts,
+// such as a Cyrillic letter among Latin ones, with ErrMixedScript.
+func WithMixedScriptCheck(enabled bool) Option {
+	return func(s *shortener) {
+		s.rejectMixedScripts = enabled
+	}
+}
+
+// WithDestinationCheck turns the check that rejects private, loopback and
+// link-local destinations with ErrPrivateDestination on or off. It is on by
+// default so the redirector cannot be used to reach internal hosts.
+func WithDestinationCheck(enabled bool) Option {
+	return func(s *shortener) {
+		s.checkDestinations = enabled
+	}
+}
+
+// WithDomainRules makes Add and Update refuse destinations that src's rules
+// deny or, when it has allow rules, do not allow.
+func WithDomainRules(src RuleSource) Option {
+	return func(s *shortener) {
+		s.rules = src
+	}
+}
+
+// AddOptions tunes how Add creates a short link. The zero value generates a
+// random code.
+type AddOptions struct {
+	// Alias is a caller-chosen short code used instead of a generated one.
+	Alias string
+	// ExpiresAt, when set, is the moment the link stops resolving. If the
+	// URL is already shortened with another expiry, Add fails with
+	// ErrURLExists rather than return a link that expires differently.
+	ExpiresAt *time.Time
+	// KeepParams are patterns of query parameters kept even when the
+	// shortener's ParamPolicy would strip them.
+	KeepParams []string
+	// Password, when set, must be given to Get to resolve the link. Only a
+	// salted hash of it is stored.
+	Password string
+}
+
+// GetOptions carries what Get needs besides the short code.
+type GetOptions struct {
+	// Password opens a password-protected link. It is ignored for others.
+	Password string
+}
+
+// AddResult describes the link Add returned. When the URL was already
+// shortened, Created is false and the fields describe the existing link.
+// URL is the canonical form the link was stored under, see Canonicalize,
+// and StrippedParams the query parameters the ParamPolicy removed from it.
+// Attempts is how many codes were tried before one was accepted.
+type AddResult struct {
+	ShortCode      string
+	URL            string
+	StrippedParams []string
+	ExpiresAt      *time.Time
+	Created        bool
+	Attempts       int
+}
+
+// BatchItem is one link for AddBatch.
+type BatchItem struct {
+	URL  string
+	Opts AddOptions
 }
 
+// BatchResult is the outcome of one BatchItem. When Err is set the item was
+// not saved and AddResult is zero.
+type BatchResult struct {
+	AddResult
+	Err error
+}
+
+// Stats summarises the recorded clicks of a single link.
+type Stats struct {
+	TotalClicks int64
+	FirstClick  *time.Time
+	LastClick   *time.Time
+	Daily       []DailyClicks
+}
+
+// DailyClicks is one bucket of the per-day click histogram. Day is midnight
+// UTC of the day the clicks fall on.
+type DailyClicks struct {
+	Day    time.Time
+	Clicks int64
+}
+
+// Revision is one destination a link has pointed to. Number counts up from 1
+// for the destination the link was created with. Protected is set when the
+// link has a password, in which case callers must not show OriginalURL to
+// anyone who has not given it.
+type Revision struct {
+	Number      int
+	OriginalURL string
+	ChangedAt   time.Time
+	Protected   bool
+}
+
+// URLItem is a link as List and Export return it. Like Revision, a Protected
+// item's OriginalURL is only for callers that may see past the password.
 type URLItem struct {
 	ID          uint64
 	OriginalURL string
 	ShortCode   string
 	CreatedAt   time.Time
 	ExpiresAt   *time.Time
+	Protected   bool
 }
 
-func New(q dbiface.Querier, gen NanoID) (URLShortener, error) {
+// New returns a shortener backed by Postgres through q.
+func New(q dbiface.Querier, gen NanoID, opts ...Option) (URLShortener, error) {
 	if q == nil {
 		return nil, fmt.Errorf("%w", ErrDBNil)
 	}
+	return NewWithStore(NewPostgresStore(q), gen, opts...)
+}
+
+// NewWithStore returns a shortener that keeps its links in store.
+func NewWithStore(store Store, gen NanoID, opts ...Option) (URLShortener, error) {
+	if store == nil {
+		return nil, fmt.Errorf("%w", ErrStoreNil)
+	}
 	if gen == nil {
 		return nil, fmt.Errorf("%w", ErrNanoIDNil)
 	}
-	return &shortener{db: q, gen: gen}, nil
+	s := &shortener{store: store, gen: gen, maxAttempts: defaultMaxAttempts, checkDestinations: true}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.params.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 const (
-	AddQuery    = "SELECT add_url($1, $2);"
-	GetQuery    = "SELECT original_url FROM url WHERE short_code = $1 AND (expires_at IS NULL OR expires_at > now());"
-	ListQuery   = "SELECT id, original_url, short_code, created_at, expires_at FROM url WHERE (expires_at IS NULL OR expires_at > now()) ORDER BY created_at DESC LIMIT $1 OFFSET $2;"
-	DeleteQuery = "DELETE FROM url WHERE short_code = $1;"
-	empty       = ""
-	codeLen     = 7
-	Alphabet    = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	empty    = ""
+	codeLen  = 7
+	Alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
 )
 
-func (s *shortener) Add(ctx context.Context, rawURL string) (string, error) {
-	if err := isValidURL(rawURL); err != nil {
-		return empty, fmt.Errorf("%w: %v", ErrIsValidURL, err)
+func (s *shortener) Add(ctx context.Context, rawURL string, opts AddOptions) (AddResult, error) {
+	if err := validateAdd(rawURL, opts); err != nil {
+		return AddResult{}, err
 	}
 
-	genID, err := s.gen.Generate(codeLen)
+	canonical, stripped, err := s.canonicalize(rawURL, opts.KeepParams)
 	if err != nil {
-		return empty, fmt.Errorf("%w: %v", ErrGenerate, err)
+		return AddResult{}, err
 	}
 
-	var id string
-	err = s.db.QueryRow(ctx, AddQuery, rawURL, genID).Scan(&id)
+	hash, err := hashPassword(opts.Password)
 	if err != nil {
-		return empty, fmt.Errorf("%w: %v", ErrQueryRow, err)
+		return AddResult{}, err
+	}
+
+	var res AddResult
+	for attempt := 1; ; attempt++ {
+		code := opts.Alias
+		if code == empty {
+			genID, err := s.gen.Generate(codeLength(attempt))
+			if err != nil {
+				return AddResult{}, fmt.Errorf("%w: %v", ErrGenerate, err)
+			}
+			code = genID
+		}
+
+		var err error
+		res, err = s.store.Add(ctx, NewLink{OriginalURL: canonical, RawURL: rawURL, ShortCode: code, ExpiresAt: opts.ExpiresAt, PasswordHash: hash})
+		if err == nil {
+			res.URL, res.StrippedParams = canonical, stripped
+			res.Attempts = attempt
+			if attempt > 1 {
+				log.Printf("add: stored short code after %d attempts", attempt)
+			}
+			break
+		}
+		if !errors.Is(err, ErrDuplicateShortCode) {
+			return AddResult{}, fmt.Errorf("%w: %v", ErrQueryRow, err)
+		}
+		if opts.Alias != empty {
+			return AddResult{}, fmt.Errorf("%w: %s", ErrAliasTaken, opts.Alias)
+		}
+
+		codeCollisions.Add(1)
+		log.Printf("add: short code collision on attempt %d/%d (length %d)", attempt, s.maxAttempts, len(code))
+		if attempt >= s.maxAttempts {
+			return AddResult{}, fmt.Errorf("%w: gave up after %d attempts", ErrCollision, attempt)
+		}
 	}
 
-	return id, nil
+	// add_url returns the existing code when the URL was already shortened,
+	// which would silently drop the alias the caller asked for.
+	if opts.Alias != empty && res.ShortCode != opts.Alias {
+		return AddResult{}, fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+	}
+	// The same goes for a password: the existing link keeps its own, or none.
+	if opts.Password != empty && !res.Created {
+		return AddResult{}, fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+	}
+	// And for an expiry other than the one the existing link already has.
+	if !res.Created && expiryDiffers(res.ExpiresAt, opts.ExpiresAt) {
+		return AddResult{}, fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+	}
+
+	return res, nil
 }
 
-func (s *shortener) Get(ctx context.Context, shortCode string) (string, error) {
-	if shortCode == empty {
-		return empty, fmt.Errorf("%w: %v", ErrShortCode, empty)
+// AddBatch saves items with one store round trip and reports each item's
+// outcome separately, so one bad row never sinks the rest. Items are
+// validated exactly like Add. If the batch hits a taken short code the items
+// are retried one at a time through Add, which isolates the offending row and
+// regenerates colliding codes.
+func (s *shortener) AddBatch(ctx context.Context, items []BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+	links := make([]NewLink, 0, len(items))
+	pending := make([]int, 0, len(items))
+	stripped := make([][]string, len(items))
+
+	for i, item := range items {
+		if err := validateAdd(item.URL, item.Opts); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		canonical, params, err := s.canonicalize(item.URL, item.Opts.KeepParams)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		stripped[i] = params
+
+		hash, err := hashPassword(item.Opts.Password)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		code := item.Opts.Alias
+		if code == empty {
+			genID, err := s.gen.Generate(codeLen)
+			if err != nil {
+				results[i].Err = fmt.Errorf("%w: %v", ErrGenerate, err)
+				continue
+			}
+			code = genID
+		}
+
+		links = append(links, NewLink{OriginalURL: canonical, RawURL: item.URL, ShortCode: code, ExpiresAt: item.Opts.ExpiresAt, PasswordHash: hash})
+		pending = append(pending, i)
+	}
+
+	if len(links) == 0 {
+		return results
 	}
 
-	var originalURL string
-	err := s.db.QueryRow(ctx, GetQuery, shortCode).Scan(&originalURL)
+	saved, err := s.store.AddBatch(ctx, links)
+	if err == nil && len(saved) != len(links) {
+		err = fmt.Errorf("store returned %d results for %d links", len(saved), len(links))
+	}
 	if err != nil {
-		if errors.Is(err, ErrNotFound) {
-			return empty, fmt.Errorf("%w: %v", ErrNotFound, shortCode)
+		if errors.Is(err, ErrDuplicateShortCode) {
+			for _, i := range pending {
+				res, err := s.Add(ctx, items[i].URL, items[i].Opts)
+				results[i] = BatchResult{AddResult: res, Err: err}
+			}
+			return results
+		}
+		for _, i := range pending {
+			results[i].Err = fmt.Errorf("%w: %v", ErrQueryRow, err)
+		}
+		return results
+	}
+
+	for j, i := range pending {
+		res := saved[j]
+		res.URL, res.StrippedParams = links[j].OriginalURL, stripped[i]
+		res.Attempts = 1
+		if alias := items[i].Opts.Alias; alias != empty && res.ShortCode != alias {
+			results[i].Err = fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+			continue
+		}
+		if items[i].Opts.Password != empty && !res.Created {
+			results[i].Err = fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+			continue
+		}
+		if !res.Created && expiryDiffers(res.ExpiresAt, items[i].Opts.ExpiresAt) {
+			results[i].Err = fmt.Errorf("%w: %s", ErrURLExists, res.ShortCode)
+			continue
+		}
+		results[i].AddResult = res
+	}
+
+	return results
+}
+
+// expiryDiffers reports whether a request for the expiry requested cannot be
+// answered with a link expiring at stored. Asking for no expiry accepts any.
+// Times are compared to the microsecond, the precision Postgres keeps.
+func expiryDiffers(stored, requested *time.Time) bool {
+	if requested == nil {
+		return false
+	}
+	if stored == nil {
+		return true
+	}
+	return !stored.Truncate(time.Microsecond).Equal(requested.Truncate(time.Microsecond))
+}
+
+// validateAdd runs the checks Add and AddBatch apply before touching the
+// store.
+func validateAdd(rawURL string, opts AddOptions) error {
+	if err := isValidURL(rawURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsValidURL, err)
+	}
+
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: %s", ErrExpiresAt, opts.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if opts.Alias != empty {
+		if err := isValidAlias(opts.Alias); err != nil {
+			return fmt.Errorf("%w: %v", ErrAlias, err)
 		}
-		return empty, fmt.Errorf("%w: %v", ErrQuery, shortCode)
 	}
 
-	return originalURL, nil
+	if err := (ParamPolicy{Patterns: opts.KeepParams}).Validate(); err != nil {
+		return err
+	}
+
+	if err := validatePassword(opts.Password); err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (s *shortener) List(ctx context.Context, limit, offset int) ([]URLItem, error) {
-	rows, err := s.db.Query(ctx, ListQuery, limit, offset)
+// canonicalize returns the form rawURL is stored and deduplicated under,
+// after the ParamPolicy has stripped its parameters, and the names of those
+// it stripped. It runs after validateAdd, so a failure here is unexpected but
+// still reported as an invalid URL.
+func (s *shortener) canonicalize(rawURL string, keep []string) (string, []string, error) {
+	trimmed, stripped, err := s.params.Apply(rawURL, keep)
+	if err != nil {
+		return empty, nil, fmt.Errorf("%w: %v", ErrIsValidURL, err)
+	}
+
+	canonical, err := Canonicalize(trimmed, s.sortQuery)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrQuery, empty)
+		return empty, nil, fmt.Errorf("%w: %v", ErrIsValidURL, err)
 	}
-	defer rows.Close()
 
-	items := make([]URLItem, 0, limit)
-	for rows.Next() {
-		var item URLItem
-		if err := rows.Scan(&item.ID, &item.OriginalURL, &item.ShortCode, &item.CreatedAt, &item.ExpiresAt); err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrScan, err)
+	if err := s.checkHost(canonical); err != nil {
+		return empty, nil, err
+	}
+	return canonical, stripped, nil
+}
+
+// checkHost applies the host checks enabled on s to rawURL, which has passed
+// isValidURL. An internal destination is reported as ErrPrivateDestination
+// rather than as an invalid URL, since the URL itself is well formed.
+func (s *shortener) checkHost(rawURL string) error {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsValidURL, err)
+	}
+	host, err := asciiHost(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIsValidURL, err)
+	}
+
+	if s.checkDestinations {
+		if err := checkDestination(host); err != nil {
+			return err
+		}
+	}
+	if s.rejectMixedScripts {
+		if err := checkScripts(host); err != nil {
+			return fmt.Errorf("%w: %w", ErrIsValidURL, err)
+		}
+	}
+	if s.rules != nil {
+		rules, err := s.rules.DomainRules()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRule, err)
+		}
+		if err := rules.Check(host); err != nil {
+			return err
 		}
-		items = append(items, item)
 	}
+	return nil
+}
+
+// codeLength grows the generated code by one character for every attempt
+// past escalateAfter, so a crowded code space is escaped quickly.
+func codeLength(attempt int) int {
+	n := codeLen
+	if attempt > escalateAfter {
+		n += attempt - escalateAfter
+	}
+	return min(n, maxCodeLen)
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrRows, err)
+// Get returns where shortCode redirects. A password-protected link fails with
+// ErrPasswordRequired when opts has no password and ErrWrongPassword when it
+// has the wrong one, so callers can ask for it instead of reporting a missing
+// link.
+func (s *shortener) Get(ctx context.Context, shortCode string, opts GetOptions) (string, error) {
+	if shortCode == empty {
+		return empty, fmt.Errorf("%w: %v", ErrShortCode, empty)
 	}
 
-	return items, nil
+	dest, err := s.store.Get(ctx, shortCode)
+	if err != nil {
+		return empty, err
+	}
+	if err := checkPassword(dest.PasswordHash, opts.Password); err != nil {
+		return empty, fmt.Errorf("%w: %s", err, shortCode)
+	}
+	return dest.URL, nil
+}
+
+// List returns a page of links in the order and with the filters of opts. It
+// fails with ErrSort for an unknown sort field and ErrCursor when opts.After
+// was taken from a listing in a different order.
+func (s *shortener) List(ctx context.Context, opts ListOptions) ([]URLItem, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	// Hosts are stored in punycode, so match a Unicode filter in that form.
+	if host, err := asciiHost(opts.Filter.Host); err == nil {
+		opts.Filter.Host = host
+	}
+	return s.store.List(ctx, opts)
+}
+
+// Export calls fn for every link, expired ones included, oldest first. Unlike
+// List it is not paged and holds only a small window of rows in memory, so it
+// suits backups of any size.
+func (s *shortener) Export(ctx context.Context, fn func(URLItem) error) error {
+	return s.store.Export(ctx, fn)
+}
+
+// Update changes where an existing link redirects to while keeping its code.
+// A destination can only belong to one link, so pointing a code at a URL
+// that is already shortened elsewhere fails with ErrURLExists.
+func (s *shortener) Update(ctx context.Context, shortCode, newURL string) error {
+	if shortCode == empty {
+		return fmt.Errorf("%w", ErrShortCode)
+	}
+
+	if err := isValidURL(newURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrIsValidURL, err)
+	}
+
+	// The destination is stored like one given to Add, so the same URL
+	// spelled differently still cannot end up behind two codes.
+	canonical, _, err := s.canonicalize(newURL, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Update(ctx, shortCode, canonical, newURL)
 }
 
 func (s *shortener) Delete(ctx context.Context, shortCode string) (bool, error) {
@@ -139,16 +616,84 @@ func (s *shortener) Delete(ctx context.Context, shortCode string) (bool, error)
 		return false, fmt.Errorf("%w", ErrShortCode)
 	}
 
-	cmdTag, err := s.db.Exec(ctx, DeleteQuery, shortCode)
+	return s.store.Delete(ctx, shortCode)
+}
+
+func (s *shortener) History(ctx context.Context, shortCode string) ([]Revision, error) {
+	if shortCode == empty {
+		return nil, fmt.Errorf("%w", ErrShortCode)
+	}
+
+	return s.store.History(ctx, shortCode)
+}
+
+// Rollback points a link back at the destination it had in revision and
+// returns that revision. The rollback is itself recorded as a new revision,
+// so the history is never rewritten.
+func (s *shortener) Rollback(ctx context.Context, shortCode string, revision int) (Revision, error) {
+	revisions, err := s.History(ctx, shortCode)
 	if err != nil {
-		return false, fmt.Errorf("%w: %v", ErrExec, err)
+		return Revision{}, err
 	}
 
-	if cmdTag.RowsAffected() == 0 {
-		return false, fmt.Errorf("%w", ErrNotFound)
+	i := slices.IndexFunc(revisions, func(r Revision) bool { return r.Number == revision })
+	if i < 0 {
+		return Revision{}, fmt.Errorf("%w: %d", ErrRevision, revision)
 	}
 
-	return true, nil
+	// The destination may have become unacceptable since it was recorded,
+	// so it goes through the same host checks as a fresh update.
+	target := revisions[i]
+	if err := s.checkHost(target.OriginalURL); err != nil {
+		return Revision{}, err
+	}
+	if err := s.store.Update(ctx, shortCode, target.OriginalURL, target.OriginalURL); err != nil {
+		return Revision{}, err
+	}
+
+	return target, nil
+}
+
+// PurgeExpired deletes expired links in batches of at most batchSize rows so
+// a large backlog never holds locks on the whole table, and returns how many
+// rows it removed in total.
+func (s *shortener) PurgeExpired(ctx context.Context, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%w: got %d", ErrBatchSize, batchSize)
+	}
+
+	var purged int64
+	for {
+		n, err := s.store.PurgeExpired(ctx, batchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		purged += n
+		if n < int64(batchSize) {
+			return purged, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return purged, fmt.Errorf("%w: %v", ErrExec, err)
+		}
+	}
+}
+
+func (s *shortener) RecordClick(ctx context.Context, shortCode string) error {
+	if shortCode == empty {
+		return fmt.Errorf("%w", ErrShortCode)
+	}
+
+	return s.store.RecordClick(ctx, shortCode)
+}
+
+func (s *shortener) Stats(ctx context.Context, shortCode string) (Stats, error) {
+	if shortCode == empty {
+		return Stats{}, fmt.Errorf("%w", ErrShortCode)
+	}
+
+	return s.store.Stats(ctx, shortCode)
 }
 
 func isValidURL(rawURL string) error {
@@ -179,5 +724,33 @@ func isValidURL(rawURL string) error {
 		return ErrScheme
 	}
 
+	if _, err := asciiHost(u.Hostname()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// isValidAlias checks a caller-chosen code. Aliases are picked and read by
+// people, so unlike generated codes they may use the look-alike characters
+// Alphabet leaves out, plus '-' and '_' as separators.
+func isValidAlias(alias string) error {
+	if len(alias) < minAliasLen || len(alias) > maxAliasLen {
+		return ErrAliasLength
+	}
+
+	for i, r := range alias {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case (r == '-' || r == '_') && i > 0 && i < len(alias)-1:
+		default:
+			return ErrAliasChars
+		}
+	}
+
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrAliasReserved
+	}
+
 	return nil
 }
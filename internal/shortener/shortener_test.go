@@ -54,6 +54,16 @@ func TestIsValidURL(t *testing.T) {
 			rawURL:      "ftp://example.com",
 			expectedErr: ErrScheme,
 		},
+		{
+			name:        "internationalized host",
+			rawURL:      "https://bücher.example/",
+			expectedErr: nil,
+		},
+		{
+			name:        "invalid internationalized host",
+			rawURL:      "https://a\u200d.例/",
+			expectedErr: ErrIDN,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -65,10 +75,38 @@ func TestIsValidURL(t *testing.T) {
 	}
 }
 
+func TestIsValidAlias(t *testing.T) {
+	testCases := []struct {
+		name        string
+		alias       string
+		expectedErr error
+	}{
+		{name: "valid alias", alias: "spring-sale", expectedErr: nil},
+		{name: "valid alias with look-alike characters", alias: "l0g1n_IO", expectedErr: nil},
+		{name: "too short", alias: "ab", expectedErr: ErrAliasLength},
+		{name: "too long", alias: strings.Repeat("a", 17), expectedErr: ErrAliasLength},
+		{name: "invalid character", alias: "spring/sale", expectedErr: ErrAliasChars},
+		{name: "non-ascii character", alias: "café-sale", expectedErr: ErrAliasChars},
+		{name: "leading separator", alias: "-spring", expectedErr: ErrAliasChars},
+		{name: "trailing separator", alias: "spring_", expectedErr: ErrAliasChars},
+		{name: "reserved word", alias: "API", expectedErr: ErrAliasReserved},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualErr := isValidAlias(tc.alias)
+
+			assert.ErrorIs(t, actualErr, tc.expectedErr)
+		})
+	}
+}
+
 func TestAdd(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
 	testCases := []struct {
 		name              string
 		rawURL            string
+		opts              AddOptions
 		gen               NanoID
 		querier           dbiface.Querier
 		expectedErr       error
@@ -90,6 +128,15 @@ func TestAdd(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:              "expiration in the past",
+			rawURL:            "http://example.com",
+			opts:              AddOptions{ExpiresAt: &past},
+			expectedErr:       ErrExpiresAt,
+			expectedShortCode: "",
+			gen:               &mockNanoID{},
+			querier:           &mockQuerier{},
+		},
 		{
 			name:              "empty URL",
 			rawURL:            "",
@@ -126,20 +173,289 @@ func TestAdd(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:              "alias success",
+			rawURL:            "http://example.com",
+			opts:              AddOptions{Alias: "spring-sale"},
+			expectedErr:       nil,
+			expectedShortCode: "spring-sale",
+			gen:               &mockNanoID{},
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{args[1]}}
+				},
+			},
+		},
+		{
+			name:              "invalid alias",
+			rawURL:            "http://example.com",
+			opts:              AddOptions{Alias: "api"},
+			expectedErr:       ErrAlias,
+			expectedShortCode: "",
+			gen:               &mockNanoID{},
+			querier:           &mockQuerier{},
+		},
+		{
+			name:              "alias taken",
+			rawURL:            "http://example.com",
+			opts:              AddOptions{Alias: "spring-sale"},
+			expectedErr:       ErrAliasTaken,
+			expectedShortCode: "",
+			gen:               &mockNanoID{},
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: fmt.Errorf("%w: unique violation", ErrDuplicateShortCode)}
+				},
+			},
+		},
+		{
+			name:              "alias for URL that already has a code",
+			rawURL:            "http://example.com",
+			opts:              AddOptions{Alias: "spring-sale"},
+			expectedErr:       ErrURLExists,
+			expectedShortCode: "",
+			gen:               &mockNanoID{},
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{"abc123"}}
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			service, _ := New(tc.querier, tc.gen)
 
-			actualShortCode, err := service.Add(context.Background(), tc.rawURL)
+			actualResult, err := service.Add(context.Background(), tc.rawURL, tc.opts)
+
+			require.Equal(t, tc.expectedShortCode, actualResult.ShortCode)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestAdd_ReportsExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(72 * time.Hour).UTC()
+	var gotArgs []any
+
+	service, _ := New(&mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			gotArgs = args
+			return &mockRow{result: []any{"abc123", &expiresAt, true}}
+		},
+	}, &mockNanoID{
+		GenerateFunc: func(n int) (string, error) {
+			return "abc123", nil
+		},
+	})
+
+	actualResult, err := service.Add(context.Background(), "HTTP://Example.com:80", AddOptions{ExpiresAt: &expiresAt})
+
+	require.NoError(t, err)
+	assert.Equal(t, AddResult{ShortCode: "abc123", URL: "http://example.com/", ExpiresAt: &expiresAt, Created: true, Attempts: 1}, actualResult)
+	assert.Equal(t, []any{"http://example.com/", "abc123", &expiresAt, "HTTP://Example.com:80", ""}, gotArgs, "stores the canonical URL and the raw input")
+}
+
+func TestAdd_ExistingLinkWithDifferentExpiry(t *testing.T) {
+	stored := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	later := stored.Add(time.Hour)
+
+	testCases := []struct {
+		name        string
+		storedAt    *time.Time
+		opts        AddOptions
+		expectedErr error
+	}{
+		{name: "no expiry requested", storedAt: &stored},
+		{name: "same expiry", storedAt: &stored, opts: AddOptions{ExpiresAt: &stored}},
+		{name: "different expiry", storedAt: &stored, opts: AddOptions{ExpiresAt: &later}, expectedErr: ErrURLExists},
+		{name: "expiry on a permanent link", opts: AddOptions{ExpiresAt: &later}, expectedErr: ErrURLExists},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			querier := &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{"Other12", tc.storedAt, false}}
+				},
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{data: [][]any{{"Other12", tc.storedAt, false}}}, nil
+				},
+			}
+			gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "abc1234", nil }}
+			service, err := New(querier, gen)
+			require.NoError(t, err)
+
+			res, err := service.Add(context.Background(), "https://example.com", tc.opts)
+			batch := service.AddBatch(context.Background(), []BatchItem{{URL: "https://example.com", Opts: tc.opts}})
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.ErrorIs(t, batch[0].Err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, batch[0].Err)
+			assert.Equal(t, "Other12", res.ShortCode)
+			assert.Equal(t, "Other12", batch[0].ShortCode)
+		})
+	}
+}
+
+func TestAdd_RetriesOnCollision(t *testing.T) {
+	duplicate := fmt.Errorf("%w: unique violation", ErrDuplicateShortCode)
+	testCases := []struct {
+		name             string
+		maxAttempts      int
+		collisions       int
+		expectedErr      error
+		expectedAttempts int
+		expectedLengths  []int
+	}{
+		{
+			name:             "succeeds after one collision",
+			maxAttempts:      5,
+			collisions:       1,
+			expectedAttempts: 2,
+			expectedLengths:  []int{7, 7},
+		},
+		{
+			name:             "escalates code length when collisions persist",
+			maxAttempts:      5,
+			collisions:       4,
+			expectedAttempts: 5,
+			expectedLengths:  []int{7, 7, 8, 9, 10},
+		},
+		{
+			name:            "gives up after max attempts",
+			maxAttempts:     3,
+			collisions:      3,
+			expectedErr:     ErrCollision,
+			expectedLengths: []int{7, 7, 8},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lengths []int
+			gen := &mockNanoID{
+				GenerateFunc: func(n int) (string, error) {
+					lengths = append(lengths, n)
+					return strings.Repeat("a", n), nil
+				},
+			}
+			calls := 0
+			querier := &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					calls++
+					if calls <= tc.collisions {
+						return &mockRow{err: duplicate}
+					}
+					return &mockRow{result: []any{args[1], (*time.Time)(nil), true}}
+				},
+			}
+
+			before := codeCollisions.Value()
+			service, _ := New(querier, gen, WithMaxAttempts(tc.maxAttempts))
+			actualResult, err := service.Add(context.Background(), "http://example.com", AddOptions{})
 
-			require.Equal(t, tc.expectedShortCode, actualShortCode)
 			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expectedAttempts, actualResult.Attempts)
+			assert.Equal(t, tc.expectedLengths, lengths)
+			assert.Equal(t, int64(tc.collisions), codeCollisions.Value()-before)
 		})
 	}
 }
 
+func TestAddBatch(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	items := []BatchItem{
+		{URL: "https://example.com/1"},
+		{URL: "not a url"},
+		{URL: "https://example.com/2", Opts: AddOptions{Alias: "spring"}},
+		{URL: "https://example.com/3", Opts: AddOptions{ExpiresAt: &past}},
+		{URL: "https://example.com/4", Opts: AddOptions{Alias: "summer"}},
+	}
+
+	var batchCodes []string
+	querier := &mockQuerier{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+			batchCodes = args[1].([]string)
+			return &mockRows{data: [][]any{
+				{"aaaaaaa", (*time.Time)(nil), true},
+				{"spring", (*time.Time)(nil), false},
+				// The URL was already shortened under another code.
+				{"Other12", (*time.Time)(nil), false},
+			}}, nil
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return strings.Repeat("a", n), nil }}
+
+	service, _ := New(querier, gen)
+	results := service.AddBatch(context.Background(), items)
+
+	require.Len(t, results, len(items))
+	assert.Equal(t, []string{"aaaaaaa", "spring", "summer"}, batchCodes, "only valid items reach the store")
+	assert.Equal(t, AddResult{ShortCode: "aaaaaaa", URL: "https://example.com/1", Created: true, Attempts: 1}, results[0].AddResult)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, ErrIsValidURL)
+	assert.Equal(t, AddResult{ShortCode: "spring", URL: "https://example.com/2", Attempts: 1}, results[2].AddResult)
+	assert.ErrorIs(t, results[3].Err, ErrExpiresAt)
+	assert.ErrorIs(t, results[4].Err, ErrURLExists)
+}
+
+func TestAddBatch_FallsBackOnDuplicateCode(t *testing.T) {
+	duplicate := fmt.Errorf("%w: unique violation", ErrDuplicateShortCode)
+	items := []BatchItem{
+		{URL: "https://example.com/1"},
+		{URL: "https://example.com/2", Opts: AddOptions{Alias: "taken"}},
+	}
+
+	querier := &mockQuerier{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+			return &mockRows{err: duplicate}, nil
+		},
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			if args[1] == "taken" {
+				return &mockRow{err: duplicate}
+			}
+			return &mockRow{result: []any{args[1], (*time.Time)(nil), true}}
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return strings.Repeat("b", n), nil }}
+
+	service, _ := New(querier, gen)
+	results := service.AddBatch(context.Background(), items)
+
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "bbbbbbb", results[0].ShortCode)
+	assert.ErrorIs(t, results[1].Err, ErrAliasTaken)
+}
+
+func TestAddBatch_StoreError(t *testing.T) {
+	querier := &mockQuerier{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+			return nil, fmt.Errorf("conn closed")
+		},
+	}
+	gen := &mockNanoID{GenerateFunc: func(n int) (string, error) { return "ccccccc", nil }}
+
+	service, _ := New(querier, gen)
+	results := service.AddBatch(context.Background(), []BatchItem{{URL: "https://example.com"}})
+
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, ErrQueryRow)
+}
+
+func TestCodeLength(t *testing.T) {
+	assert.Equal(t, codeLen, codeLength(1))
+	assert.Equal(t, codeLen, codeLength(escalateAfter))
+	assert.Equal(t, codeLen+1, codeLength(escalateAfter+1))
+	assert.Equal(t, maxCodeLen, codeLength(100))
+}
+
 func TestGet(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -199,7 +515,7 @@ func TestGet(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			service, _ := New(tc.querier, tc.gen)
-			actualRawURL, err := service.Get(context.Background(), tc.shortCode)
+			actualRawURL, err := service.Get(context.Background(), tc.shortCode, GetOptions{})
 
 			require.Equal(t, tc.expectedRawURL, actualRawURL)
 			assert.ErrorIs(t, err, tc.expectedErr)
@@ -207,6 +523,269 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestUpdate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		shortCode   string
+		newURL      string
+		expectedErr error
+		querier     dbiface.Querier
+	}{
+		{
+			name:      "success",
+			shortCode: "GL9VeCa",
+			newURL:    "https://example.com/summer",
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{uint64(1)}}
+				},
+			},
+		},
+		{
+			name:        "empty short code",
+			shortCode:   "",
+			newURL:      "https://example.com",
+			expectedErr: ErrShortCode,
+			querier:     &mockQuerier{},
+		},
+		{
+			name:        "invalid URL",
+			shortCode:   "GL9VeCa",
+			newURL:      "ftp://example.com",
+			expectedErr: ErrIsValidURL,
+			querier:     &mockQuerier{},
+		},
+		{
+			name:        "not found",
+			shortCode:   "missing",
+			newURL:      "https://example.com",
+			expectedErr: ErrNotFound,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: ErrNotFound}
+				},
+			},
+		},
+		{
+			name:        "URL already shortened elsewhere",
+			shortCode:   "GL9VeCa",
+			newURL:      "https://example.com",
+			expectedErr: ErrURLExists,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: fmt.Errorf("%w: unique violation", ErrURLExists)}
+				},
+			},
+		},
+		{
+			name:        "query error",
+			shortCode:   "GL9VeCa",
+			newURL:      "https://example.com",
+			expectedErr: ErrQuery,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: fmt.Errorf("conn closed")}
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := New(tc.querier, &mockNanoID{})
+			err := service.Update(context.Background(), tc.shortCode, tc.newURL)
+
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestUpdate_StoresCanonicalURL(t *testing.T) {
+	var gotArgs []any
+	service, err := New(&mockQuerier{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+			gotArgs = args
+			return &mockRow{result: []any{uint64(1)}}
+		},
+	}, &mockNanoID{})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Update(context.Background(), "GL9VeCa", "HTTPS://Example.com:443/summer"))
+	assert.Equal(t, []any{"GL9VeCa", "https://example.com/summer", "HTTPS://Example.com:443/summer"}, gotArgs)
+}
+
+func historyRows() *mockRows {
+	return &mockRows{data: [][]any{
+		{1, "https://example.com/spring", time.Date(2025, time.August, 20, 12, 0, 0, 0, time.UTC), false},
+		{2, "https://example.com/summer", time.Date(2025, time.August, 21, 12, 0, 0, 0, time.UTC), false},
+	}}
+}
+
+func TestHistory(t *testing.T) {
+	testCases := []struct {
+		name        string
+		shortCode   string
+		expected    []Revision
+		expectedErr error
+		querier     dbiface.Querier
+	}{
+		{
+			name:      "success",
+			shortCode: "GL9VeCa",
+			expected: []Revision{
+				{Number: 1, OriginalURL: "https://example.com/spring", ChangedAt: time.Date(2025, time.August, 20, 12, 0, 0, 0, time.UTC)},
+				{Number: 2, OriginalURL: "https://example.com/summer", ChangedAt: time.Date(2025, time.August, 21, 12, 0, 0, 0, time.UTC)},
+			},
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return historyRows(), nil
+				},
+			},
+		},
+		{
+			name:        "empty short code",
+			expectedErr: ErrShortCode,
+			querier:     &mockQuerier{},
+		},
+		{
+			name:        "not found",
+			shortCode:   "missing",
+			expectedErr: ErrNotFound,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{}, nil
+				},
+			},
+		},
+		{
+			name:        "query error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrQuery,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return nil, fmt.Errorf("conn closed")
+				},
+			},
+		},
+		{
+			name:        "rows error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrRows,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{err: fmt.Errorf("broken pipe")}, nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := New(tc.querier, &mockNanoID{})
+			actual, err := service.History(context.Background(), tc.shortCode)
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	testCases := []struct {
+		name        string
+		revision    int
+		expectedURL string
+		expectedErr error
+		updateErr   error
+	}{
+		{
+			name:        "success",
+			revision:    1,
+			expectedURL: "https://example.com/spring",
+		},
+		{
+			name:        "unknown revision",
+			revision:    3,
+			expectedErr: ErrRevision,
+		},
+		{
+			name:        "destination taken by another link",
+			revision:    1,
+			expectedErr: ErrURLExists,
+			updateErr:   ErrURLExists,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updatedTo any
+			querier := &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return historyRows(), nil
+				},
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					updatedTo = args[1]
+					return &mockRow{result: []any{uint64(1)}, err: tc.updateErr}
+				},
+			}
+
+			service, _ := New(querier, &mockNanoID{})
+			actual, err := service.Rollback(context.Background(), "GL9VeCa", tc.revision)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedURL, actual.OriginalURL)
+			assert.Equal(t, tc.expectedURL, updatedTo)
+		})
+	}
+}
+
+func TestRollback_ChecksDestination(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []Option
+		expectedErr error
+	}{
+		{
+			name:        "denied domain",
+			opts:        []Option{WithDomainRules(DomainRules{{Action: RuleDeny, Pattern: "example.com"}})},
+			expectedErr: ErrDomainDenied,
+		},
+		{
+			name:        "domain not allowed",
+			opts:        []Option{WithDomainRules(DomainRules{{Action: RuleAllow, Pattern: "example.org"}})},
+			expectedErr: ErrDomainNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			querier := &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return historyRows(), nil
+				},
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					t.Fatal("store.Update must not be called for a rejected destination")
+					return nil
+				},
+			}
+
+			service, err := New(querier, &mockNanoID{}, tc.opts...)
+			require.NoError(t, err)
+
+			_, err = service.Rollback(context.Background(), "GL9VeCa", 1)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func TestList(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -222,16 +801,16 @@ func TestList(t *testing.T) {
 			limit: 10, offset: 0,
 			expectedErr: nil,
 			expectedItems: []URLItem{
-				{uint64(1), "http://example.com/1", "GL9VeCa", time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC), (*time.Time)(nil)},
-				{uint64(2), "http://example.com/2", "GL9VeCb", time.Date(2025, 8, 20, 12, 5, 0, 0, time.UTC), (*time.Time)(nil)},
+				{uint64(1), "http://example.com/1", "GL9VeCa", time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC), (*time.Time)(nil), false},
+				{uint64(2), "http://example.com/2", "GL9VeCb", time.Date(2025, 8, 20, 12, 5, 0, 0, time.UTC), (*time.Time)(nil), true},
 			},
 			gen: &mockNanoID{},
 			querier: &mockQuerier{
 				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
 					return &mockRows{
 						data: [][]any{
-							{uint64(1), "http://example.com/1", "GL9VeCa", time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC), (*time.Time)(nil)},
-							{uint64(2), "http://example.com/2", "GL9VeCb", time.Date(2025, 8, 20, 12, 5, 0, 0, time.UTC), (*time.Time)(nil)},
+							{uint64(1), "http://example.com/1", "GL9VeCa", time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC), (*time.Time)(nil), false},
+							{uint64(2), "http://example.com/2", "GL9VeCb", time.Date(2025, 8, 20, 12, 5, 0, 0, time.UTC), (*time.Time)(nil), true},
 						},
 						index: 0,
 					}, nil
@@ -310,7 +889,7 @@ func TestList(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			service, _ := New(tc.querier, tc.gen)
-			actualItems, err := service.List(context.Background(), tc.limit, tc.offset)
+			actualItems, err := service.List(context.Background(), ListOptions{Limit: tc.limit, Offset: tc.offset})
 
 			require.Equal(t, tc.expectedItems, actualItems)
 			assert.ErrorIs(t, err, tc.expectedErr)
@@ -318,6 +897,77 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestExport(t *testing.T) {
+	created := time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		expectedErr   error
+		expectedItems []URLItem
+		querier       dbiface.Querier
+	}{
+		{
+			name: "success",
+			expectedItems: []URLItem{
+				{uint64(1), "http://example.com/1", "GL9VeCa", created, (*time.Time)(nil), false},
+				{uint64(2), "http://example.com/2", "GL9VeCb", created, &created, true},
+			},
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{
+						data: [][]any{
+							{uint64(1), "http://example.com/1", "GL9VeCa", created, (*time.Time)(nil), false},
+							{uint64(2), "http://example.com/2", "GL9VeCb", created, &created, true},
+						},
+					}, nil
+				},
+			},
+		},
+		{
+			name:        "query error",
+			expectedErr: ErrQuery,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return nil, fmt.Errorf("query error")
+				},
+			},
+		},
+		{
+			name:        "scan error",
+			expectedErr: ErrScan,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{data: [][]any{{"http://example.com/1"}}}, nil
+				},
+			},
+		},
+		{
+			name:        "rows error",
+			expectedErr: ErrRows,
+			querier: &mockQuerier{
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{data: [][]any{}, err: ErrRows}, nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := New(tc.querier, &mockNanoID{})
+
+			var items []URLItem
+			err := service.Export(context.Background(), func(item URLItem) error {
+				items = append(items, item)
+				return nil
+			})
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expectedItems, items)
+		})
+	}
+}
+
 func TestDelete(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -388,6 +1038,244 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestPurgeExpired(t *testing.T) {
+	testCases := []struct {
+		name           string
+		batchSize      int
+		batches        []int64
+		execErr        error
+		expectedErr    error
+		expectedPurged int64
+		expectedCalls  int
+	}{
+		{
+			name:           "nothing to purge",
+			batchSize:      2,
+			batches:        []int64{0},
+			expectedPurged: 0,
+			expectedCalls:  1,
+		},
+		{
+			name:           "several batches",
+			batchSize:      2,
+			batches:        []int64{2, 2, 1},
+			expectedPurged: 5,
+			expectedCalls:  3,
+		},
+		{
+			name:           "last batch exactly full",
+			batchSize:      2,
+			batches:        []int64{2, 0},
+			expectedPurged: 2,
+			expectedCalls:  2,
+		},
+		{
+			name:          "invalid batch size",
+			batchSize:     0,
+			expectedErr:   ErrBatchSize,
+			expectedCalls: 0,
+		},
+		{
+			name:          "exec error",
+			batchSize:     2,
+			execErr:       fmt.Errorf("connection reset"),
+			expectedErr:   ErrExec,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			querier := &mockQuerier{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (dbiface.CommandResult, error) {
+					calls++
+					if tc.execErr != nil {
+						return nil, tc.execErr
+					}
+					require.Equal(t, PurgeQuery, sql)
+					require.Equal(t, []any{tc.batchSize}, arguments)
+					return &mockCommandResult{rowsAffected: tc.batches[calls-1]}, nil
+				},
+			}
+
+			service, _ := New(querier, &mockNanoID{})
+			actualPurged, err := service.PurgeExpired(context.Background(), tc.batchSize)
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expectedPurged, actualPurged)
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestRecordClick(t *testing.T) {
+	testCases := []struct {
+		name        string
+		shortCode   string
+		expectedErr error
+		querier     dbiface.Querier
+	}{
+		{
+			name:        "success",
+			shortCode:   "GL9VeCa",
+			expectedErr: nil,
+			querier: &mockQuerier{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (dbiface.CommandResult, error) {
+					return &mockCommandResult{rowsAffected: 1}, nil
+				},
+			},
+		},
+		{
+			name:        "empty short code",
+			shortCode:   "",
+			expectedErr: ErrShortCode,
+			querier:     &mockQuerier{},
+		},
+		{
+			name:        "unknown short code",
+			shortCode:   "nonexistent",
+			expectedErr: ErrNotFound,
+			querier: &mockQuerier{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (dbiface.CommandResult, error) {
+					return &mockCommandResult{rowsAffected: 0}, nil
+				},
+			},
+		},
+		{
+			name:        "exec error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrExec,
+			querier: &mockQuerier{
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (dbiface.CommandResult, error) {
+					return nil, fmt.Errorf("connection reset")
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := New(tc.querier, &mockNanoID{})
+			err := service.RecordClick(context.Background(), tc.shortCode)
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	first := time.Date(2025, 8, 20, 9, 0, 0, 0, time.UTC)
+	last := time.Date(2025, 8, 21, 18, 30, 0, 0, time.UTC)
+	day1 := time.Date(2025, 8, 20, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 8, 21, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		shortCode     string
+		expectedErr   error
+		expectedStats Stats
+		querier       dbiface.Querier
+	}{
+		{
+			name:        "success",
+			shortCode:   "GL9VeCa",
+			expectedErr: nil,
+			expectedStats: Stats{
+				TotalClicks: 3,
+				FirstClick:  &first,
+				LastClick:   &last,
+				Daily:       []DailyClicks{{Day: day1, Clicks: 2}, {Day: day2, Clicks: 1}},
+			},
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{uint64(7), int64(3), &first, &last}}
+				},
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					require.Equal(t, []any{uint64(7)}, args)
+					return &mockRows{data: [][]any{{day1, int64(2)}, {day2, int64(1)}}}, nil
+				},
+			},
+		},
+		{
+			name:          "no clicks yet",
+			shortCode:     "GL9VeCa",
+			expectedErr:   nil,
+			expectedStats: Stats{Daily: []DailyClicks{}},
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{uint64(7), int64(0), (*time.Time)(nil), (*time.Time)(nil)}}
+				},
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{data: [][]any{}}, nil
+				},
+			},
+		},
+		{
+			name:        "empty short code",
+			shortCode:   "",
+			expectedErr: ErrShortCode,
+			querier:     &mockQuerier{},
+		},
+		{
+			name:        "not found",
+			shortCode:   "nonexistent",
+			expectedErr: ErrNotFound,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: ErrNotFound}
+				},
+			},
+		},
+		{
+			name:        "totals query error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrQuery,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{err: fmt.Errorf("connection reset")}
+				},
+			},
+		},
+		{
+			name:        "daily query error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrQuery,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{uint64(7), int64(0), (*time.Time)(nil), (*time.Time)(nil)}}
+				},
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return nil, fmt.Errorf("connection reset")
+				},
+			},
+		},
+		{
+			name:        "daily rows error",
+			shortCode:   "GL9VeCa",
+			expectedErr: ErrRows,
+			querier: &mockQuerier{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) dbiface.Row {
+					return &mockRow{result: []any{uint64(7), int64(0), (*time.Time)(nil), (*time.Time)(nil)}}
+				},
+				QueryFunc: func(ctx context.Context, sql string, args ...any) (dbiface.Rows, error) {
+					return &mockRows{data: [][]any{}, err: fmt.Errorf("connection reset")}, nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := New(tc.querier, &mockNanoID{})
+			actualStats, err := service.Stats(context.Background(), tc.shortCode)
+
+			require.Equal(t, tc.expectedStats, actualStats)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func TestNew_ReturnsError_WhenDBIsNil(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -434,6 +1322,13 @@ func TestNew_ReturnsError_WhenDBIsNil(t *testing.T) {
 	}
 }
 
+func TestNewWithStore_ReturnsError_WhenStoreIsNil(t *testing.T) {
+	svc, err := NewWithStore(nil, &mockNanoID{})
+
+	require.Nil(t, svc)
+	assert.ErrorIs(t, err, ErrStoreNil)
+}
+
 func TestClose(t *testing.T) {
 	db := &mockQuerier{
 		CloseFunc: func() {
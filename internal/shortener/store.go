@@ -0,0 +1,62 @@
+package shortener
+
+import (
+	"context"
+	"time"
+)
+
+// NewLink is a link handed to Store.Add and Store.AddBatch. OriginalURL is
+// the canonical form links are deduplicated on; RawURL is the destination as
+// the user typed it, kept for reference. PasswordHash is the bcrypt hash of
+// the link's password, or empty for an open link.
+type NewLink struct {
+	OriginalURL  string
+	RawURL       string
+	ShortCode    string
+	ExpiresAt    *time.Time
+	PasswordHash string
+}
+
+// Destination is what Store.Get finds for a short code: where it redirects
+// and the hash of its password, if it has one.
+type Destination struct {
+	URL          string
+	PasswordHash string
+}
+
+// Store persists links. The shortener validates input, generates codes and
+// retries collisions; a Store only saves and looks up what it is given.
+//
+// Implementations report a taken short code with ErrDuplicateShortCode and a
+// missing or expired link with ErrNotFound so the service can tell them apart
+// from infrastructure failures.
+type Store interface {
+	// Add saves a link. If its OriginalURL is already stored the existing
+	// link is returned with Created set to false instead. An expired link
+	// holds neither its URL nor its short code: it is replaced.
+	Add(ctx context.Context, link NewLink) (AddResult, error)
+	// AddBatch saves several links in one round trip and returns one result
+	// per link, in order. It is all or nothing: if any link fails, for
+	// instance on a taken short code, none of them are saved.
+	AddBatch(ctx context.Context, links []NewLink) ([]AddResult, error)
+	// Get returns the destination of a link that has not expired.
+	Get(ctx context.Context, shortCode string) (Destination, error)
+	// List returns the page of links opts selects, in the order it asks for.
+	// opts has already been validated.
+	List(ctx context.Context, opts ListOptions) ([]URLItem, error)
+	// Export calls fn for every stored link, expired ones included, in order
+	// of ID. It stops at the first error fn returns and returns that error.
+	Export(ctx context.Context, fn func(URLItem) error) error
+	// Update points an unexpired link at originalURL, the canonical form of
+	// rawURL, and keeps rawURL alongside as Add does. It reports ErrURLExists
+	// when another link already has that destination.
+	Update(ctx context.Context, shortCode, originalURL, rawURL string) error
+	Delete(ctx context.Context, shortCode string) (bool, error)
+	// History returns every destination a link has had, oldest first. The
+	// destination the link was created with is revision 1.
+	History(ctx context.Context, shortCode string) ([]Revision, error)
+	// PurgeExpired deletes at most limit expired links.
+	PurgeExpired(ctx context.Context, limit int) (int64, error)
+	RecordClick(ctx context.Context, shortCode string) error
+	Stats(ctx context.Context, shortCode string) (Stats, error)
+}
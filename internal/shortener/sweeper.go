@@ -0,0 +1,50 @@
+package shortener
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const DefaultPurgeBatchSize = 1000
+
+// Sweeper periodically purges expired links for as long as its context lives.
+type Sweeper struct {
+	svc       URLShortener
+	interval  time.Duration
+	batchSize int
+}
+
+func NewSweeper(svc URLShortener, interval time.Duration, batchSize int) *Sweeper {
+	if batchSize <= 0 {
+		batchSize = DefaultPurgeBatchSize
+	}
+	return &Sweeper{svc: svc, interval: interval, batchSize: batchSize}
+}
+
+// Run sweeps once immediately and then every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	purged, err := s.svc.PurgeExpired(ctx, s.batchSize)
+	if err != nil {
+		log.Printf("sweeper: purged %d expired links before failing: %v", purged, err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("sweeper: purged %d expired links", purged)
+	}
+}
@@ -0,0 +1,45 @@
+package shortener
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anewball/urlshortener/internal/dbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweeper_Run(t *testing.T) {
+	var sweeps atomic.Int32
+	querier := &mockQuerier{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (dbiface.CommandResult, error) {
+			sweeps.Add(1)
+			return &mockCommandResult{rowsAffected: 0}, nil
+		},
+	}
+	service, _ := New(querier, &mockNanoID{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewSweeper(service, 10*time.Millisecond, 0).Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return sweeps.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sweeper did not stop after context cancellation")
+	}
+}
+
+func TestNewSweeper_DefaultsBatchSize(t *testing.T) {
+	sweeper := NewSweeper(nil, time.Minute, 0)
+
+	assert.Equal(t, DefaultPurgeBatchSize, sweeper.batchSize)
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 
 	"github.com/anewball/urlshortener/cmd"
@@ -14,24 +14,33 @@ import (
 	"github.com/anewball/urlshortener/core"
 	"github.com/anewball/urlshortener/env"
 	"github.com/anewball/urlshortener/internal/db"
+	"github.com/anewball/urlshortener/internal/memstore"
+	"github.com/anewball/urlshortener/internal/rules"
+	"github.com/anewball/urlshortener/internal/server"
 	"github.com/anewball/urlshortener/internal/shortener"
 	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(core.ExitCode(err))
 	}
 }
 
 func run() error {
 	log.SetOutput(os.Stderr)
 
-	envMap := setupViper()
+	_ = godotenv.Load()
+
+	settings, err := config.Load(configFlag(os.Args[1:]))
+	if err != nil {
+		return err
+	}
 
-	en := env.New(envMap)
+	en := env.New(settings)
 	cfg, err := config.NewBuilder(en).FromEnv().Build()
 	if err != nil {
 		return err
@@ -40,57 +49,102 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	querier, err := db.NewQuerier(ctx, cfg)
+	store, migrator, closeStore, err := openStore(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	log.Println("Connected to database successfully")
-	defer func() {
-		querier.Close()
-		log.Println("Database connection pool closed")
-	}()
+	defer closeStore()
+
+	ruleFile := rules.NewFile(cfg.DomainRulesFile)
 
 	gen := shortener.NewNanoID(shortener.Alphabet)
-	svc, err := shortener.New(querier, gen)
+	svc, err := shortener.NewWithStore(store, gen,
+		shortener.WithMaxAttempts(cfg.AddMaxAttempts),
+		shortener.WithQuerySort(cfg.SortQueryParams),
+		shortener.WithParamPolicy(shortener.ParamPolicy{
+			Mode:     shortener.ParamMode(cfg.ParamPolicy),
+			Patterns: cfg.ParamPatterns,
+		}),
+		shortener.WithMixedScriptCheck(cfg.RejectMixedScripts),
+		shortener.WithDestinationCheck(cfg.BlockPrivateDestinations),
+		shortener.WithDomainRules(ruleFile),
+	)
 	if err != nil {
 		return err
 	}
 
 	actions := core.NewActions(svc, cfg.ListMaxLimit)
 
-	root := cmd.NewRoot(actions)
+	var sweeper *shortener.Sweeper
+	if cfg.PurgeInterval > 0 {
+		sweeper = shortener.NewSweeper(svc, cfg.PurgeInterval, cfg.PurgeBatchSize)
+	}
+
+	srv := server.New(svc, actions, sweeper)
+
+	root := cmd.NewRoot(actions, srv, migrator, ruleFile)
+	if cfg.AutoMigrate && migrator != nil {
+		// The migrate subcommands override this hook, so a failed migration
+		// can still be inspected and forced.
+		root.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+			res, err := migrator.Up(c.Context())
+			if err != nil {
+				return fmt.Errorf("auto-migrate: %w", err)
+			}
+			if len(res.Applied) > 0 {
+				log.Printf("auto-migrate: applied %v, schema at version %d", res.Applied, res.Version)
+			}
+			return nil
+		}
+	}
 	root.SetContext(ctx)
 	root.SetArgs(os.Args[1:])
 
 	return root.Execute()
 }
 
-func setupViper() map[string]string {
-	_ = godotenv.Load()
-
-	v := viper.New()
-	v.AutomaticEnv()
-
-	keys := []string{
-		"POSTGRES_USER",
-		"POSTGRES_PASSWORD",
-		"POSTGRES_DB",
-		"DB_MAX_CONNS",
-		"DB_MIN_CONNS",
-		"DB_MAX_CONN_LIFETIME",
-		"DB_MAX_CONN_IDLE_TIME",
-		"DB_URL",
-		"LIST_MAX_LIMIT",
-	}
-
-	for _, k := range keys {
-		_ = v.BindEnv(k)
-	}
-
-	envMap := make(map[string]string, len(keys))
-	for _, k := range keys {
-		envMap[k] = strings.TrimSpace(v.GetString(k))
+// openStore builds the storage backend selected by cfg.StorageBackend and
+// returns a function that releases it. The migrator is nil for backends
+// without a database schema.
+func openStore(ctx context.Context, cfg config.Config) (shortener.Store, db.Migrator, func(), error) {
+	switch cfg.StorageBackend {
+	case config.BackendMemory:
+		return memstore.New(), nil, func() {}, nil
+	case config.BackendFile:
+		store, err := memstore.Open(cfg.StoragePath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return store, nil, func() {}, nil
+	default:
+		querier, err := db.NewQuerier(ctx, cfg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		log.Println("Connected to database successfully")
+		closeDB := func() {
+			querier.Close()
+			log.Println("Database connection pool closed")
+		}
+
+		migrator, err := db.NewMigrator(querier)
+		if err != nil {
+			closeDB()
+			return nil, nil, nil, err
+		}
+		return shortener.NewPostgresStore(querier), migrator, closeDB, nil
 	}
+}
 
-	return envMap
+// configFlag pulls --config out of args before the command tree exists,
+// since the commands are built from the loaded configuration. Cobra parses
+// the flag again later; everything else is left to it.
+func configFlag(args []string) string {
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	path := fs.String("config", "", "")
+	_ = fs.Parse(args)
+	return *path
 }